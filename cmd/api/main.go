@@ -2,22 +2,34 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/gamassss/url-shortener/internal/audit"
 	"github.com/gamassss/url-shortener/internal/config"
 	"github.com/gamassss/url-shortener/internal/handler"
 	"github.com/gamassss/url-shortener/internal/logger"
 	"github.com/gamassss/url-shortener/internal/middleware"
+	"github.com/gamassss/url-shortener/internal/repository/memory"
 	"github.com/gamassss/url-shortener/internal/repository/postgres"
 	redisRepo "github.com/gamassss/url-shortener/internal/repository/redis"
 	"github.com/gamassss/url-shortener/internal/service"
+	"github.com/gamassss/url-shortener/migrations"
+	"github.com/gamassss/url-shortener/pkg/breaker"
+	"github.com/gamassss/url-shortener/pkg/detector"
+	"github.com/gamassss/url-shortener/pkg/geoip"
+	"github.com/gamassss/url-shortener/pkg/metadata"
+	"github.com/gamassss/url-shortener/pkg/migrate"
+	"github.com/gamassss/url-shortener/pkg/webhook"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
@@ -49,6 +61,26 @@ func main() {
 		"log_level", cfg.Log.Level,
 	)
 
+	if cfg.Analytics.UAPatternsPath != "" {
+		if err := detector.LoadPatterns(cfg.Analytics.UAPatternsPath); err != nil {
+			log.Error("Failed to load user agent patterns", "path", cfg.Analytics.UAPatternsPath, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.Analytics.CrawlerRangesPath != "" {
+		if err := detector.LoadCrawlerRanges(cfg.Analytics.CrawlerRangesPath); err != nil {
+			log.Error("Failed to load crawler ranges", "path", cfg.Analytics.CrawlerRangesPath, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	geoResolver, err := geoip.Open(cfg.Analytics.GeoIPDBPath)
+	if err != nil {
+		log.Error("Failed to open GeoIP database", "path", cfg.Analytics.GeoIPDBPath, "error", err)
+		os.Exit(1)
+	}
+
 	dbPool, err := setupDatabase(cfg)
 	if err != nil {
 		log.Error("Failed to setup database", "error", err)
@@ -56,6 +88,22 @@ func main() {
 	}
 	defer dbPool.Close()
 
+	if cfg.Database.AutoMigrate {
+		if err := migrateDatabase(dbPool, log); err != nil {
+			log.Error("Failed to migrate database", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	replicas, err := setupReplicas(cfg, dbPool)
+	if err != nil {
+		log.Error("Failed to setup database replicas", "error", err)
+		os.Exit(1)
+	}
+	if replicas != nil {
+		defer replicas.Close()
+	}
+
 	redisClient, err := setupRedis(cfg)
 	if err != nil {
 		log.Error("Failed to setup redis", "error", err)
@@ -63,17 +111,130 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	urlRepo := postgres.NewURLRepository(dbPool)
+	// redirects are served from the database alone until Redis is reachable
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		log.Warn("Redis unavailable, starting without cache", "error", err)
+	}
+
+	urlRepo := postgres.NewURLRepository(dbPool, replicas)
 	urlCache := redisRepo.NewURLCache(redisClient)
-	analyticsRepo := postgres.NewAnalyticsRepository(dbPool)
+	cacheBreaker := breaker.New(cfg.Redis.BreakerThreshold, cfg.Redis.BreakerCooldown, func(from, to breaker.State) {
+		log.Warn("Redis cache circuit breaker changed state", "from", from.String(), "to", to.String())
+	})
+	resilientCache := service.NewResilientCache(urlCache, cacheBreaker, cfg.Redis.Timeout)
+	analyticsRepo := postgres.NewAnalyticsRepository(dbPool, replicas)
+	tagRepo := postgres.NewTagRepository(dbPool)
+	auditRepo := postgres.NewAuditRepository(dbPool)
+	webhookRepo := postgres.NewWebhookRepository(dbPool)
+	visitorCache := redisRepo.NewVisitorSketchCache(redisClient)
+	sketchRepo := postgres.NewVisitorSketchRepository(dbPool)
+	clickStream := redisRepo.NewClickStream(redisClient)
+
+	pageFetcher := metadata.NewFetcher(metadata.NewClient(cfg.Preview.FetchTimeout), cfg.Preview.FetchTimeout, cfg.Preview.MaxBodySize)
+	webhookSender := webhook.NewSender(metadata.NewClient(cfg.Webhook.Timeout))
+
+	var auditSink service.AuditSink
+	if cfg.Audit.LogPath != "" {
+		fileSink, err := audit.NewFileSink(cfg.Audit.LogPath)
+		if err != nil {
+			log.Error("Failed to open audit log", "path", cfg.Audit.LogPath, "error", err)
+			os.Exit(1)
+		}
+		defer fileSink.Close()
+		auditSink = fileSink
+	}
 
-	shortenerService := service.NewShortenerService(urlRepo, urlCache, analyticsRepo)
+	// background workers are stopped and waited for on shutdown, before the
+	// connections they use are closed
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var workers sync.WaitGroup
+	background := func(run func()) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run()
+		}()
+	}
+
+	var cacheRepo service.CacheRepository = resilientCache
+	if replicas != nil {
+		background(func() { replicas.Run(backgroundCtx, cfg.Database.ReplicaCheckInterval) })
+		// a replica can fall behind by up to the max lag until its next check
+		cacheRepo = service.NewReplicaLagCache(resilientCache, cfg.Database.ReplicaMaxLag+cfg.Database.ReplicaCheckInterval)
+	}
+
+	var localCache service.LocalCache
+	if cfg.Cache.L1MaxBytes > 0 {
+		localURLCache := memory.NewURLCache(cfg.Cache.L1MaxBytes, cfg.Cache.L1TTL)
+		background(func() { urlCache.WatchInvalidations(backgroundCtx, localURLCache.Delete, localURLCache.Purge) })
+		localCache = localURLCache
+	}
+
+	var shortCodes service.ShortCodeIndex
+	if cfg.Cache.BloomCapacity > 0 {
+		// while Redis is down announcements of new codes can't arrive
+		filter := service.NewShortCodeFilter(urlRepo, cfg.Cache.BloomCapacity, cfg.Cache.BloomFalsePositiveRate, resilientCache.Available)
+		background(func() { filter.Run(backgroundCtx, cfg.Cache.BloomRebuildInterval) })
+		background(func() { urlCache.WatchCreated(backgroundCtx, filter.Add, filter.Reset) })
+		shortCodes = filter
+	}
+
+	cacheFiller := service.NewCacheFiller(cfg.Cache.FillQueueSize)
+	clickRollup := service.NewClickRollup(analyticsRepo, cfg.Analytics.RollupBatchSize)
+
+	auditService := service.NewAuditService(auditRepo, auditSink)
+	visitorService := service.NewVisitorService(visitorCache, sketchRepo)
+	previewService := service.NewPreviewService(pageFetcher, urlRepo, cfg.Preview.QueueSize)
+	webhookService := service.NewWebhookService(webhookRepo, webhookSender, auditService, service.RetryPolicy{
+		MaxAttempts: cfg.Webhook.MaxAttempts,
+		BaseDelay:   cfg.Webhook.RetryBaseDelay,
+		MaxDelay:    cfg.Webhook.RetryMaxDelay,
+	}, cfg.Webhook.BatchSize, 2*cfg.Webhook.Timeout)
+	shortenerService := service.NewShortenerService(urlRepo, cacheRepo, analyticsRepo, visitorService, clickStream, service.ShortenerOptions{
+		Previews: previewService,
+		Auditor:  auditService,
+		Events:   webhookService,
+		Local:    localCache,
+		Codes:    shortCodes,
+		Fills:    cacheFiller,
+	})
+	tagService := service.NewTagService(tagRepo, auditService)
+
+	background(func() { visitorService.Run(backgroundCtx, cfg.Analytics.SketchFlushInterval) })
+	background(func() { previewService.Run(backgroundCtx, cfg.Preview.Workers) })
+	background(func() { cacheFiller.Run(backgroundCtx, cfg.Cache.FillWorkers) })
+	background(func() { clickRollup.Run(backgroundCtx, cfg.Analytics.RollupInterval) })
+
+	var warmup handler.Warmup
+	if cfg.Cache.WarmTopN > 0 {
+		cacheWarmer := service.NewCacheWarmer(urlRepo, resilientCache, service.WarmupPlan{
+			TopN:        cfg.Cache.WarmTopN,
+			Window:      cfg.Cache.WarmWindow,
+			BatchSize:   cfg.Cache.WarmBatchSize,
+			Concurrency: cfg.Cache.WarmConcurrency,
+			Budget:      cfg.Cache.WarmBudget,
+		})
+		background(func() { cacheWarmer.Run(backgroundCtx, cfg.Cache.WarmInterval) })
+		if cfg.Cache.WarmBeforeReady {
+			warmup = cacheWarmer
+		}
+	}
+	background(func() { webhookService.Run(backgroundCtx, cfg.Webhook.PollInterval) })
+	background(func() {
+		geoResolver.Watch(backgroundCtx, cfg.Analytics.GeoIPReloadInterval, func(err error) {
+			log.Error("Failed to reload GeoIP database", "error", err)
+		})
+	})
 
-	shortenerHandler := handler.NewShortenerHandler(shortenerService, cfg.Server.BaseURL)
+	shortenerHandler := handler.NewShortenerHandler(shortenerService, cfg.Server.BaseURL, geoResolver, cfg.Analytics.BotMinClickDelay)
 	analyticsHandler := handler.NewAnalyticsHandler(shortenerService)
-	healthHandler := handler.NewHealthHandler(dbPool, redisClient)
+	tagHandler := handler.NewTagHandler(tagService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	healthHandler := handler.NewHealthHandler(dbPool, redisClient, resilientCache, warmup)
 
-	router := setupRouter(shortenerHandler, analyticsHandler, healthHandler)
+	router := setupRouter(cfg.Audit.APIKeys, shortenerHandler, analyticsHandler, tagHandler, auditHandler, webhookHandler, healthHandler)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Server.Port),
@@ -81,6 +242,8 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
+	// open click streams never go idle, so end them as soon as shutdown starts
+	srv.RegisterOnShutdown(clickStream.Close)
 
 	go func() {
 		log.Info("Server listening", "address", srv.Addr)
@@ -90,12 +253,59 @@ func main() {
 		}
 	}()
 
-	gracefulShutdown(srv, cfg.Server.ShutdownTimeout, dbPool, redisClient, log)
+	drain := func() {
+		// redirects already served may still be recording their clicks
+		shortenerHandler.WaitForClicks()
+		stopBackground()
+		workers.Wait()
+	}
+	gracefulShutdown(srv, cfg.Server.ShutdownTimeout, drain, dbPool, redisClient, visitorService, log)
 }
 
 func setupDatabase(cfg *config.Config) (*pgxpool.Pool, error) {
-	dbConfig := cfg.Database
-	poolConfig, err := pgxpool.ParseConfig(dbConfig.URL)
+	return newPool(cfg.Database, cfg.Database.URL)
+}
+
+// migrateDatabase applies the embedded migrations the database hasn't got.
+// Replicas starting together take turns, and all but the first find nothing
+// to do.
+func migrateDatabase(db *pgxpool.Pool, log *slog.Logger) error {
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, migration := range applied {
+		log.Info("Applied database migration", "version", migration.Version, "name", migration.Name)
+	}
+	return err
+}
+
+// setupReplicas creates a pool per read replica in DB_REPLICA_URLS, sized like
+// the primary's. It returns nil when there are none.
+func setupReplicas(cfg *config.Config, primary *pgxpool.Pool) (*postgres.ReplicaSet, error) {
+	if len(cfg.Database.ReplicaURLs) == 0 {
+		return nil, nil
+	}
+
+	var pools []*pgxpool.Pool
+	for _, url := range cfg.Database.ReplicaURLs {
+		pool, err := newPool(cfg.Database, url)
+		if err != nil {
+			for _, pool := range pools {
+				pool.Close()
+			}
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+
+	return postgres.NewReplicaSet(primary, pools, cfg.Database.ReplicaMaxLag), nil
+}
+
+func newPool(dbConfig config.DatabaseConfig, url string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(url)
 	if err != nil {
 		return nil, err
 	}
@@ -113,26 +323,62 @@ func setupDatabase(cfg *config.Config) (*pgxpool.Pool, error) {
 	return dbPool, nil
 }
 
-func setupRedis(cfg *config.Config) (*redis.Client, error) {
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:         cfg.Redis.Addr,
-		Password:     cfg.Redis.Password,
-		DB:           cfg.Redis.DB,
-		PoolSize:     cfg.Redis.PoolSize,
-		MinIdleConns: cfg.Redis.MinIdleConns,
-		MaxRetries:   cfg.Redis.MaxRetries,
-	})
+// setupRedis creates a client for the standalone, sentinel or cluster
+// deployment set by REDIS_MODE. It doesn't connect; commands connect lazily.
+func setupRedis(cfg *config.Config) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Redis.Addrs,
+		Username:         cfg.Redis.Username,
+		Password:         cfg.Redis.Password,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		DB:               cfg.Redis.DB,
+		PoolSize:         cfg.Redis.PoolSize,
+		MinIdleConns:     cfg.Redis.MinIdleConns,
+		MaxRetries:       cfg.Redis.MaxRetries,
+	}
 
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	switch cfg.Redis.Mode {
+	case "standalone":
+		opts.Addrs = []string{cfg.Redis.Addr}
+	case "sentinel":
+		if cfg.Redis.MasterName == "" {
+			return nil, fmt.Errorf("REDIS_MASTER_NAME is required in sentinel mode")
+		}
+		opts.MasterName = cfg.Redis.MasterName
+	case "cluster":
+		opts.IsClusterMode = true
+	default:
+		return nil, fmt.Errorf("unknown REDIS_MODE %q", cfg.Redis.Mode)
 	}
 
-	return redisClient, nil
+	if cfg.Redis.TLS {
+		tlsConfig := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: cfg.Redis.TLSServerName,
+		}
+		if cfg.Redis.TLSCAFile != "" {
+			pem, err := os.ReadFile(cfg.Redis.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in redis CA file %s", cfg.Redis.TLSCAFile)
+			}
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	return redis.NewUniversalClient(opts), nil
 }
 
 func setupRouter(
+	apiKeys map[string]string,
 	shortenerHandler *handler.ShortenerHandler,
 	analyticsHandler *handler.AnalyticsHandler,
+	tagHandler *handler.TagHandler,
+	auditHandler *handler.AuditHandler,
+	webhookHandler *handler.WebhookHandler,
 	healthHandler *handler.HealthHandler,
 ) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
@@ -146,19 +392,48 @@ func setupRouter(
 	router.GET("/readyz", healthHandler.Readyz)
 
 	api := router.Group("/api")
+	api.Use(middleware.Actor(apiKeys))
 	{
 		api.POST("/shorten", shortenerHandler.ShortenURL)
-
+		api.GET("/urls", shortenerHandler.ListURLs)
+		api.PATCH("/urls/:shortCode", shortenerHandler.UpdateURL)
+		api.GET("/urls/:shortCode/history", shortenerHandler.GetURLHistory)
+		api.POST("/urls/:shortCode/rollback/:version", shortenerHandler.RollbackURL)
+
+		api.GET("/tags", tagHandler.ListTags)
+		api.PATCH("/tags/:name", tagHandler.RenameTag)
+		api.POST("/tags/:name/merge", tagHandler.MergeTag)
+		api.DELETE("/tags/:name", tagHandler.DeleteTag)
+
+		api.GET("/analytics/overview", analyticsHandler.GetOverview)
+		api.GET("/analytics/top", analyticsHandler.GetTopLinks)
 		api.GET("/analytics/:shortCode", analyticsHandler.GetAnalytics)
 		api.GET("/analytics/:shortCode/clicks", analyticsHandler.GetClickHistory)
+		api.GET("/analytics/:shortCode/stream", analyticsHandler.StreamClicks)
+		api.GET("/analytics/:shortCode/clicks/export", analyticsHandler.ExportClicks)
+		api.GET("/analytics/export", analyticsHandler.ExportAllClicks)
+
+		api.GET("/audit", auditHandler.ListEvents)
+
+		api.GET("/cache/stats", shortenerHandler.CacheStats)
+
+		api.POST("/webhooks", webhookHandler.CreateWebhook)
+		api.GET("/webhooks", webhookHandler.ListWebhooks)
+		api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		api.POST("/webhooks/:id/deliveries/:deliveryId/retry", webhookHandler.RetryDelivery)
 	}
 
 	router.GET("/:shortCode", shortenerHandler.Redirect)
+	router.HEAD("/:shortCode", shortenerHandler.Redirect)
 
 	return router
 }
 
-func gracefulShutdown(srv *http.Server, timeout time.Duration, dbPool *pgxpool.Pool, redisClient *redis.Client, log *slog.Logger) {
+// gracefulShutdown stops accepting requests, waits for them and then for drain,
+// which stops the background work, before flushing the visitor sketches and
+// closing the connections.
+func gracefulShutdown(srv *http.Server, timeout time.Duration, drain func(), dbPool *pgxpool.Pool, redisClient redis.UniversalClient, visitorService *service.VisitorService, log *slog.Logger) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -172,6 +447,22 @@ func gracefulShutdown(srv *http.Server, timeout time.Duration, dbPool *pgxpool.P
 		log.Error("Forced shutdown", "error", err)
 	}
 
+	drained := make(chan struct{})
+	go func() {
+		drain()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Info("Background work stopped")
+	case <-ctx.Done():
+		log.Error("Timed out waiting for background work to stop")
+	}
+
+	if _, err := visitorService.Flush(ctx); err != nil {
+		log.Error("Failed to flush visitor sketches", "error", err)
+	}
+
 	dbPool.Close()
 	log.Info("Database connection closed")
 
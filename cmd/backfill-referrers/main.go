@@ -0,0 +1,59 @@
+// Command backfill-referrers sets the referrer source of clicks recorded
+// before sources were, using the same host to source mapping as new clicks,
+// so top sources don't split a source between its name and its raw hosts.
+// It only touches clicks without a source and can be stopped and rerun.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gamassss/url-shortener/internal/config"
+	"github.com/gamassss/url-shortener/internal/repository/postgres"
+	"github.com/gamassss/url-shortener/pkg/referrer"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const batchSize = 5000
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v\n", err)
+	}
+	defer pool.Close()
+
+	updated, err := backfill(ctx, postgres.NewAnalyticsRepository(pool, nil))
+	if err != nil {
+		log.Fatalf("Backfill stopped after %d clicks: %v\n", updated, err)
+	}
+	fmt.Printf("set the referrer source of %d clicks\n", updated)
+}
+
+func backfill(ctx context.Context, repo *postgres.AnalyticsRepository) (int, error) {
+	var afterID int64
+	updated := 0
+	for {
+		clicks, err := repo.ListUnsourcedClicks(ctx, afterID, batchSize)
+		if err != nil || len(clicks) == 0 {
+			return updated, err
+		}
+
+		for i := range clicks {
+			clicks[i].ReferrerSource = referrer.Source(clicks[i].ReferrerHost)
+		}
+		if err := repo.SetReferrerSources(ctx, clicks); err != nil {
+			return updated, err
+		}
+
+		updated += len(clicks)
+		afterID = clicks[len(clicks)-1].ID
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/gamassss/url-shortener/internal/config"
+	"github.com/gamassss/url-shortener/migrations"
+	"github.com/gamassss/url-shortener/pkg/migrate"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up             apply every pending migration
+  down N         undo the last N migrations
+  status         show the current version and pending migrations
+  force VERSION  set the version without running migrations, and clear the dirty flag`
+
+var errUsage = errors.New("usage")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v\n", err)
+	}
+	defer pool.Close()
+
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v\n", err)
+	}
+
+	err = run(ctx, migrator, os.Args[1], os.Args[2:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, migrator *migrate.Migrator, command string, args []string) error {
+	switch {
+	case command == "up" && len(args) == 0:
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+
+	case command == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("down takes a positive number of migrations, got %q", args[0])
+		}
+		undone, err := migrator.Down(ctx, n)
+		for _, migration := range undone {
+			fmt.Printf("undid %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case command == "status" && len(args) == 0:
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version %d", status.Version)
+		if status.Dirty {
+			fmt.Print(" (dirty)")
+		}
+		fmt.Println()
+		for _, migration := range status.Pending {
+			fmt.Printf("pending %d_%s\n", migration.Version, migration.Name)
+		}
+		return nil
+
+	case command == "force" && len(args) == 1:
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("force takes a version, got %q", args[0])
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		fmt.Printf("version %d\n", version)
+		return nil
+	}
+
+	return errUsage
+}
@@ -0,0 +1,25 @@
+package audit
+
+import "context"
+
+type contextKey string
+
+const actorKey contextKey = "audit_actor"
+
+// Actor is who made a request, as recorded in audit events. ID is the actor
+// the request's API key belongs to, and empty for anonymous requests.
+type Actor struct {
+	ID string
+	IP string
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey).(Actor); ok {
+		return actor
+	}
+	return Actor{}
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+)
+
+// FileSink appends audit events to a file as newline-delimited JSON, for
+// shipping to a SIEM.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (s *FileSink) Write(event *domain.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enc.Encode(event)
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink_AppendsNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "events.ndjson")
+
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&domain.AuditEvent{Action: domain.AuditURLCreate, Target: "abc1234"}))
+	require.NoError(t, sink.Close())
+
+	// reopening appends rather than truncating
+	sink, err = NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&domain.AuditEvent{Action: domain.AuditTagDelete, Target: "promo"}))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var actions []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event domain.AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		actions = append(actions, event.Action)
+	}
+
+	assert.Equal(t, []string{domain.AuditURLCreate, domain.AuditTagDelete}, actions)
+}
@@ -3,27 +3,44 @@ package config
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Redis    RedisConfig
-	Server   ServerConfig
-	Database DatabaseConfig
-	Log      LogConfig
+	Redis     RedisConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Log       LogConfig
+	Analytics AnalyticsConfig
+	Preview   PreviewConfig
+	Audit     AuditConfig
+	Webhook   WebhookConfig
+	Cache     CacheConfig
 }
 
 type RedisConfig struct {
-	Host         string
-	Port         string
-	Password     string
-	DB           int
-	Addr         string
-	PoolSize     int
-	MinIdleConns int
-	MaxRetries   int
+	Mode             string
+	Host             string
+	Port             string
+	Username         string
+	Password         string
+	DB               int
+	Addr             string
+	Addrs            []string
+	MasterName       string
+	SentinelPassword string
+	TLS              bool
+	TLSCAFile        string
+	TLSServerName    string
+	PoolSize         int
+	MinIdleConns     int
+	MaxRetries       int
+	Timeout          time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
 type ServerConfig struct {
@@ -45,6 +62,61 @@ type DatabaseConfig struct {
 	MinConns        int
 	ConnMaxLifetime time.Duration
 	MaxConnIdleTime time.Duration
+	AutoMigrate     bool
+
+	ReplicaURLs          []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+}
+
+type AnalyticsConfig struct {
+	SketchFlushInterval time.Duration
+	UAPatternsPath      string
+	GeoIPDBPath         string
+	GeoIPReloadInterval time.Duration
+	CrawlerRangesPath   string
+	BotMinClickDelay    time.Duration
+	RollupInterval      time.Duration
+	RollupBatchSize     int
+}
+
+type PreviewConfig struct {
+	FetchTimeout time.Duration
+	MaxBodySize  int64
+	Workers      int
+	QueueSize    int
+}
+
+type AuditConfig struct {
+	LogPath string
+	// APIKeys maps each API key to the actor it identifies
+	APIKeys map[string]string
+}
+
+type WebhookConfig struct {
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	BatchSize      int
+}
+
+type CacheConfig struct {
+	L1MaxBytes             int64
+	L1TTL                  time.Duration
+	BloomCapacity          uint64
+	BloomFalsePositiveRate float64
+	BloomRebuildInterval   time.Duration
+	FillWorkers            int
+	FillQueueSize          int
+	WarmTopN               int
+	WarmWindow             time.Duration
+	WarmBatchSize          int
+	WarmConcurrency        int
+	WarmBudget             time.Duration
+	WarmInterval           time.Duration
+	WarmBeforeReady        bool
 }
 
 type LogConfig struct {
@@ -67,13 +139,24 @@ func Load() (*Config, error) {
 	viper.SetDefault("SERVER_READ_TIMEOUT", 10)
 	viper.SetDefault("SERVER_WRITE_TIMEOUT", 10)
 
+	viper.SetDefault("REDIS_MODE", "standalone") // standalone, sentinel or cluster
 	viper.SetDefault("REDIS_HOST", "localhost")
 	viper.SetDefault("REDIS_PORT", "6379")
+	viper.SetDefault("REDIS_USERNAME", "") // ACL user, empty for the default user
 	viper.SetDefault("REDIS_PASSWORD", "")
+	viper.SetDefault("REDIS_ADDRS", "")       // comma-separated sentinel or cluster node addresses
+	viper.SetDefault("REDIS_MASTER_NAME", "") // sentinel only
+	viper.SetDefault("REDIS_SENTINEL_PASSWORD", "")
+	viper.SetDefault("REDIS_TLS", false)
+	viper.SetDefault("REDIS_TLS_CA_FILE", "") // empty uses the system roots
+	viper.SetDefault("REDIS_TLS_SERVER_NAME", "")
 	viper.SetDefault("REDIS_DB", 0)
 	viper.SetDefault("REDIS_POOL_SIZE", 100)
 	viper.SetDefault("REDIS_MIN_IDLE_CONNS", 20)
 	viper.SetDefault("REDIS_MAX_RETRIES", 3)
+	viper.SetDefault("REDIS_TIMEOUT", 100)         // in milliseconds, per cache call
+	viper.SetDefault("REDIS_BREAKER_THRESHOLD", 5) // consecutive failures before Redis is bypassed
+	viper.SetDefault("REDIS_BREAKER_COOLDOWN", 10) // in seconds
 
 	viper.SetDefault("DB_HOST", "localhost")
 	viper.SetDefault("DB_PORT", "5432")
@@ -83,7 +166,11 @@ func Load() (*Config, error) {
 	viper.SetDefault("DB_MAX_CONNS", 40)
 	viper.SetDefault("DB_MIN_CONNS", 10)
 	viper.SetDefault("DB_CONN_MAX_LIFETIME", 5)
-	viper.SetDefault("DB_CONN_MAX_IDLE_TIME", 30) // in seconds
+	viper.SetDefault("DB_CONN_MAX_IDLE_TIME", 30)    // in seconds
+	viper.SetDefault("DB_AUTO_MIGRATE", false)       // apply pending migrations at startup
+	viper.SetDefault("DB_REPLICA_URLS", "")          // comma-separated read replica connection URLs
+	viper.SetDefault("DB_REPLICA_MAX_LAG", 5)        // in seconds, before a replica stops taking reads
+	viper.SetDefault("DB_REPLICA_CHECK_INTERVAL", 5) // in seconds
 
 	viper.SetDefault("LOG_LEVEL", "info")
 	viper.SetDefault("LOG_FORMAT", "json")
@@ -93,21 +180,78 @@ func Load() (*Config, error) {
 	viper.SetDefault("LOG_MAX_AGE", 7)
 	viper.SetDefault("LOG_COMPRESS", true)
 
+	viper.SetDefault("ANALYTICS_SKETCH_FLUSH_INTERVAL", 60) // in seconds
+	viper.SetDefault("ANALYTICS_UA_PATTERNS_PATH", "")
+	viper.SetDefault("ANALYTICS_GEOIP_DB_PATH", "")
+	viper.SetDefault("ANALYTICS_GEOIP_RELOAD_INTERVAL", 60) // in seconds
+	viper.SetDefault("ANALYTICS_CRAWLER_RANGES_PATH", "")
+	viper.SetDefault("ANALYTICS_BOT_MIN_CLICK_DELAY", 2000) // in milliseconds
+	viper.SetDefault("ANALYTICS_ROLLUP_INTERVAL", 10)       // in seconds
+	viper.SetDefault("ANALYTICS_ROLLUP_BATCH_SIZE", 10000)
+
+	viper.SetDefault("PREVIEW_FETCH_TIMEOUT", 5)   // in seconds
+	viper.SetDefault("PREVIEW_MAX_BODY_SIZE", 512) // in kilobytes
+	viper.SetDefault("PREVIEW_WORKERS", 4)
+	viper.SetDefault("PREVIEW_QUEUE_SIZE", 1000)
+
+	viper.SetDefault("AUDIT_LOG_PATH", "")
+	viper.SetDefault("AUDIT_API_KEYS", "") // comma-separated actor:key pairs
+
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", 5) // in seconds
+	viper.SetDefault("WEBHOOK_TIMEOUT", 10)      // in seconds
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_RETRY_BASE_DELAY", 30)   // in seconds
+	viper.SetDefault("WEBHOOK_RETRY_MAX_DELAY", 21600) // in seconds
+	viper.SetDefault("WEBHOOK_BATCH_SIZE", 50)
+
+	viper.SetDefault("CACHE_L1_MAX_SIZE", 64)          // in megabytes, 0 disables the in-process cache
+	viper.SetDefault("CACHE_L1_TTL", 30)               // in seconds
+	viper.SetDefault("CACHE_BLOOM_CAPACITY", 10000000) // expected short codes, 0 disables the filter
+	viper.SetDefault("CACHE_BLOOM_FALSE_POSITIVE_RATE", 0.01)
+	viper.SetDefault("CACHE_BLOOM_REBUILD_INTERVAL", 60) // in minutes, 0 rebuilds only on reconnect
+	viper.SetDefault("CACHE_FILL_WORKERS", 4)
+	viper.SetDefault("CACHE_FILL_QUEUE_SIZE", 1000)
+	viper.SetDefault("CACHE_WARM_TOP_N", 10000) // 0 disables warming
+	viper.SetDefault("CACHE_WARM_WINDOW", 24)   // in hours of clicks counted
+	viper.SetDefault("CACHE_WARM_BATCH_SIZE", 500)
+	viper.SetDefault("CACHE_WARM_CONCURRENCY", 4)
+	viper.SetDefault("CACHE_WARM_BUDGET", 60)   // in seconds
+	viper.SetDefault("CACHE_WARM_INTERVAL", 60) // in minutes, 0 warms only at startup
+	viper.SetDefault("CACHE_WARM_BEFORE_READY", false)
+
 	if err := viper.ReadInConfig(); err != nil {
 		log.Println("Warning: .env file not found, using default values")
 	}
 
 	redisConfig := RedisConfig{
-		Host:         viper.GetString("REDIS_HOST"),
-		Port:         viper.GetString("REDIS_PORT"),
-		Password:     viper.GetString("REDIS_PASSWORD"),
-		DB:           viper.GetInt("REDIS_DB"),
-		PoolSize:     viper.GetInt("REDIS_POOL_SIZE"),
-		MinIdleConns: viper.GetInt("REDIS_MIN_IDLE_CONNS"),
-		MaxRetries:   viper.GetInt("REDIS_MAX_RETRIES"),
+		Mode:             viper.GetString("REDIS_MODE"),
+		Host:             viper.GetString("REDIS_HOST"),
+		Port:             viper.GetString("REDIS_PORT"),
+		Username:         viper.GetString("REDIS_USERNAME"),
+		Password:         viper.GetString("REDIS_PASSWORD"),
+		MasterName:       viper.GetString("REDIS_MASTER_NAME"),
+		SentinelPassword: viper.GetString("REDIS_SENTINEL_PASSWORD"),
+		TLS:              viper.GetBool("REDIS_TLS"),
+		TLSCAFile:        viper.GetString("REDIS_TLS_CA_FILE"),
+		TLSServerName:    viper.GetString("REDIS_TLS_SERVER_NAME"),
+		DB:               viper.GetInt("REDIS_DB"),
+		PoolSize:         viper.GetInt("REDIS_POOL_SIZE"),
+		MinIdleConns:     viper.GetInt("REDIS_MIN_IDLE_CONNS"),
+		MaxRetries:       viper.GetInt("REDIS_MAX_RETRIES"),
+		Timeout:          time.Duration(viper.GetInt("REDIS_TIMEOUT")) * time.Millisecond,
+		BreakerThreshold: viper.GetInt("REDIS_BREAKER_THRESHOLD"),
+		BreakerCooldown:  time.Duration(viper.GetInt("REDIS_BREAKER_COOLDOWN")) * time.Second,
 	}
 
 	redisConfig.Addr = fmt.Sprintf("%s:%s", redisConfig.Host, redisConfig.Port)
+	for _, addr := range strings.Split(viper.GetString("REDIS_ADDRS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			redisConfig.Addrs = append(redisConfig.Addrs, addr)
+		}
+	}
+	if len(redisConfig.Addrs) == 0 {
+		redisConfig.Addrs = []string{redisConfig.Addr}
+	}
 
 	dbConfig := DatabaseConfig{
 		Host:            viper.GetString("DB_HOST"),
@@ -119,6 +263,16 @@ func Load() (*Config, error) {
 		MinConns:        viper.GetInt("DB_MIN_CONNS"),
 		ConnMaxLifetime: time.Duration(viper.GetInt("DB_CONN_MAX_LIFETIME")) * time.Minute,
 		MaxConnIdleTime: time.Duration(viper.GetInt("DB_CONN_MAX_IDLE_TIME")) * time.Second,
+		AutoMigrate:     viper.GetBool("DB_AUTO_MIGRATE"),
+
+		ReplicaMaxLag:        time.Duration(viper.GetInt("DB_REPLICA_MAX_LAG")) * time.Second,
+		ReplicaCheckInterval: time.Duration(viper.GetInt("DB_REPLICA_CHECK_INTERVAL")) * time.Second,
+	}
+
+	for _, url := range strings.Split(viper.GetString("DB_REPLICA_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			dbConfig.ReplicaURLs = append(dbConfig.ReplicaURLs, url)
+		}
 	}
 
 	dbConfig.URL = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
@@ -129,6 +283,18 @@ func Load() (*Config, error) {
 		dbConfig.Name,
 	)
 
+	apiKeys := make(map[string]string)
+	for _, pair := range strings.Split(viper.GetString("AUDIT_API_KEYS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		actor, key, ok := strings.Cut(pair, ":")
+		if actor, key = strings.TrimSpace(actor), strings.TrimSpace(key); !ok || actor == "" || key == "" {
+			return nil, fmt.Errorf("AUDIT_API_KEYS entries must be actor:key pairs")
+		}
+		apiKeys[key] = actor
+	}
+
 	logConfig := LogConfig{
 		Level:      viper.GetString("LOG_LEVEL"),
 		Format:     viper.GetString("LOG_FORMAT"),
@@ -139,6 +305,24 @@ func Load() (*Config, error) {
 		Compress:   viper.GetBool("LOG_COMPRESS"),
 	}
 
+	analyticsConfig := AnalyticsConfig{
+		SketchFlushInterval: time.Duration(viper.GetInt("ANALYTICS_SKETCH_FLUSH_INTERVAL")) * time.Second,
+		UAPatternsPath:      viper.GetString("ANALYTICS_UA_PATTERNS_PATH"),
+		GeoIPDBPath:         viper.GetString("ANALYTICS_GEOIP_DB_PATH"),
+		GeoIPReloadInterval: time.Duration(viper.GetInt("ANALYTICS_GEOIP_RELOAD_INTERVAL")) * time.Second,
+		CrawlerRangesPath:   viper.GetString("ANALYTICS_CRAWLER_RANGES_PATH"),
+		BotMinClickDelay:    time.Duration(viper.GetInt("ANALYTICS_BOT_MIN_CLICK_DELAY")) * time.Millisecond,
+		RollupInterval:      time.Duration(viper.GetInt("ANALYTICS_ROLLUP_INTERVAL")) * time.Second,
+		RollupBatchSize:     viper.GetInt("ANALYTICS_ROLLUP_BATCH_SIZE"),
+	}
+
+	previewConfig := PreviewConfig{
+		FetchTimeout: time.Duration(viper.GetInt("PREVIEW_FETCH_TIMEOUT")) * time.Second,
+		MaxBodySize:  viper.GetInt64("PREVIEW_MAX_BODY_SIZE") << 10,
+		Workers:      viper.GetInt("PREVIEW_WORKERS"),
+		QueueSize:    viper.GetInt("PREVIEW_QUEUE_SIZE"),
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port:            viper.GetString("SERVER_PORT"),
@@ -147,9 +331,39 @@ func Load() (*Config, error) {
 			ReadTimeout:     time.Duration(viper.GetInt("SERVER_READ_TIMEOUT")) * time.Second,
 			WriteTimeout:    time.Duration(viper.GetInt("SERVER_WRITE_TIMEOUT")) * time.Second,
 		},
-		Redis:    redisConfig,
-		Database: dbConfig,
-		Log:      logConfig,
+		Redis:     redisConfig,
+		Database:  dbConfig,
+		Log:       logConfig,
+		Analytics: analyticsConfig,
+		Preview:   previewConfig,
+		Audit: AuditConfig{
+			LogPath: viper.GetString("AUDIT_LOG_PATH"),
+			APIKeys: apiKeys,
+		},
+		Webhook: WebhookConfig{
+			PollInterval:   time.Duration(viper.GetInt("WEBHOOK_POLL_INTERVAL")) * time.Second,
+			Timeout:        time.Duration(viper.GetInt("WEBHOOK_TIMEOUT")) * time.Second,
+			MaxAttempts:    viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+			RetryBaseDelay: time.Duration(viper.GetInt("WEBHOOK_RETRY_BASE_DELAY")) * time.Second,
+			RetryMaxDelay:  time.Duration(viper.GetInt("WEBHOOK_RETRY_MAX_DELAY")) * time.Second,
+			BatchSize:      viper.GetInt("WEBHOOK_BATCH_SIZE"),
+		},
+		Cache: CacheConfig{
+			L1MaxBytes:             viper.GetInt64("CACHE_L1_MAX_SIZE") << 20,
+			L1TTL:                  time.Duration(viper.GetInt("CACHE_L1_TTL")) * time.Second,
+			BloomCapacity:          viper.GetUint64("CACHE_BLOOM_CAPACITY"),
+			BloomFalsePositiveRate: viper.GetFloat64("CACHE_BLOOM_FALSE_POSITIVE_RATE"),
+			BloomRebuildInterval:   time.Duration(viper.GetInt("CACHE_BLOOM_REBUILD_INTERVAL")) * time.Minute,
+			FillWorkers:            viper.GetInt("CACHE_FILL_WORKERS"),
+			FillQueueSize:          viper.GetInt("CACHE_FILL_QUEUE_SIZE"),
+			WarmTopN:               viper.GetInt("CACHE_WARM_TOP_N"),
+			WarmWindow:             time.Duration(viper.GetInt("CACHE_WARM_WINDOW")) * time.Hour,
+			WarmBatchSize:          viper.GetInt("CACHE_WARM_BATCH_SIZE"),
+			WarmConcurrency:        viper.GetInt("CACHE_WARM_CONCURRENCY"),
+			WarmBudget:             time.Duration(viper.GetInt("CACHE_WARM_BUDGET")) * time.Second,
+			WarmInterval:           time.Duration(viper.GetInt("CACHE_WARM_INTERVAL")) * time.Minute,
+			WarmBeforeReady:        viper.GetBool("CACHE_WARM_BEFORE_READY"),
+		},
 	}
 
 	return cfg, nil
@@ -2,40 +2,195 @@ package domain
 
 import "time"
 
+const (
+	BotReasonUserAgent   = "user_agent"
+	BotReasonCrawlerIP   = "crawler_ip"
+	BotReasonHeadRequest = "head_request"
+	BotReasonTooFast     = "too_fast"
+)
+
 type URLClick struct {
-	ID          int64     `json:"id"`
-	URLID       int64     `json:"url_id"`
+	ID             int64     `json:"id"`
+	URLID          int64     `json:"url_id"`
+	ShortCode      string    `json:"short_code,omitempty"`
+	URLVersion     int       `json:"url_version"`
+	ClickedAt      time.Time `json:"clicked_at"`
+	UserAgent      string    `json:"user_agent"`
+	Referer        string    `json:"referer"`
+	ReferrerHost   string    `json:"referrer_host,omitempty"`
+	ReferrerSource string    `json:"referrer_source"`
+	UTMSource      string    `json:"utm_source,omitempty"`
+	UTMMedium      string    `json:"utm_medium,omitempty"`
+	UTMCampaign    string    `json:"utm_campaign,omitempty"`
+	UTMTerm        string    `json:"utm_term,omitempty"`
+	UTMContent     string    `json:"utm_content,omitempty"`
+	IPAddress      string    `json:"ip_address"`
+	CountryCode    string    `json:"country_code,omitempty"`
+	Region         string    `json:"region,omitempty"`
+	City           string    `json:"city,omitempty"`
+	DeviceType     string    `json:"device_type"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version,omitempty"`
+	OS             string    `json:"os"`
+	OSVersion      string    `json:"os_version,omitempty"`
+	IsBot          bool      `json:"is_bot"`
+	BotReason      string    `json:"bot_reason,omitempty"`
+}
+
+type ClickRequest struct {
+	URLID          int64
+	ShortCode      string
+	URLVersion     int
+	UserAgent      string
+	Referer        string
+	ReferrerHost   string
+	ReferrerSource string
+	UTMSource      string
+	UTMMedium      string
+	UTMCampaign    string
+	UTMTerm        string
+	UTMContent     string
+	IPAddress      string
+	CountryCode    string
+	Region         string
+	City           string
+	DeviceType     string
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	IsBot          bool
+	BotReason      string
+}
+
+type ClickEvent struct {
 	ClickedAt   time.Time `json:"clicked_at"`
-	UserAgent   string    `json:"user_agent"`
+	DeviceType  string    `json:"device_type"`
 	Referer     string    `json:"referer"`
-	IPAddress   string    `json:"ip_address"`
+	Source      string    `json:"source"`
 	CountryCode string    `json:"country_code,omitempty"`
-	DeviceType  string    `json:"device_type"`
+	TotalClicks int64     `json:"total_clicks"`
 }
 
-type ClickRequest struct {
-	URLID      int64
-	UserAgent  string
-	Referer    string
-	IPAddress  string
-	DeviceType string
+type URLAnalytics struct {
+	ShortCode       string          `json:"short_code"`
+	OriginalURL     string          `json:"original_url"`
+	TotalClicks     int64           `json:"total_clicks"`
+	BotClicks       int64           `json:"bot_clicks"`
+	UniqueIPs       int64           `json:"unique_ips"`
+	UniqueVisitors  int64           `json:"unique_visitors"`
+	LastClickedAt   *time.Time      `json:"last_clicked_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	From            time.Time       `json:"from"`
+	To              time.Time       `json:"to"`
+	Granularity     string          `json:"granularity"`
+	Timezone        string          `json:"timezone"`
+	Comparison      *Comparison     `json:"comparison"`
+	ClicksByDate    []ClicksByDate  `json:"clicks_by_date"`
+	TopReferrers    []ReferrerStats `json:"top_referrers"`
+	TopSources      []SourceStats   `json:"top_sources"`
+	TopHosts        []HostStats     `json:"top_referrer_hosts"`
+	UTM             UTMStats        `json:"utm"`
+	DeviceStats     DeviceStats     `json:"device_stats"`
+	TopBrowsers     []BrowserStats  `json:"top_browsers"`
+	TopOS           []OSStats       `json:"top_os"`
+	TopCountries    []CountryStats  `json:"top_countries"`
+	TopCities       []CityStats     `json:"top_cities"`
+	ClicksByVersion []VersionStats  `json:"clicks_by_version"`
 }
 
-type URLAnalytics struct {
-	ShortCode     string          `json:"short_code"`
-	OriginalURL   string          `json:"original_url"`
-	TotalClicks   int64           `json:"total_clicks"`
-	UniqueIPs     int64           `json:"unique_ips"`
-	LastClickedAt *time.Time      `json:"last_clicked_at"`
-	CreatedAt     time.Time       `json:"created_at"`
-	ClicksByDate  []ClicksByDate  `json:"clicks_by_date"`
-	TopReferrers  []ReferrerStats `json:"top_referrers"`
-	DeviceStats   DeviceStats     `json:"device_stats"`
+// AnalyticsQuery selects the period that per-link analytics are computed over.
+type AnalyticsQuery struct {
+	Range       *TimeRange
+	IncludeBots bool
+}
+
+// Comparison sets a period against the equal-length period right before it.
+type Comparison struct {
+	ClicksComparison
+	UniqueVisitors         int64 `json:"unique_visitors"`
+	PreviousUniqueVisitors int64 `json:"previous_unique_visitors"`
+}
+
+// ClicksComparison is the click count of a period and of the equal-length
+// period before it. ClicksChange is the percentage change, and nil when the
+// previous period had no clicks.
+type ClicksComparison struct {
+	Clicks         int64     `json:"clicks"`
+	PreviousClicks int64     `json:"previous_clicks"`
+	ClicksChange   *float64  `json:"clicks_change"`
+	PreviousFrom   time.Time `json:"previous_from"`
+	PreviousTo     time.Time `json:"previous_to"`
+}
+
+func NewClicksComparison(clicks, previousClicks int64, previous *TimeRange) ClicksComparison {
+	comparison := ClicksComparison{
+		Clicks:         clicks,
+		PreviousClicks: previousClicks,
+		PreviousFrom:   previous.From,
+		PreviousTo:     previous.To,
+	}
+	if previousClicks > 0 {
+		change := float64(clicks-previousClicks) / float64(previousClicks) * 100
+		comparison.ClicksChange = &change
+	}
+	return comparison
+}
+
+// LinkSet selects the links that listings and overview analytics cover. An
+// empty set matches every link.
+type LinkSet struct {
+	ShortCodes  []string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Tag         string
+	Folder      string
+	Owner       string
+}
+
+// AnalyticsOverview aggregates the clicks of a set of links over a period.
+// ActiveLinks counts the links clicked in the period and LinksCreated the
+// links created in it.
+type AnalyticsOverview struct {
+	From         time.Time         `json:"from"`
+	To           time.Time         `json:"to"`
+	Granularity  string            `json:"granularity"`
+	Timezone     string            `json:"timezone"`
+	TotalClicks  int64             `json:"total_clicks"`
+	ActiveLinks  int64             `json:"active_links"`
+	LinksCreated int64             `json:"links_created"`
+	Comparison   *ClicksComparison `json:"comparison"`
+	ClicksByDate []ClicksByDate    `json:"clicks_by_date"`
+}
+
+type TopLinks struct {
+	From     time.Time   `json:"from"`
+	To       time.Time   `json:"to"`
+	Timezone string      `json:"timezone"`
+	Links    []LinkStats `json:"links"`
+}
+
+// LinkStats is the click count of a link over a period and over the
+// equal-length period before it.
+type LinkStats struct {
+	ShortCode      string    `json:"short_code"`
+	OriginalURL    string    `json:"original_url"`
+	CreatedAt      time.Time `json:"created_at"`
+	Clicks         int64     `json:"clicks"`
+	PreviousClicks int64     `json:"previous_clicks"`
 }
 
 type ClicksByDate struct {
-	Date  string `json:"date"`
-	Count int64  `json:"count"`
+	Date           string `json:"date"`
+	Count          int64  `json:"count"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
+
+// VersionStats counts the clicks made while a version of a link was live.
+type VersionStats struct {
+	Version     int    `json:"version"`
+	OriginalURL string `json:"original_url"`
+	Count       int64  `json:"count"`
 }
 
 type ReferrerStats struct {
@@ -43,6 +198,49 @@ type ReferrerStats struct {
 	Count   int64  `json:"count"`
 }
 
+type SourceStats struct {
+	Source string `json:"source"`
+	Count  int64  `json:"count"`
+}
+
+type HostStats struct {
+	Host  string `json:"host"`
+	Count int64  `json:"count"`
+}
+
+type UTMStats struct {
+	Sources   []UTMValueStats `json:"sources"`
+	Mediums   []UTMValueStats `json:"mediums"`
+	Campaigns []UTMValueStats `json:"campaigns"`
+}
+
+type UTMValueStats struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type BrowserStats struct {
+	Browser string `json:"browser"`
+	Count   int64  `json:"count"`
+}
+
+type OSStats struct {
+	OS    string `json:"os"`
+	Count int64  `json:"count"`
+}
+
+type CountryStats struct {
+	CountryCode string `json:"country_code"`
+	Count       int64  `json:"count"`
+}
+
+type CityStats struct {
+	City        string `json:"city"`
+	Region      string `json:"region,omitempty"`
+	CountryCode string `json:"country_code"`
+	Count       int64  `json:"count"`
+}
+
 type DeviceStats struct {
 	Mobile  int64 `json:"mobile"`
 	Desktop int64 `json:"desktop"`
@@ -51,10 +249,37 @@ type DeviceStats struct {
 	Unknown int64 `json:"unknown"`
 }
 
+// ClickFilter selects clicks for history and exports. A zero URLID matches
+// the clicks of every link, and After continues from a previous page.
+type ClickFilter struct {
+	URLID        int64
+	From         *time.Time
+	To           *time.Time
+	DeviceType   string
+	ReferrerHost string
+	CountryCode  string
+	IncludeBots  bool
+	After        *ClickCursor
+}
+
+// ClickHistory is one page of clicks. Total, Page and TotalPages are only set
+// when paging by page number; cursor pages set NextCursor instead.
 type ClickHistory struct {
 	Clicks     []URLClick `json:"clicks"`
 	Total      int64      `json:"total"`
 	Page       int        `json:"page"`
 	PageSize   int        `json:"page_size"`
 	TotalPages int        `json:"total_pages"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// AllTimeSketch is the Day value of a link's lifetime visitor sketch.
+const AllTimeSketch = ""
+
+// VisitorSketch is a serialized HyperLogLog of the visitors of a link, either
+// for a single day (YYYY-MM-DD) or for the link's whole lifetime.
+type VisitorSketch struct {
+	URLID int64
+	Day   string
+	Data  []byte
 }
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditURLCreate   = "url.create"
+	AuditURLUpdate   = "url.update"
+	AuditURLRollback = "url.rollback"
+	AuditTagRename   = "tag.rename"
+	AuditTagMerge    = "tag.merge"
+	AuditTagDelete   = "tag.delete"
+
+	AuditWebhookCreate = "webhook.create"
+	AuditWebhookDelete = "webhook.delete"
+)
+
+// AuditEvent records an administrative action. Target is the short code, tag
+// or webhook ID the action applied to, and Before and After hold its state on
+// either side of the change.
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Target     string          `json:"target"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	ClientIP   string          `json:"client_ip,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+}
+
+// AuditFilter selects audit events. Empty fields match every event.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	From   *time.Time
+	To     *time.Time
+}
+
+// AuditLog is one page of audit events, newest first.
+type AuditLog struct {
+	Events     []AuditEvent `json:"events"`
+	Total      int64        `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalPages int          `json:"total_pages"`
+}
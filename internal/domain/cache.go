@@ -0,0 +1,36 @@
+package domain
+
+import "errors"
+
+// ErrInvalidCacheEntry is returned for cached links that can't be decoded,
+// such as ones written in a format this version doesn't know.
+var ErrInvalidCacheEntry = errors.New("invalid cache entry")
+
+// Cache tiers a redirect can be served from, reported in X-Cache-Hit.
+const (
+	CacheTierL1   = "l1"
+	CacheTierL2   = "l2"
+	CacheTierMiss = "miss"
+)
+
+// CacheStats counts where redirects were served from since the process
+// started. L1 is the in-process cache and L2 the shared Redis cache.
+// Filtered lookups were ruled out by the short code index, NegativeHits found
+// a cached not-found in Redis, and Misses went to the database. L2HitRatio,
+// counting negative hits, is over the lookups that reached Redis.
+// EarlyRefreshes counts Redis hits that also reloaded the link ahead of its
+// expiry.
+type CacheStats struct {
+	Lookups        int64   `json:"lookups"`
+	L1Hits         int64   `json:"l1_hits"`
+	Filtered       int64   `json:"filtered"`
+	L2Hits         int64   `json:"l2_hits"`
+	NegativeHits   int64   `json:"negative_hits"`
+	Misses         int64   `json:"misses"`
+	L1HitRatio     float64 `json:"l1_hit_ratio"`
+	L2HitRatio     float64 `json:"l2_hit_ratio"`
+	HitRatio       float64 `json:"hit_ratio"`
+	L1Entries      int     `json:"l1_entries"`
+	L1Bytes        int64   `json:"l1_bytes"`
+	EarlyRefreshes int64   `json:"early_refreshes"`
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ClickCursor marks a position in clicks ordered by (clicked_at, id).
+type ClickCursor struct {
+	ClickedAt time.Time
+	ID        int64
+}
+
+func (c ClickCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.ClickedAt.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeClickCursor(token string) (*ClickCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var micros, id int64
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &micros, &id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &ClickCursor{ClickedAt: time.UnixMicro(micros).UTC(), ID: id}, nil
+}
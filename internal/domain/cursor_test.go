@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickCursor_RoundTrip(t *testing.T) {
+	cursor := ClickCursor{ClickedAt: time.Date(2025, 12, 26, 10, 30, 0, 123456000, time.UTC), ID: 987654321}
+
+	decoded, err := DecodeClickCursor(cursor.Encode())
+	require.NoError(t, err)
+
+	assert.True(t, cursor.ClickedAt.Equal(decoded.ClickedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeClickCursor_Invalid(t *testing.T) {
+	for _, token := range []string{"", "!!!", "bm90LWEtY3Vyc29y"} {
+		_, err := DecodeClickCursor(token)
+		assert.ErrorIs(t, err, ErrInvalidCursor, token)
+	}
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+	ErrInvalidTag  = errors.New("invalid tag")
+)
+
+type Tag struct {
+	Name      string    `json:"name"`
+	LinkCount int64     `json:"link_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type MergeTagRequest struct {
+	Into string `json:"into" validate:"required,max=50"`
+}
+
+// NormalizeTag makes tag names case-insensitive, so "Promo" and "promo " are
+// the same tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// NormalizeTags normalizes names, dropping empty and repeated ones.
+func NormalizeTags(names []string) []string {
+	tags := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		tag := NormalizeTag(name)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
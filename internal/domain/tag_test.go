@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags([]string{"Promo", " promo ", "", "Black Friday", "  "})
+
+	assert.Equal(t, []string{"promo", "black friday"}, tags)
+}
+
+func TestNormalizeTags_Empty(t *testing.T) {
+	assert.Empty(t, NormalizeTags(nil))
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+
+	MaxTimeRangeBuckets = 1000
+
+	// bucketKeyLayout identifies a bucket by its local wall-clock start.
+	bucketKeyLayout = "2006-01-02T15"
+)
+
+// TimeRange is the half-open interval [From, To) split into buckets of one
+// granularity, with bucket boundaries at local midnight (or hour) in Location.
+// Weeks start on Monday.
+type TimeRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Location    *time.Location
+}
+
+func NewTimeRange(from, to time.Time, granularity, timezone string) (*TimeRange, error) {
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	switch granularity {
+	case GranularityHour, GranularityDay, GranularityWeek, GranularityMonth:
+	default:
+		return nil, fmt.Errorf("granularity must be one of hour, day, week or month")
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", timezone)
+	}
+
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	r := &TimeRange{From: from, To: to, Granularity: granularity, Location: loc}
+	if n := len(r.Buckets()); n > MaxTimeRangeBuckets {
+		return nil, fmt.Errorf("range has %d %s buckets, at most %d are allowed", n, granularity, MaxTimeRangeBuckets)
+	}
+
+	return r, nil
+}
+
+// Truncate returns the start of the bucket containing t.
+func (r *TimeRange) Truncate(t time.Time) time.Time {
+	t = t.In(r.Location)
+	year, month, day := t.Date()
+
+	switch r.Granularity {
+	case GranularityHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, r.Location)
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, r.Location)
+	case GranularityMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, r.Location)
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, r.Location)
+}
+
+func (r *TimeRange) next(bucket time.Time) time.Time {
+	year, month, day := bucket.Date()
+
+	switch r.Granularity {
+	case GranularityHour:
+		// step in absolute time so DST transitions neither skip nor repeat
+		// an hour, then realign to the wall clock; a repeated wall-clock hour
+		// resolves to its first occurrence, so keep the absolute step there
+		next := r.Truncate(bucket.Add(time.Hour))
+		if !next.After(bucket) {
+			next = bucket.Add(time.Hour)
+		}
+		return next
+	case GranularityWeek:
+		return time.Date(year, month, day+7, 0, 0, 0, 0, r.Location)
+	case GranularityMonth:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, r.Location)
+	}
+	return time.Date(year, month, day+1, 0, 0, 0, 0, r.Location)
+}
+
+// Buckets returns the start of every bucket overlapping the range, in order.
+func (r *TimeRange) Buckets() []time.Time {
+	var buckets []time.Time
+	for bucket := r.Truncate(r.From); bucket.Before(r.To); bucket = r.next(bucket) {
+		if n := len(buckets); n > 0 && BucketKey(buckets[n-1]) == BucketKey(bucket) {
+			continue
+		}
+		buckets = append(buckets, bucket)
+		if len(buckets) > MaxTimeRangeBuckets {
+			break
+		}
+	}
+	return buckets
+}
+
+// Previous returns the range of equal length that ends where r starts.
+func (r *TimeRange) Previous() *TimeRange {
+	length := r.To.Sub(r.From)
+	return &TimeRange{
+		From:        r.From.Add(-length),
+		To:          r.From,
+		Granularity: r.Granularity,
+		Location:    r.Location,
+	}
+}
+
+// FormatBucket renders a bucket start as a date, or as a timestamp with its
+// UTC offset for hourly buckets.
+func (r *TimeRange) FormatBucket(bucket time.Time) string {
+	if r.Granularity == GranularityHour {
+		return bucket.In(r.Location).Format(time.RFC3339)
+	}
+	return bucket.In(r.Location).Format("2006-01-02")
+}
+
+// BucketKey identifies a bucket by its wall-clock start, matching the local
+// timestamps that Postgres groups by.
+func BucketKey(wallClock time.Time) string {
+	return wallClock.Format(bucketKeyLayout)
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func formatBuckets(r *TimeRange) []string {
+	var formatted []string
+	for _, bucket := range r.Buckets() {
+		formatted = append(formatted, r.FormatBucket(bucket))
+	}
+	return formatted
+}
+
+func TestTimeRange_Buckets(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, to    string
+		granularity string
+		timezone    string
+		want        []string
+	}{
+		{
+			name:        "days include partial first and last day",
+			from:        "2025-12-29T15:00:00Z",
+			to:          "2026-01-01T03:00:00Z",
+			granularity: GranularityDay,
+			want:        []string{"2025-12-29", "2025-12-30", "2025-12-31", "2026-01-01"},
+		},
+		{
+			name:        "days follow the timezone",
+			from:        "2025-12-29T15:00:00Z",
+			to:          "2025-12-30T15:00:00Z",
+			granularity: GranularityDay,
+			timezone:    "Asia/Jakarta",
+			want:        []string{"2025-12-29", "2025-12-30"},
+		},
+		{
+			name:        "weeks start on Monday",
+			from:        "2025-12-31T00:00:00Z",
+			to:          "2026-01-13T00:00:00Z",
+			granularity: GranularityWeek,
+			want:        []string{"2025-12-29", "2026-01-05", "2026-01-12"},
+		},
+		{
+			name:        "months",
+			from:        "2025-11-15T00:00:00Z",
+			to:          "2026-02-01T00:00:00Z",
+			granularity: GranularityMonth,
+			want:        []string{"2025-11-01", "2025-12-01", "2026-01-01"},
+		},
+		{
+			name:        "hours carry the UTC offset",
+			from:        "2025-12-29T00:30:00Z",
+			to:          "2025-12-29T03:00:00Z",
+			granularity: GranularityHour,
+			timezone:    "Asia/Jakarta",
+			want:        []string{"2025-12-29T07:00:00+07:00", "2025-12-29T08:00:00+07:00", "2025-12-29T09:00:00+07:00"},
+		},
+		{
+			name:        "spring forward skips the missing hour",
+			from:        "2025-03-09T06:00:00Z",
+			to:          "2025-03-09T09:00:00Z",
+			granularity: GranularityHour,
+			timezone:    "America/New_York",
+			want:        []string{"2025-03-09T01:00:00-05:00", "2025-03-09T03:00:00-04:00", "2025-03-09T04:00:00-04:00"},
+		},
+		{
+			name:        "fall back merges the repeated hour",
+			from:        "2025-11-02T05:00:00Z",
+			to:          "2025-11-02T08:00:00Z",
+			granularity: GranularityHour,
+			timezone:    "America/New_York",
+			want:        []string{"2025-11-02T01:00:00-04:00", "2025-11-02T02:00:00-05:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, err := time.Parse(time.RFC3339, tt.from)
+			require.NoError(t, err)
+			to, err := time.Parse(time.RFC3339, tt.to)
+			require.NoError(t, err)
+
+			r, err := NewTimeRange(from, to, tt.granularity, tt.timezone)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, formatBuckets(r))
+		})
+	}
+}
+
+func TestNewTimeRange_Invalid(t *testing.T) {
+	now := time.Now()
+
+	_, err := NewTimeRange(now, now.Add(time.Hour), "minute", "")
+	assert.Error(t, err)
+
+	_, err = NewTimeRange(now, now.Add(time.Hour), GranularityHour, "Mars/Olympus_Mons")
+	assert.Error(t, err)
+
+	_, err = NewTimeRange(now, now, GranularityDay, "")
+	assert.Error(t, err)
+
+	_, err = NewTimeRange(now.AddDate(-1, 0, 0), now, GranularityHour, "")
+	assert.ErrorContains(t, err, "at most")
+}
+
+func TestTimeRange_Previous(t *testing.T) {
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 8, 0, 0, 0, 0, time.UTC)
+
+	r, err := NewTimeRange(from, to, GranularityDay, "")
+	require.NoError(t, err)
+
+	previous := r.Previous()
+	assert.Equal(t, time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC), previous.From)
+	assert.Equal(t, from, previous.To)
+}
+
+func TestBucketKey_MatchesWallClock(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+
+	local := time.Date(2025, 12, 29, 7, 0, 0, 0, jakarta)
+	wallClock := time.Date(2025, 12, 29, 7, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, BucketKey(local), BucketKey(wallClock))
+}
@@ -1,20 +1,115 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrURLNotFound is returned for short codes known not to exist.
+var ErrURLNotFound = errors.New("URL not found")
 
 type URL struct {
-	ID          int64      `json:"id"`
-	ShortCode   string     `json:"short_code"`
-	OriginalURL string     `json:"original_url"`
-	ClickCount  int64      `json:"click_count"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	ExpiresAt   *time.Time `json:"expires_at"`
-	IsActive    bool       `json:"is_active"`
+	ID          int64        `json:"id"`
+	ShortCode   string       `json:"short_code"`
+	OriginalURL string       `json:"original_url"`
+	ClickCount  int64        `json:"click_count"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	ExpiresAt   *time.Time   `json:"expires_at"`
+	IsActive    bool         `json:"is_active"`
+	Version     int          `json:"version"`
+	Title       string       `json:"title,omitempty"`
+	Notes       string       `json:"notes,omitempty"`
+	Folder      string       `json:"folder,omitempty"`
+	Owner       string       `json:"owner,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	SocialCard  SocialCard   `json:"social_card"`
+	Preview     *LinkPreview `json:"preview,omitempty"`
+}
+
+// SocialCard is the Open Graph title, description and image shown when a link
+// is unfurled in chat apps and social networks. Empty fields fall back to the
+// link's fetched preview.
+type SocialCard struct {
+	Title       string `json:"og_title,omitempty"`
+	Description string `json:"og_description,omitempty"`
+	Image       string `json:"og_image,omitempty"`
+}
+
+// Card returns the social card unfurlers are served, filling the fields the
+// link doesn't set from its preview and title.
+func (u *URL) Card() SocialCard {
+	preview := u.Preview
+	if preview == nil {
+		preview = &LinkPreview{}
+	}
+
+	return SocialCard{
+		Title:       firstNonEmpty(u.SocialCard.Title, preview.OGTitle, u.Title, preview.PageTitle),
+		Description: firstNonEmpty(u.SocialCard.Description, preview.OGDescription),
+		Image:       firstNonEmpty(u.SocialCard.Image, preview.OGImage),
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// LinkPreview is what the destination page says about itself, fetched in the
+// background after a link is created.
+type LinkPreview struct {
+	PageTitle     string    `json:"page_title,omitempty"`
+	OGTitle       string    `json:"og_title,omitempty"`
+	OGDescription string    `json:"og_description,omitempty"`
+	OGImage       string    `json:"og_image,omitempty"`
+	FaviconURL    string    `json:"favicon_url,omitempty"`
+	FetchedAt     time.Time `json:"fetched_at"`
 }
 
+// CreatedURLRequest describes a new link. Owner is who created it, from the
+// request's API key.
 type CreatedURLRequest struct {
-	OriginalURL string `json:"original_url" validate:"required,url"`
-	CustomAlias string `json:"custom_alias,omitempty" validate:"omitempty,min=4,max=20,alias"`
-	ExpiryHours int    `json:"expiry_hours,omitempty" validate:"omitempty,gte=1"`
+	OriginalURL   string   `json:"original_url" validate:"required,url"`
+	CustomAlias   string   `json:"custom_alias,omitempty" validate:"omitempty,min=4,max=20,alias"`
+	ExpiryHours   int      `json:"expiry_hours,omitempty" validate:"omitempty,gte=1"`
+	Title         string   `json:"title,omitempty" validate:"omitempty,max=255"`
+	Notes         string   `json:"notes,omitempty" validate:"omitempty,max=2000"`
+	Folder        string   `json:"folder,omitempty" validate:"omitempty,max=100"`
+	Tags          []string `json:"tags,omitempty" validate:"omitempty,max=20,dive,min=1,max=50"`
+	OGTitle       string   `json:"og_title,omitempty" validate:"omitempty,max=255"`
+	OGDescription string   `json:"og_description,omitempty" validate:"omitempty,max=1000"`
+	OGImage       string   `json:"og_image,omitempty" validate:"omitempty,max=2048,web_url"`
+	Owner         string   `json:"-"`
+}
+
+// UpdateURLRequest changes where a link points and how it is described,
+// organized and unfurled. Nil fields are left as they are; an empty string
+// clears a field, an empty list removes every tag and an ExpiryHours of 0
+// removes the expiry. ChangedBy is recorded in the link's version history.
+type UpdateURLRequest struct {
+	OriginalURL   *string   `json:"original_url" validate:"omitempty,url"`
+	ExpiryHours   *int      `json:"expiry_hours" validate:"omitempty,gte=0"`
+	IsActive      *bool     `json:"is_active"`
+	Title         *string   `json:"title" validate:"omitempty,max=255"`
+	Notes         *string   `json:"notes" validate:"omitempty,max=2000"`
+	Folder        *string   `json:"folder" validate:"omitempty,max=100"`
+	Tags          *[]string `json:"tags" validate:"omitempty,max=20,dive,min=1,max=50"`
+	OGTitle       *string   `json:"og_title" validate:"omitempty,max=255"`
+	OGDescription *string   `json:"og_description" validate:"omitempty,max=1000"`
+	OGImage       *string   `json:"og_image" validate:"omitempty,max=2048,web_url"`
+	ChangedBy     string    `json:"-"`
+}
+
+// URLList is one page of links.
+type URLList struct {
+	URLs       []URL `json:"urls"`
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalPages int   `json:"total_pages"`
 }
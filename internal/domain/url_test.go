@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLCard_FallsBackToPreviewAndTitle(t *testing.T) {
+	url := &URL{
+		Title:      "Spring sale",
+		SocialCard: SocialCard{Description: "Everything 20% off"},
+		Preview: &LinkPreview{
+			PageTitle:     "Shop | Example",
+			OGDescription: "The example shop",
+			OGImage:       "https://example.com/og.png",
+		},
+	}
+
+	assert.Equal(t, SocialCard{
+		Title:       "Spring sale",
+		Description: "Everything 20% off",
+		Image:       "https://example.com/og.png",
+	}, url.Card())
+}
+
+func TestURLCard_CustomCardWins(t *testing.T) {
+	card := SocialCard{Title: "Custom", Description: "Custom description", Image: "https://cdn.example.com/card.png"}
+	url := &URL{
+		Title:      "Spring sale",
+		SocialCard: card,
+		Preview:    &LinkPreview{OGTitle: "Shop", OGImage: "https://example.com/og.png"},
+	}
+
+	assert.Equal(t, card, url.Card())
+}
+
+func TestURLCard_NoPreview(t *testing.T) {
+	assert.Equal(t, SocialCard{}, (&URL{}).Card())
+}
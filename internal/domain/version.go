@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ChangeCreate   = "create"
+	ChangeUpdate   = "update"
+	ChangeRollback = "rollback"
+)
+
+var ErrVersionNotFound = errors.New("version not found")
+
+// URLVersion is an immutable snapshot of where a link pointed. A new version
+// is recorded whenever its destination, expiry or active flag changes;
+// RestoredVersion is the version a rollback copied.
+type URLVersion struct {
+	Version         int        `json:"version"`
+	OriginalURL     string     `json:"original_url"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	IsActive        bool       `json:"is_active"`
+	ChangeType      string     `json:"change_type"`
+	RestoredVersion *int       `json:"restored_version,omitempty"`
+	ChangedBy       string     `json:"changed_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
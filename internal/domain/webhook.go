@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	EventLinkCreated        = "link.created"
+	EventLinkExpired        = "link.expired"
+	EventLinkClickMilestone = "link.click_milestone"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// Webhook is a subscription to link events. Secret signs every delivery and
+// is only shown when the webhook is created. ClickMilestones are the click
+// counts that trigger link.click_milestone.
+type Webhook struct {
+	ID              int64     `json:"id"`
+	URL             string    `json:"url"`
+	Secret          string    `json:"secret,omitempty"`
+	Events          []string  `json:"events"`
+	ClickMilestones []int64   `json:"click_milestones"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook receives events of the given type.
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMilestone reports whether reaching clicks triggers link.click_milestone.
+func (w *Webhook) HasMilestone(clicks int64) bool {
+	for _, m := range w.ClickMilestones {
+		if m == clicks {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookRequest subscribes a URL to link events. A secret is generated
+// when none is given, and ClickMilestones defaults to the first click.
+type CreateWebhookRequest struct {
+	URL             string   `json:"url" validate:"required,max=2048,web_url"`
+	Secret          string   `json:"secret,omitempty" validate:"omitempty,min=16,max=255"`
+	Events          []string `json:"events" validate:"required,min=1,dive,oneof=link.created link.expired link.click_milestone"`
+	ClickMilestones []int64  `json:"click_milestones,omitempty" validate:"omitempty,max=20,dive,gte=1"`
+}
+
+// WebhookEvent is the JSON body of a delivery. Key identifies what the event
+// is about, so the same event is never queued twice for one webhook.
+type WebhookEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+	Key        string          `json:"-"`
+}
+
+// LinkEventData is the data of link events. Clicks is set for
+// link.click_milestone only.
+type LinkEventData struct {
+	ShortCode   string     `json:"short_code"`
+	OriginalURL string     `json:"original_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Clicks      int64      `json:"clicks,omitempty"`
+}
+
+// WebhookDelivery is one event queued for one webhook. Pending deliveries are
+// retried with backoff until they succeed or run out of attempts, when they
+// are left dead until retried by hand.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// ClaimedDelivery is a due delivery leased to a worker, with what it needs to
+// send it.
+type ClaimedDelivery struct {
+	ID        int64
+	WebhookID int64
+	EventType string
+	Payload   []byte
+	Attempts  int
+	URL       string
+	Secret    string
+}
+
+// DeliveryLog is one page of a webhook's deliveries, newest first.
+type DeliveryLog struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+}
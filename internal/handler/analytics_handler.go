@@ -2,19 +2,35 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
 	"github.com/gamassss/url-shortener/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
 type AnalyticsService interface {
-	GetAnalytics(ctx context.Context, shortCode string, days int) (*domain.URLAnalytics, error)
-	GetClickHistory(ctx context.Context, shortCode string, page, pageSize int) (*domain.ClickHistory, error)
+	GetAnalytics(ctx context.Context, shortCode string, q *domain.AnalyticsQuery) (*domain.URLAnalytics, error)
+	GetClickHistory(ctx context.Context, shortCode string, filter *domain.ClickFilter, page, pageSize int) (*domain.ClickHistory, error)
+	GetClickHistoryAfter(ctx context.Context, shortCode string, filter *domain.ClickFilter, pageSize int) (*domain.ClickHistory, error)
+	StreamClicks(ctx context.Context, shortCode string) (<-chan *domain.ClickEvent, error)
+	ExportClicks(ctx context.Context, shortCode string, filter *domain.ClickFilter, fn func(*domain.URLClick) error) error
+	GetOverview(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet) (*domain.AnalyticsOverview, error)
+	GetTopLinks(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet, limit int) (*domain.TopLinks, error)
 }
 
+// streamHeartbeat keeps idle streams from being dropped by proxies.
+const streamHeartbeat = 15 * time.Second
+
+// maxLinkSetShortCodes caps the short codes a link set can list.
+const maxLinkSetShortCodes = 100
+
 type AnalyticsHandler struct {
 	service AnalyticsService
 }
@@ -30,14 +46,17 @@ func (h *AnalyticsHandler) GetAnalytics(c *gin.Context) {
 		return
 	}
 
-	days := 30
-	if daysParam := c.Query("days"); daysParam != "" {
-		if d, err := strconv.Atoi(daysParam); err == nil && d > 0 && d <= 365 {
-			days = d
-		}
+	timeRange, ok := bindTimeRange(c)
+	if !ok {
+		return
 	}
 
-	analytics, err := h.service.GetAnalytics(c.Request.Context(), shortCode, days)
+	includeBots, _ := strconv.ParseBool(c.Query("include_bots"))
+
+	analytics, err := h.service.GetAnalytics(c.Request.Context(), shortCode, &domain.AnalyticsQuery{
+		Range:       timeRange,
+		IncludeBots: includeBots,
+	})
 	if err != nil {
 		response.NotFound(c, err.Error())
 		return
@@ -46,6 +65,43 @@ func (h *AnalyticsHandler) GetAnalytics(c *gin.Context) {
 	response.Success(c, http.StatusOK, "Analytics retrieved successfully", analytics)
 }
 
+func (h *AnalyticsHandler) GetOverview(c *gin.Context) {
+	q, links, ok := bindOverviewQuery(c)
+	if !ok {
+		return
+	}
+
+	overview, err := h.service.GetOverview(c.Request.Context(), q, links)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Overview retrieved successfully", overview)
+}
+
+func (h *AnalyticsHandler) GetTopLinks(c *gin.Context) {
+	q, links, ok := bindOverviewQuery(c)
+	if !ok {
+		return
+	}
+
+	limit := 10
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	top, err := h.service.GetTopLinks(c.Request.Context(), q, links, limit)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Top links retrieved successfully", top)
+}
+
 func (h *AnalyticsHandler) GetClickHistory(c *gin.Context) {
 	shortCode := c.Param("shortCode")
 	if shortCode == "" {
@@ -67,7 +123,18 @@ func (h *AnalyticsHandler) GetClickHistory(c *gin.Context) {
 		}
 	}
 
-	history, err := h.service.GetClickHistory(c.Request.Context(), shortCode, page, pageSize)
+	filter, ok := bindClickFilter(c)
+	if !ok {
+		return
+	}
+
+	var history *domain.ClickHistory
+	var err error
+	if _, cursorMode := c.GetQuery("cursor"); cursorMode {
+		history, err = h.service.GetClickHistoryAfter(c.Request.Context(), shortCode, filter, pageSize)
+	} else {
+		history, err = h.service.GetClickHistory(c.Request.Context(), shortCode, filter, page, pageSize)
+	}
 	if err != nil {
 		response.NotFound(c, err.Error())
 		return
@@ -75,3 +142,254 @@ func (h *AnalyticsHandler) GetClickHistory(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, "Click history retrieved successfully", history)
 }
+
+func (h *AnalyticsHandler) StreamClicks(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		response.BadRequest(c, "Short code is required")
+		return
+	}
+
+	events, err := h.service.StreamClicks(c.Request.Context(), shortCode)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	// the server write timeout would otherwise cut the stream off
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("click", event)
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
+
+func (h *AnalyticsHandler) ExportClicks(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		response.BadRequest(c, "Short code is required")
+		return
+	}
+
+	h.exportClicks(c, shortCode)
+}
+
+func (h *AnalyticsHandler) ExportAllClicks(c *gin.Context) {
+	h.exportClicks(c, "")
+}
+
+func (h *AnalyticsHandler) exportClicks(c *gin.Context, shortCode string) {
+	filter, ok := bindClickFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", exportFormatCSV)
+	writer, contentType, err := newClickWriter(format, c.Writer)
+	if err != nil {
+		response.BadRequest(c, "Unsupported format, expected csv, ndjson or parquet")
+		return
+	}
+
+	// exports can run far longer than the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	filename := "clicks." + format
+	if shortCode != "" {
+		filename = fmt.Sprintf("clicks-%s.%s", shortCode, format)
+	}
+
+	// headers go out with the first row so a lookup failure can still be
+	// reported as a normal error response
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Status(http.StatusOK)
+		started = true
+	}
+
+	err = h.service.ExportClicks(c.Request.Context(), shortCode, filter, func(click *domain.URLClick) error {
+		start()
+		return writer.Write(click)
+	})
+	if err != nil {
+		if !started {
+			respondURLError(c, err)
+			return
+		}
+		// the status is already sent, so the client only sees a truncated file
+		logger.FromContext(c.Request.Context()).Error("Click export failed", "short_code", shortCode, "error", err)
+		return
+	}
+
+	start()
+	if err := writer.Close(); err != nil {
+		logger.FromContext(c.Request.Context()).Error("Click export failed", "short_code", shortCode, "error", err)
+	}
+}
+
+// bindClickFilter reads the click filter query parameters, responding with
+// 400 Bad Request and returning false when one is invalid.
+func bindClickFilter(c *gin.Context) (*domain.ClickFilter, bool) {
+	filter := &domain.ClickFilter{
+		DeviceType:   strings.ToLower(c.Query("device_type")),
+		ReferrerHost: strings.TrimPrefix(strings.ToLower(c.Query("referrer")), "www."),
+		CountryCode:  strings.ToUpper(c.Query("country")),
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(c.Query("from"), time.UTC); err != nil {
+		response.BadRequest(c, "Invalid from, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return nil, false
+	}
+	if filter.To, err = parseTimeParam(c.Query("to"), time.UTC); err != nil {
+		response.BadRequest(c, "Invalid to, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return nil, false
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if filter.After, err = domain.DecodeClickCursor(cursor); err != nil {
+			response.BadRequest(c, "Invalid cursor")
+			return nil, false
+		}
+	}
+	filter.IncludeBots, _ = strconv.ParseBool(c.Query("include_bots"))
+
+	return filter, true
+}
+
+// bindOverviewQuery reads the time range and the link set of overview
+// analytics, responding with 400 Bad Request and returning false when one is
+// invalid.
+func bindOverviewQuery(c *gin.Context) (*domain.AnalyticsQuery, *domain.LinkSet, bool) {
+	timeRange, ok := bindTimeRange(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	links, ok := bindLinkSet(c, timeRange.Location)
+	if !ok {
+		return nil, nil, false
+	}
+
+	includeBots, _ := strconv.ParseBool(c.Query("include_bots"))
+	return &domain.AnalyticsQuery{Range: timeRange, IncludeBots: includeBots}, links, true
+}
+
+// bindLinkSet reads short_codes, created_from, created_to, tag, folder and owner,
+// responding with 400 Bad Request and returning false when one is invalid.
+// Dates are taken as midnight in loc.
+func bindLinkSet(c *gin.Context, loc *time.Location) (*domain.LinkSet, bool) {
+	links := &domain.LinkSet{
+		Tag:    domain.NormalizeTag(c.Query("tag")),
+		Folder: strings.TrimSpace(c.Query("folder")),
+		Owner:  strings.TrimSpace(c.Query("owner")),
+	}
+
+	if shortCodes := c.Query("short_codes"); shortCodes != "" {
+		for _, code := range strings.Split(shortCodes, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				links.ShortCodes = append(links.ShortCodes, code)
+			}
+		}
+		if len(links.ShortCodes) > maxLinkSetShortCodes {
+			response.BadRequest(c, fmt.Sprintf("At most %d short codes are allowed", maxLinkSetShortCodes))
+			return nil, false
+		}
+	}
+
+	var err error
+	if links.CreatedFrom, err = parseTimeParam(c.Query("created_from"), loc); err != nil {
+		response.BadRequest(c, "Invalid created_from, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return nil, false
+	}
+	if links.CreatedTo, err = parseTimeParam(c.Query("created_to"), loc); err != nil {
+		response.BadRequest(c, "Invalid created_to, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return nil, false
+	}
+
+	return links, true
+}
+
+// bindTimeRange reads from, to, granularity and tz, responding with 400 Bad
+// Request and returning false when they are invalid. Without from, the range
+// covers the last days (default 30, max 365) before to, which defaults to now.
+func bindTimeRange(c *gin.Context) (*domain.TimeRange, bool) {
+	tz := c.DefaultQuery("tz", "UTC")
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		response.BadRequest(c, "Invalid tz, expected an IANA timezone such as Asia/Jakarta")
+		return nil, false
+	}
+
+	from, err := parseTimeParam(c.Query("from"), loc)
+	if err != nil {
+		response.BadRequest(c, "Invalid from, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return nil, false
+	}
+	to, err := parseTimeParam(c.Query("to"), loc)
+	if err != nil {
+		response.BadRequest(c, "Invalid to, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return nil, false
+	}
+
+	if to == nil {
+		now := time.Now()
+		to = &now
+	}
+	if from == nil {
+		days := 30
+		if daysParam := c.Query("days"); daysParam != "" {
+			if d, err := strconv.Atoi(daysParam); err == nil && d > 0 && d <= 365 {
+				days = d
+			}
+		}
+		start := to.AddDate(0, 0, -days)
+		from = &start
+	}
+
+	timeRange, err := domain.NewTimeRange(*from, *to, c.Query("granularity"), tz)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return nil, false
+	}
+
+	return timeRange, true
+}
+
+// parseTimeParam accepts an RFC 3339 timestamp, or a date taken as midnight in loc.
+func parseTimeParam(value string, loc *time.Location) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
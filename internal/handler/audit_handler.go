@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditService interface {
+	ListEvents(ctx context.Context, filter *domain.AuditFilter, page, pageSize int) (*domain.AuditLog, error)
+}
+
+type AuditHandler struct {
+	service AuditService
+}
+
+func NewAuditHandler(service AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+func (h *AuditHandler) ListEvents(c *gin.Context) {
+	page := 1
+	if pageParam := c.Query("page"); pageParam != "" {
+		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	pageSize := 50
+	if sizeParam := c.Query("page_size"); sizeParam != "" {
+		if s, err := strconv.Atoi(sizeParam); err == nil && s > 0 && s <= 500 {
+			pageSize = s
+		}
+	}
+
+	filter := &domain.AuditFilter{
+		Actor:  strings.TrimSpace(c.Query("actor")),
+		Action: strings.TrimSpace(c.Query("action")),
+		Target: strings.TrimSpace(c.Query("target")),
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(c.Query("from"), time.UTC); err != nil {
+		response.BadRequest(c, "Invalid from, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return
+	}
+	if filter.To, err = parseTimeParam(c.Query("to"), time.UTC); err != nil {
+		response.BadRequest(c, "Invalid to, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return
+	}
+
+	events, err := h.service.ListEvents(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Audit events retrieved successfully", events)
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+)
+
+const (
+	exportFormatCSV     = "csv"
+	exportFormatNDJSON  = "ndjson"
+	exportFormatParquet = "parquet"
+)
+
+// exportRow is a click as written to export files. Cursor resumes an
+// interrupted export right after this row.
+type exportRow struct {
+	Cursor         string    `json:"cursor" parquet:"cursor"`
+	ID             int64     `json:"id" parquet:"id"`
+	URLID          int64     `json:"url_id" parquet:"url_id"`
+	ShortCode      string    `json:"short_code" parquet:"short_code"`
+	URLVersion     int       `json:"url_version" parquet:"url_version"`
+	ClickedAt      time.Time `json:"clicked_at" parquet:"clicked_at,timestamp(microsecond)"`
+	UserAgent      string    `json:"user_agent" parquet:"user_agent"`
+	Referer        string    `json:"referer" parquet:"referer"`
+	ReferrerHost   string    `json:"referrer_host" parquet:"referrer_host"`
+	ReferrerSource string    `json:"referrer_source" parquet:"referrer_source"`
+	UTMSource      string    `json:"utm_source" parquet:"utm_source"`
+	UTMMedium      string    `json:"utm_medium" parquet:"utm_medium"`
+	UTMCampaign    string    `json:"utm_campaign" parquet:"utm_campaign"`
+	UTMTerm        string    `json:"utm_term" parquet:"utm_term"`
+	UTMContent     string    `json:"utm_content" parquet:"utm_content"`
+	IPAddress      string    `json:"ip_address" parquet:"ip_address"`
+	CountryCode    string    `json:"country_code" parquet:"country_code"`
+	Region         string    `json:"region" parquet:"region"`
+	City           string    `json:"city" parquet:"city"`
+	DeviceType     string    `json:"device_type" parquet:"device_type"`
+	Browser        string    `json:"browser" parquet:"browser"`
+	BrowserVersion string    `json:"browser_version" parquet:"browser_version"`
+	OS             string    `json:"os" parquet:"os"`
+	OSVersion      string    `json:"os_version" parquet:"os_version"`
+	IsBot          bool      `json:"is_bot" parquet:"is_bot"`
+	BotReason      string    `json:"bot_reason" parquet:"bot_reason"`
+}
+
+var exportCSVHeader = []string{
+	"cursor", "id", "url_id", "short_code", "url_version", "clicked_at", "user_agent", "referer",
+	"referrer_host", "referrer_source", "utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "ip_address",
+	"country_code", "region", "city", "device_type", "browser", "browser_version", "os", "os_version",
+	"is_bot", "bot_reason",
+}
+
+func newExportRow(click *domain.URLClick) exportRow {
+	return exportRow{
+		Cursor:         domain.ClickCursor{ClickedAt: click.ClickedAt, ID: click.ID}.Encode(),
+		ID:             click.ID,
+		URLID:          click.URLID,
+		ShortCode:      click.ShortCode,
+		URLVersion:     click.URLVersion,
+		ClickedAt:      click.ClickedAt.UTC(),
+		UserAgent:      click.UserAgent,
+		Referer:        click.Referer,
+		ReferrerHost:   click.ReferrerHost,
+		ReferrerSource: click.ReferrerSource,
+		UTMSource:      click.UTMSource,
+		UTMMedium:      click.UTMMedium,
+		UTMCampaign:    click.UTMCampaign,
+		UTMTerm:        click.UTMTerm,
+		UTMContent:     click.UTMContent,
+		IPAddress:      click.IPAddress,
+		CountryCode:    click.CountryCode,
+		Region:         click.Region,
+		City:           click.City,
+		DeviceType:     click.DeviceType,
+		Browser:        click.Browser,
+		BrowserVersion: click.BrowserVersion,
+		OS:             click.OS,
+		OSVersion:      click.OSVersion,
+		IsBot:          click.IsBot,
+		BotReason:      click.BotReason,
+	}
+}
+
+type clickWriter interface {
+	Write(click *domain.URLClick) error
+	Close() error
+}
+
+func newClickWriter(format string, w io.Writer) (clickWriter, string, error) {
+	switch format {
+	case exportFormatCSV:
+		return &csvClickWriter{w: csv.NewWriter(w)}, "text/csv", nil
+	case exportFormatNDJSON:
+		return &ndjsonClickWriter{enc: json.NewEncoder(w)}, "application/x-ndjson", nil
+	case exportFormatParquet:
+		return &parquetClickWriter{
+			w: parquet.NewGenericWriter[exportRow](w, parquet.Compression(&snappy.Codec{})),
+		}, "application/vnd.apache.parquet", nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported export format %q", format)
+}
+
+type csvClickWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (cw *csvClickWriter) Write(click *domain.URLClick) error {
+	if !cw.wroteHeader {
+		if err := cw.w.Write(exportCSVHeader); err != nil {
+			return err
+		}
+		cw.wroteHeader = true
+	}
+
+	row := newExportRow(click)
+	return cw.w.Write([]string{
+		row.Cursor,
+		strconv.FormatInt(row.ID, 10),
+		strconv.FormatInt(row.URLID, 10),
+		row.ShortCode,
+		strconv.Itoa(row.URLVersion),
+		row.ClickedAt.Format(time.RFC3339Nano),
+		row.UserAgent,
+		row.Referer,
+		row.ReferrerHost,
+		row.ReferrerSource,
+		row.UTMSource,
+		row.UTMMedium,
+		row.UTMCampaign,
+		row.UTMTerm,
+		row.UTMContent,
+		row.IPAddress,
+		row.CountryCode,
+		row.Region,
+		row.City,
+		row.DeviceType,
+		row.Browser,
+		row.BrowserVersion,
+		row.OS,
+		row.OSVersion,
+		strconv.FormatBool(row.IsBot),
+		row.BotReason,
+	})
+}
+
+func (cw *csvClickWriter) Close() error {
+	if !cw.wroteHeader {
+		if err := cw.w.Write(exportCSVHeader); err != nil {
+			return err
+		}
+	}
+
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type ndjsonClickWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonClickWriter) Write(click *domain.URLClick) error {
+	return nw.enc.Encode(newExportRow(click))
+}
+
+func (nw *ndjsonClickWriter) Close() error {
+	return nil
+}
+
+type parquetClickWriter struct {
+	w *parquet.GenericWriter[exportRow]
+}
+
+func (pw *parquetClickWriter) Write(click *domain.URLClick) error {
+	_, err := pw.w.Write([]exportRow{newExportRow(click)})
+	return err
+}
+
+func (pw *parquetClickWriter) Close() error {
+	return pw.w.Close()
+}
@@ -10,9 +10,22 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// CacheAvailability reports whether the Redis cache is in use, or bypassed
+// after repeated failures.
+type CacheAvailability interface {
+	Available() bool
+}
+
+// Warmup reports whether the cache warm-up at startup has finished.
+type Warmup interface {
+	Ready() bool
+}
+
 type HealthHandler struct {
-	db    *pgxpool.Pool
-	redis *redis.Client
+	db     *pgxpool.Pool
+	redis  redis.UniversalClient
+	cache  CacheAvailability
+	warmup Warmup
 }
 
 type HealthResponse struct {
@@ -31,10 +44,14 @@ type Metadata struct {
 	Timestamp string `json:"timestamp"`
 }
 
-func NewHealthHandler(db *pgxpool.Pool, redis *redis.Client) *HealthHandler {
+// NewHealthHandler creates the handler. warmup may be nil, in which case
+// readiness doesn't wait for the cache to be warmed.
+func NewHealthHandler(db *pgxpool.Pool, redis redis.UniversalClient, cache CacheAvailability, warmup Warmup) *HealthHandler {
 	return &HealthHandler{
-		db:    db,
-		redis: redis,
+		db:     db,
+		redis:  redis,
+		cache:  cache,
+		warmup: warmup,
 	}
 }
 
@@ -50,19 +67,14 @@ func (h *HealthHandler) Readyz(c *gin.Context) {
 	defer cancel()
 
 	checks := make(map[string]Check)
-	allHealthy := true
 
+	// redirects fall back to the database without Redis, so only the database
+	// being down makes the service unready
 	dbCheck := h.checkDatabase(ctx)
 	checks["database"] = dbCheck
-	if dbCheck.Status != "up" {
-		allHealthy = false
-	}
 
 	redisCheck := h.checkRedis(ctx)
 	checks["redis"] = redisCheck
-	if redisCheck.Status != "up" {
-		allHealthy = false
-	}
 
 	response := HealthResponse{
 		Status: "up",
@@ -73,12 +85,23 @@ func (h *HealthHandler) Readyz(c *gin.Context) {
 		},
 	}
 
-	if !allHealthy {
+	if dbCheck.Status != "up" {
 		response.Status = "down"
 		c.JSON(http.StatusServiceUnavailable, response)
 		return
 	}
 
+	if h.warmup != nil && !h.warmup.Ready() {
+		checks["cache_warmup"] = Check{Status: "warming", Message: "preloading popular links"}
+		response.Status = "warming"
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	if redisCheck.Status != "up" {
+		response.Status = "degraded"
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -105,6 +128,13 @@ func (h *HealthHandler) checkRedis(ctx context.Context) Check {
 		}
 	}
 
+	if !h.cache.Available() {
+		return Check{
+			Status:  "degraded",
+			Message: "connected, caching paused after repeated failures",
+		}
+	}
+
 	return Check{
 		Status:  "up",
 		Message: "connected",
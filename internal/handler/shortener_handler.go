@@ -2,11 +2,20 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	"github.com/gamassss/url-shortener/internal/audit"
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
 	"github.com/gamassss/url-shortener/pkg/detector"
+	"github.com/gamassss/url-shortener/pkg/geoip"
+	"github.com/gamassss/url-shortener/pkg/referrer"
 	"github.com/gamassss/url-shortener/pkg/response"
 	"github.com/gamassss/url-shortener/pkg/validator"
 	"github.com/gin-gonic/gin"
@@ -14,17 +23,37 @@ import (
 
 type ShortenerService interface {
 	ShortenURL(ctx context.Context, req *domain.CreatedURLRequest) (*domain.URL, error)
-	GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, bool, error)
+	GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, string, error)
+	CacheStats() *domain.CacheStats
 	RecordClick(ctx context.Context, click *domain.ClickRequest) error
+	ListURLs(ctx context.Context, links *domain.LinkSet, page, pageSize int) (*domain.URLList, error)
+	UpdateURL(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error)
+	GetSocialCard(ctx context.Context, shortCode string) (*domain.SocialCard, error)
+	GetURLHistory(ctx context.Context, shortCode string) ([]domain.URLVersion, error)
+	RollbackURL(ctx context.Context, shortCode string, version int, changedBy string) (*domain.URL, error)
+}
+
+// column sizes of url_clicks
+const (
+	maxFieldLength  = 255
+	maxSourceLength = 100
+)
+
+type GeoLocator interface {
+	Lookup(ip string) geoip.Location
 }
 
 type ShortenerHandler struct {
-	service ShortenerService
-	baseURL string
+	service       ShortenerService
+	baseURL       string
+	geo           GeoLocator
+	minClickDelay time.Duration
+	// clicks counts redirects whose click is still being recorded
+	clicks sync.WaitGroup
 }
 
-func NewShortenerHandler(service ShortenerService, baseURL string) *ShortenerHandler {
-	return &ShortenerHandler{service: service, baseURL: baseURL}
+func NewShortenerHandler(service ShortenerService, baseURL string, geo GeoLocator, minClickDelay time.Duration) *ShortenerHandler {
+	return &ShortenerHandler{service: service, baseURL: baseURL, geo: geo, minClickDelay: minClickDelay}
 }
 
 func (h *ShortenerHandler) ShortenURL(c *gin.Context) {
@@ -44,12 +73,28 @@ func (h *ShortenerHandler) ShortenURL(c *gin.Context) {
 		return
 	}
 
+	req.Owner = audit.ActorFromContext(c.Request.Context()).ID
+
 	url, err := h.service.ShortenURL(c.Request.Context(), &req)
 	if err != nil {
 		response.InternalServerError(c, err.Error())
 		return
 	}
 
+	response.Created(c, "URL shortened successfully", gin.H{
+		"short_url":    h.shortURL(c, url.ShortCode),
+		"short_code":   url.ShortCode,
+		"original_url": url.OriginalURL,
+		"expires_at":   url.ExpiresAt,
+		"title":        url.Title,
+		"folder":       url.Folder,
+		"owner":        url.Owner,
+		"tags":         url.Tags,
+		"social_card":  url.SocialCard,
+	})
+}
+
+func (h *ShortenerHandler) shortURL(c *gin.Context, shortCode string) string {
 	baseURL := h.baseURL
 	if baseURL == "" {
 		scheme := "https"
@@ -63,12 +108,114 @@ func (h *ShortenerHandler) ShortenURL(c *gin.Context) {
 		baseURL = fmt.Sprintf("%s://%s", scheme, c.Request.Host)
 	}
 
-	response.Created(c, "URL shortened successfully", gin.H{
-		"short_url":    baseURL + "/" + url.ShortCode,
-		"short_code":   url.ShortCode,
-		"original_url": url.OriginalURL,
-		"expires_at":   url.ExpiresAt,
-	})
+	return baseURL + "/" + shortCode
+}
+
+func (h *ShortenerHandler) ListURLs(c *gin.Context) {
+	page := 1
+	if pageParam := c.Query("page"); pageParam != "" {
+		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	pageSize := 20
+	if sizeParam := c.Query("page_size"); sizeParam != "" {
+		if s, err := strconv.Atoi(sizeParam); err == nil && s > 0 && s <= 100 {
+			pageSize = s
+		}
+	}
+
+	links, ok := bindLinkSet(c, time.UTC)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.ListURLs(c.Request.Context(), links, page, pageSize)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "URLs retrieved successfully", list)
+}
+
+func (h *ShortenerHandler) UpdateURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		response.BadRequest(c, "Short code is required")
+		return
+	}
+
+	var req domain.UpdateURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid JSON format")
+		return
+	}
+
+	if validationErros := validator.Validate(req); len(validationErros) > 0 {
+		response.ValidationErrors(c, validationErros)
+		return
+	}
+	req.ChangedBy = audit.ActorFromContext(c.Request.Context()).ID
+
+	url, err := h.service.UpdateURL(c.Request.Context(), shortCode, &req)
+	if err != nil {
+		respondURLError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "URL updated successfully", url)
+}
+
+func (h *ShortenerHandler) GetURLHistory(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		response.BadRequest(c, "Short code is required")
+		return
+	}
+
+	versions, err := h.service.GetURLHistory(c.Request.Context(), shortCode)
+	if err != nil {
+		respondURLError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "URL history retrieved successfully", versions)
+}
+
+func (h *ShortenerHandler) RollbackURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		response.BadRequest(c, "Short code is required")
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		response.BadRequest(c, "Invalid version")
+		return
+	}
+
+	actor := audit.ActorFromContext(c.Request.Context())
+	url, err := h.service.RollbackURL(c.Request.Context(), shortCode, version, actor.ID)
+	if err != nil {
+		respondURLError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "URL rolled back successfully", url)
+}
+
+// respondURLError reports a missing link or version as not found, and any
+// other failure as a server error.
+func respondURLError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrURLNotFound), errors.Is(err, domain.ErrVersionNotFound):
+		response.NotFound(c, err.Error())
+	default:
+		response.InternalServerError(c, err.Error())
+	}
 }
 
 func (h *ShortenerHandler) Redirect(c *gin.Context) {
@@ -79,38 +226,108 @@ func (h *ShortenerHandler) Redirect(c *gin.Context) {
 		return
 	}
 
-	url, cacheHit, err := h.service.GetOriginalURL(c.Request.Context(), shortCode)
+	url, cacheTier, err := h.service.GetOriginalURL(c.Request.Context(), shortCode)
 	if err != nil {
 		response.NotFound(c, "URL not found")
 		return
 	}
 
+	userAgent := c.Request.UserAgent()
+	referer := c.Request.Referer()
+	clientIP := detector.GetClientIP(
+		c.Request.RemoteAddr,
+		c.Request.Header.Get("X-Forwarded-For"),
+		c.Request.Header.Get("X-Real-IP"),
+	)
+	isHead := c.Request.Method == http.MethodHead
+	query := c.Request.URL.Query()
+	sinceCreated := time.Since(url.CreatedAt)
+
+	h.clicks.Add(1)
 	go func() {
-		userAgent := c.Request.UserAgent()
-		referer := c.Request.Referer()
-		clientIP := detector.GetClientIP(
-			c.Request.RemoteAddr,
-			c.Request.Header.Get("X-Forwarded-For"),
-			c.Request.Header.Get("X-Real-IP"),
-		)
-		deviceType := detector.DetectDeviceType(userAgent)
+		defer h.clicks.Done()
+		ua := detector.Parse(userAgent)
+		loc := h.geo.Lookup(clientIP)
+		botReason := h.classifyBot(ua, clientIP, isHead, sinceCreated)
+		ref := referrer.Parse(referer)
 
 		clickReq := &domain.ClickRequest{
-			URLID:      url.ID,
-			UserAgent:  userAgent,
-			Referer:    referer,
-			IPAddress:  clientIP,
-			DeviceType: deviceType,
+			URLID:          url.ID,
+			URLVersion:     url.Version,
+			UserAgent:      userAgent,
+			Referer:        referer,
+			ReferrerHost:   truncate(ref.Host, maxFieldLength),
+			ReferrerSource: truncate(ref.Source, maxSourceLength),
+			UTMSource:      truncate(query.Get("utm_source"), maxFieldLength),
+			UTMMedium:      truncate(query.Get("utm_medium"), maxFieldLength),
+			UTMCampaign:    truncate(query.Get("utm_campaign"), maxFieldLength),
+			UTMTerm:        truncate(query.Get("utm_term"), maxFieldLength),
+			UTMContent:     truncate(query.Get("utm_content"), maxFieldLength),
+			IPAddress:      clientIP,
+			CountryCode:    loc.CountryCode,
+			Region:         loc.Region,
+			City:           loc.City,
+			DeviceType:     ua.DeviceType,
+			Browser:        ua.BrowserFamily,
+			BrowserVersion: ua.BrowserVersion,
+			OS:             ua.OSFamily,
+			OSVersion:      ua.OSVersion,
+			IsBot:          botReason != "",
+			BotReason:      botReason,
 		}
 
 		_ = h.service.RecordClick(context.Background(), clickReq)
 	}()
 
-	if cacheHit {
-		c.Header("X-Cache-Hit", "true")
-	} else {
-		c.Header("X-Cache-Hit", "false")
+	c.Header("X-Cache-Hit", cacheTier)
+
+	// Unfurlers get a page with the link's social card instead of a redirect,
+	// so chat apps show the card rather than the destination's own tags.
+	if detector.IsUnfurler(userAgent) {
+		card, err := h.service.GetSocialCard(c.Request.Context(), shortCode)
+		if err == nil {
+			h.renderSocialCard(c, card, h.shortURL(c, shortCode), url.OriginalURL)
+			return
+		}
+		if !errors.Is(err, domain.ErrURLNotFound) {
+			logger.FromContext(c.Request.Context()).Error("Failed to get social card", "short_code", shortCode, "error", err)
+		}
 	}
 
 	c.Redirect(http.StatusMovedPermanently, url.OriginalURL)
 }
+
+// WaitForClicks blocks until the clicks of redirects already served have been
+// recorded.
+func (h *ShortenerHandler) WaitForClicks() {
+	h.clicks.Wait()
+}
+
+func (h *ShortenerHandler) CacheStats(c *gin.Context) {
+	response.Success(c, http.StatusOK, "Cache stats retrieved successfully", h.service.CacheStats())
+}
+
+// classifyBot returns why a click looks automated, or an empty string for a
+// human click. Unfurlers and link scanners tend to fetch a link within moments
+// of it being created, often with a browser-like user agent.
+func (h *ShortenerHandler) classifyBot(ua detector.UserAgent, clientIP string, isHead bool, sinceCreated time.Duration) string {
+	switch {
+	case ua.IsBot:
+		return domain.BotReasonUserAgent
+	case isHead:
+		return domain.BotReasonHeadRequest
+	case detector.IsCrawlerIP(clientIP):
+		return domain.BotReasonCrawlerIP
+	case sinceCreated < h.minClickDelay:
+		return domain.BotReasonTooFast
+	}
+
+	return ""
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
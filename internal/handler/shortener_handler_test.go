@@ -6,28 +6,66 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/middleware"
+	"github.com/gamassss/url-shortener/pkg/geoip"
+	"github.com/gamassss/url-shortener/pkg/response"
 	"github.com/gamassss/url-shortener/tests/mocks"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+const (
+	browserUA  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	slackbotUA = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+)
+
+type fakeGeo map[string]geoip.Location
+
+func (g fakeGeo) Lookup(ip string) geoip.Location {
+	return g[ip]
+}
+
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	return gin.New()
 }
 
+func newTestHandler(service ShortenerService) *ShortenerHandler {
+	return NewShortenerHandler(service, "http://localhost:8080", fakeGeo{}, 0)
+}
+
+// testURL is a link created long enough ago that clicks on it aren't too fast.
+func testURL() *domain.URL {
+	return &domain.URL{
+		ID:          1,
+		ShortCode:   "abc1234",
+		OriginalURL: "https://example.com",
+		IsActive:    true,
+		Version:     2,
+		CreatedAt:   time.Now().Add(-time.Hour),
+	}
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body
+}
+
 func TestShortenURL_Success(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
-	reqBody := `{"url": "https://example.com"}`
+	reqBody := `{"original_url": "https://example.com"}`
 	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -42,26 +80,24 @@ func TestShortenURL_Success(t *testing.T) {
 	}
 
 	mockService.On("ShortenURL", mock.Anything, mock.MatchedBy(func(req *domain.CreatedURLRequest) bool {
-		return req.URL == "https://example.com"
+		return req.OriginalURL == "https://example.com"
 	})).Return(mockURL, nil).Once()
 
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	assert.NoError(t, err)
-	assert.Equal(t, "http://localhost:8080/abc1234", response["short_url"])
-	assert.Equal(t, "abc1234", response["short_code"])
-	assert.Equal(t, "https://example.com", response["original_url"])
+	data := decodeResponse(t, w)["data"].(map[string]interface{})
+	assert.Equal(t, "http://localhost:8080/abc1234", data["short_url"])
+	assert.Equal(t, "abc1234", data["short_code"])
+	assert.Equal(t, "https://example.com", data["original_url"])
 
 	mockService.AssertExpectations(t)
 }
 
 func TestShortenURL_InvalidJSON(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
@@ -78,7 +114,7 @@ func TestShortenURL_InvalidJSON(t *testing.T) {
 
 func TestShortenURL_MissingURL(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
@@ -91,20 +127,21 @@ func TestShortenURL_MissingURL(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "required")
+	var body response.ValidationErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Errors, 1)
+	assert.Contains(t, body.Errors[0].Message, "required")
 
 	mockService.AssertNotCalled(t, "ShortenURL")
 }
 
 func TestShortenURL_InvalidURLFormat(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
-	reqBody := `{"url": "not-a-valid-url"}`
+	reqBody := `{"original_url": "not-a-valid-url"}`
 	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -113,20 +150,21 @@ func TestShortenURL_InvalidURLFormat(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "url")
+	var body response.ValidationErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Errors, 1)
+	assert.Contains(t, body.Errors[0].Message, "valid URL")
 
 	mockService.AssertNotCalled(t, "ShortenURL")
 }
 
 func TestShortenURL_ServiceError(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
-	reqBody := `{"url": "https://example.com"}`
+	reqBody := `{"original_url": "https://example.com"}`
 	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -137,21 +175,18 @@ func TestShortenURL_ServiceError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "database error")
+	assert.Contains(t, decodeResponse(t, w)["error"], "database error")
 
 	mockService.AssertExpectations(t)
 }
 
 func TestShortenURL_WithCustomAlias(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
-	reqBody := `{"url": "https://example.com", "custom_alias": "mylink"}`
+	reqBody := `{"original_url": "https://example.com", "custom_alias": "mylink"}`
 	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -170,20 +205,19 @@ func TestShortenURL_WithCustomAlias(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Equal(t, "mylink", response["short_code"])
+	data := decodeResponse(t, w)["data"].(map[string]interface{})
+	assert.Equal(t, "mylink", data["short_code"])
 
 	mockService.AssertExpectations(t)
 }
 
 func TestShortenURL_WithExpiry(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.POST("/api/shorten", handler.ShortenURL)
 
-	reqBody := `{"url": "https://example.com", "expiry_hours": 24}`
+	reqBody := `{"original_url": "https://example.com", "expiry_hours": 24}`
 	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -204,42 +238,44 @@ func TestShortenURL_WithExpiry(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.NotNil(t, response["expires_at"])
+	data := decodeResponse(t, w)["data"].(map[string]interface{})
+	assert.NotNil(t, data["expires_at"])
 
 	mockService.AssertExpectations(t)
 }
 
 func TestRedirect_Success(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.GET("/:shortCode", handler.Redirect)
 
 	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", browserUA)
 	w := httptest.NewRecorder()
 
-	mockURL := &domain.URL{
-		ShortCode:   "abc1234",
-		OriginalURL: "https://example.com",
-		IsActive:    true,
-	}
-
+	clicks := expectClick(mockService)
 	mockService.On("GetOriginalURL", mock.Anything, "abc1234").
-		Return(mockURL, nil).Once()
+		Return(testURL(), nil).Once()
 
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusMovedPermanently, w.Code)
 	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	assert.Equal(t, domain.CacheTierMiss, w.Header().Get("X-Cache-Hit"))
+
+	click := waitForClick(t, clicks)
+	assert.Equal(t, int64(1), click.URLID)
+	assert.Equal(t, 2, click.URLVersion)
+	assert.False(t, click.IsBot)
+	assert.Empty(t, click.BotReason)
 
 	mockService.AssertExpectations(t)
 }
 
 func TestRedirect_NotFound(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.GET("/:shortCode", handler.Redirect)
 
@@ -247,22 +283,20 @@ func TestRedirect_NotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	mockService.On("GetOriginalURL", mock.Anything, "notfound").
-		Return(nil, errors.New("URL not found")).Once()
+		Return(nil, domain.ErrURLNotFound).Once()
 
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "URL not found")
+	assert.Contains(t, decodeResponse(t, w)["error"], "URL not found")
 
 	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "RecordClick", mock.Anything, mock.Anything)
 }
 
 func TestRedirect_ServiceError(t *testing.T) {
 	mockService := new(mocks.MockShortenerService)
-	handler := NewShortenerHandler(mockService)
+	handler := newTestHandler(mockService)
 	router := setupTestRouter()
 	router.GET("/:shortCode", handler.Redirect)
 
@@ -278,3 +312,404 @@ func TestRedirect_ServiceError(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+// expectClick returns a channel that receives the click a redirect records in
+// the background.
+func expectClick(mockService *mocks.MockShortenerService) <-chan *domain.ClickRequest {
+	clicks := make(chan *domain.ClickRequest, 1)
+	mockService.On("RecordClick", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			clicks <- args.Get(1).(*domain.ClickRequest)
+		}).
+		Return(nil).Once()
+	return clicks
+}
+
+func waitForClick(t *testing.T, clicks <-chan *domain.ClickRequest) *domain.ClickRequest {
+	t.Helper()
+	select {
+	case click := <-clicks:
+		return click
+	case <-time.After(time.Second):
+		t.Fatal("click was not recorded")
+		return nil
+	}
+}
+
+// redirectClick serves req with handler and returns the click it recorded.
+func redirectClick(t *testing.T, handler *ShortenerHandler, mockService *mocks.MockShortenerService, url *domain.URL, req *http.Request) *domain.ClickRequest {
+	t.Helper()
+	router := setupTestRouter()
+	router.GET("/:shortCode", handler.Redirect)
+	router.HEAD("/:shortCode", handler.Redirect)
+
+	clicks := expectClick(mockService)
+	mockService.On("GetOriginalURL", mock.Anything, url.ShortCode).Return(url, nil).Once()
+
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	return waitForClick(t, clicks)
+}
+
+func TestRedirect_WaitForClicks(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.GET("/:shortCode", handler.Redirect)
+
+	url := testURL()
+	var recorded atomic.Bool
+	mockService.On("GetOriginalURL", mock.Anything, url.ShortCode).Return(url, nil).Once()
+	mockService.On("RecordClick", mock.Anything, mock.Anything).
+		After(50 * time.Millisecond).
+		Run(func(args mock.Arguments) { recorded.Store(true) }).
+		Return(nil).Once()
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc1234", nil))
+	handler.WaitForClicks()
+
+	assert.True(t, recorded.Load(), "the click should be recorded before WaitForClicks returns")
+}
+
+func TestRedirect_BotReasons(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		userAgent string
+		ip        string
+		createdAt time.Time
+		want      string
+	}{
+		{name: "human", method: "GET", userAgent: browserUA, ip: "203.0.113.7", want: ""},
+		{name: "bot user agent", method: "GET", userAgent: "Googlebot/2.1 (+http://www.google.com/bot.html)", ip: "203.0.113.7", want: domain.BotReasonUserAgent},
+		{name: "HEAD request", method: "HEAD", userAgent: browserUA, ip: "203.0.113.7", want: domain.BotReasonHeadRequest},
+		{name: "crawler IP", method: "GET", userAgent: browserUA, ip: "66.249.66.1", want: domain.BotReasonCrawlerIP},
+		{name: "too fast", method: "GET", userAgent: browserUA, ip: "203.0.113.7", createdAt: time.Now(), want: domain.BotReasonTooFast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mocks.MockShortenerService)
+			handler := NewShortenerHandler(mockService, "http://localhost:8080", fakeGeo{}, time.Minute)
+
+			url := testURL()
+			if !tt.createdAt.IsZero() {
+				url.CreatedAt = tt.createdAt
+			}
+			req := httptest.NewRequest(tt.method, "/abc1234", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			req.Header.Set("X-Forwarded-For", tt.ip)
+
+			click := redirectClick(t, handler, mockService, url, req)
+
+			assert.Equal(t, tt.want, click.BotReason)
+			assert.Equal(t, tt.want != "", click.IsBot)
+		})
+	}
+}
+
+func TestRedirect_RecordsGeoLocation(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	geo := fakeGeo{"203.0.113.7": {CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}}
+	handler := NewShortenerHandler(mockService, "http://localhost:8080", geo, 0)
+
+	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", browserUA)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	click := redirectClick(t, handler, mockService, testURL(), req)
+
+	assert.Equal(t, "203.0.113.7", click.IPAddress)
+	assert.Equal(t, "ID", click.CountryCode)
+	assert.Equal(t, "Jakarta", click.Region)
+	assert.Equal(t, "Jakarta", click.City)
+}
+
+func TestRedirect_RecordsReferrerAndUTM(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/abc1234?utm_source=newsletter&utm_medium=email&utm_campaign=q4&utm_term=shoes&utm_content="+strings.Repeat("x", 300), nil)
+	req.Header.Set("User-Agent", browserUA)
+	req.Header.Set("Referer", "https://t.co/abc")
+
+	click := redirectClick(t, handler, mockService, testURL(), req)
+
+	assert.Equal(t, "https://t.co/abc", click.Referer)
+	assert.Equal(t, "t.co", click.ReferrerHost)
+	assert.Equal(t, "Twitter", click.ReferrerSource)
+	assert.Equal(t, "newsletter", click.UTMSource)
+	assert.Equal(t, "email", click.UTMMedium)
+	assert.Equal(t, "q4", click.UTMCampaign)
+	assert.Equal(t, "shoes", click.UTMTerm)
+	// longer than the column it is stored in
+	assert.Len(t, click.UTMContent, maxFieldLength)
+}
+
+func TestRedirect_DirectClick(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", browserUA)
+
+	click := redirectClick(t, handler, mockService, testURL(), req)
+
+	assert.Empty(t, click.ReferrerHost)
+	assert.Equal(t, "Direct", click.ReferrerSource)
+	assert.Empty(t, click.UTMSource)
+}
+
+func TestRedirect_SocialCardForUnfurler(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.GET("/:shortCode", handler.Redirect)
+
+	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", slackbotUA)
+	w := httptest.NewRecorder()
+
+	clicks := expectClick(mockService)
+	mockService.On("GetOriginalURL", mock.Anything, "abc1234").Return(testURL(), nil).Once()
+	mockService.On("GetSocialCard", mock.Anything, "abc1234").Return(&domain.SocialCard{
+		Title:       "Our Q4 newsletter",
+		Description: "Everything we shipped",
+		Image:       "https://cdn.example.com/q4.png",
+	}, nil).Once()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, `<meta property="og:title" content="Our Q4 newsletter">`)
+	assert.Contains(t, body, `<meta property="og:description" content="Everything we shipped">`)
+	assert.Contains(t, body, `<meta property="og:image" content="https://cdn.example.com/q4.png">`)
+	assert.Contains(t, body, `<meta name="twitter:card" content="summary_large_image">`)
+	assert.Contains(t, body, `<meta property="og:url" content="http://localhost:8080/abc1234">`)
+	assert.Contains(t, body, `<a href="https://example.com">`)
+
+	// unfurls are still recorded, as bots
+	assert.True(t, waitForClick(t, clicks).IsBot)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirect_SocialCardEscapesFields(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.GET("/:shortCode", handler.Redirect)
+
+	url := testURL()
+	url.OriginalURL = `https://example.com/?q="><script>alert(1)</script>`
+	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", slackbotUA)
+	w := httptest.NewRecorder()
+
+	expectClick(mockService)
+	mockService.On("GetOriginalURL", mock.Anything, "abc1234").Return(url, nil).Once()
+	mockService.On("GetSocialCard", mock.Anything, "abc1234").Return(&domain.SocialCard{
+		Title:       `Tom & Jerry"><script>alert(1)</script>`,
+		Description: `<img src=x onerror=alert(1)>`,
+		Image:       `https://cdn.example.com/card.png?w=1200&h=630`,
+	}, nil).Once()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.NotContains(t, body, "<script>")
+	assert.NotContains(t, body, "<img")
+	assert.Contains(t, body, `content="https://cdn.example.com/card.png?w=1200&amp;h=630"`)
+	assert.Contains(t, body, `content="Tom &amp; Jerry&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"`)
+}
+
+func TestRedirect_SocialCardFallsBackToDestination(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.GET("/:shortCode", handler.Redirect)
+
+	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", slackbotUA)
+	w := httptest.NewRecorder()
+
+	expectClick(mockService)
+	mockService.On("GetOriginalURL", mock.Anything, "abc1234").Return(testURL(), nil).Once()
+	mockService.On("GetSocialCard", mock.Anything, "abc1234").Return(&domain.SocialCard{}, nil).Once()
+
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `<title>https://example.com</title>`)
+	assert.Contains(t, body, `<meta name="twitter:card" content="summary">`)
+	assert.NotContains(t, body, "og:image")
+}
+
+func TestRedirect_SocialCardError(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.GET("/:shortCode", handler.Redirect)
+
+	req := httptest.NewRequest("GET", "/abc1234", nil)
+	req.Header.Set("User-Agent", slackbotUA)
+	w := httptest.NewRecorder()
+
+	expectClick(mockService)
+	mockService.On("GetOriginalURL", mock.Anything, "abc1234").Return(testURL(), nil).Once()
+	mockService.On("GetSocialCard", mock.Anything, "abc1234").Return(nil, errors.New("database error")).Once()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+}
+
+func TestGetURLHistory_Success(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.GET("/api/urls/:shortCode/history", handler.GetURLHistory)
+
+	versions := []domain.URLVersion{
+		{Version: 2, OriginalURL: "https://example.com/new", IsActive: true, ChangeType: domain.ChangeUpdate, ChangedBy: "alice"},
+		{Version: 1, OriginalURL: "https://example.com", IsActive: true, ChangeType: domain.ChangeCreate},
+	}
+	mockService.On("GetURLHistory", mock.Anything, "abc1234").Return(versions, nil).Once()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/urls/abc1234/history", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := decodeResponse(t, w)["data"].([]interface{})
+	require.Len(t, data, 2)
+	assert.Equal(t, float64(2), data[0].(map[string]interface{})["version"])
+	assert.Equal(t, "alice", data[0].(map[string]interface{})["changed_by"])
+}
+
+func TestGetURLHistory_Errors(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"not found":      {err: domain.ErrURLNotFound, want: http.StatusNotFound},
+		"database error": {err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(mocks.MockShortenerService)
+			handler := newTestHandler(mockService)
+			router := setupTestRouter()
+			router.GET("/api/urls/:shortCode/history", handler.GetURLHistory)
+
+			mockService.On("GetURLHistory", mock.Anything, "abc1234").Return(nil, tt.err).Once()
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/api/urls/abc1234/history", nil))
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestRollbackURL_Success(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.Use(middleware.Actor(map[string]string{"alice-key": "alice"}))
+	router.POST("/api/urls/:shortCode/rollback/:version", handler.RollbackURL)
+
+	url := testURL()
+	url.Version = 3
+	mockService.On("RollbackURL", mock.Anything, "abc1234", 1, "alice").Return(url, nil).Once()
+
+	req := httptest.NewRequest("POST", "/api/urls/abc1234/rollback/1", nil)
+	req.Header.Set("Authorization", "Bearer alice-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := decodeResponse(t, w)["data"].(map[string]interface{})
+	assert.Equal(t, float64(3), data["version"])
+	mockService.AssertExpectations(t)
+}
+
+func TestRollbackURL_UnknownAPIKey(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.Use(middleware.Actor(map[string]string{"alice-key": "alice"}))
+	router.POST("/api/urls/:shortCode/rollback/:version", handler.RollbackURL)
+
+	req := httptest.NewRequest("POST", "/api/urls/abc1234/rollback/1", nil)
+	req.Header.Set("Authorization", "Bearer guessed-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockService.AssertNotCalled(t, "RollbackURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRollbackURL_ActorHeaderIsNotTrusted(t *testing.T) {
+	mockService := new(mocks.MockShortenerService)
+	handler := newTestHandler(mockService)
+	router := setupTestRouter()
+	router.Use(middleware.Actor(map[string]string{"alice-key": "alice"}))
+	router.POST("/api/urls/:shortCode/rollback/:version", handler.RollbackURL)
+
+	mockService.On("RollbackURL", mock.Anything, "abc1234", 1, "").Return(testURL(), nil).Once()
+
+	req := httptest.NewRequest("POST", "/api/urls/abc1234/rollback/1", nil)
+	req.Header.Set("X-Actor", "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRollbackURL_InvalidVersion(t *testing.T) {
+	for _, version := range []string{"0", "-1", "latest"} {
+		t.Run(version, func(t *testing.T) {
+			mockService := new(mocks.MockShortenerService)
+			handler := newTestHandler(mockService)
+			router := setupTestRouter()
+			router.POST("/api/urls/:shortCode/rollback/:version", handler.RollbackURL)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/api/urls/abc1234/rollback/"+version, nil))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "RollbackURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRollbackURL_Errors(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"link not found":    {err: domain.ErrURLNotFound, want: http.StatusNotFound},
+		"version not found": {err: domain.ErrVersionNotFound, want: http.StatusNotFound},
+		"database error":    {err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(mocks.MockShortenerService)
+			handler := newTestHandler(mockService)
+			router := setupTestRouter()
+			router.POST("/api/urls/:shortCode/rollback/:version", handler.RollbackURL)
+
+			mockService.On("RollbackURL", mock.Anything, "abc1234", 5, "").Return(nil, tt.err).Once()
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/api/urls/abc1234/rollback/5", nil))
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+var socialCardTemplate = template.Must(template.New("social_card").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.URL}}">
+{{- with .Title}}
+<meta property="og:title" content="{{.}}">
+<meta name="twitter:title" content="{{.}}">
+{{- end}}
+{{- with .Description}}
+<meta property="og:description" content="{{.}}">
+<meta name="twitter:description" content="{{.}}">
+{{- end}}
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+<meta http-equiv="refresh" content="0;url={{.Destination}}">
+</head>
+<body>
+<a href="{{.Destination}}">{{.Destination}}</a>
+</body>
+</html>
+`))
+
+type socialCardPage struct {
+	domain.SocialCard
+	URL         string
+	Destination string
+}
+
+// renderSocialCard writes a page carrying card as Open Graph and Twitter tags,
+// which refreshes to destination if a browser ever ends up on it.
+func (h *ShortenerHandler) renderSocialCard(c *gin.Context, card *domain.SocialCard, shortURL, destination string) {
+	page := socialCardPage{SocialCard: *card, URL: shortURL, Destination: destination}
+	if page.Title == "" {
+		page.Title = destination
+	}
+
+	var buf bytes.Buffer
+	if err := socialCardTemplate.Execute(&buf, page); err != nil {
+		logger.FromContext(c.Request.Context()).Error("Failed to render social card", "error", err)
+		c.Redirect(http.StatusMovedPermanently, destination)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/response"
+	"github.com/gamassss/url-shortener/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type TagService interface {
+	ListTags(ctx context.Context) ([]domain.Tag, error)
+	RenameTag(ctx context.Context, name, newName string) error
+	MergeTags(ctx context.Context, source, target string) error
+	DeleteTag(ctx context.Context, name string) error
+}
+
+type TagHandler struct {
+	service TagService
+}
+
+func NewTagHandler(service TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+func (h *TagHandler) ListTags(c *gin.Context) {
+	tags, err := h.service.ListTags(c.Request.Context())
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Tags retrieved successfully", tags)
+}
+
+func (h *TagHandler) RenameTag(c *gin.Context) {
+	var req domain.RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid JSON format")
+		return
+	}
+
+	if validationErros := validator.Validate(req); len(validationErros) > 0 {
+		response.ValidationErrors(c, validationErros)
+		return
+	}
+
+	if err := h.service.RenameTag(c.Request.Context(), c.Param("name"), req.Name); err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	response.OK(c, "Tag renamed successfully", gin.H{"name": domain.NormalizeTag(req.Name)})
+}
+
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	var req domain.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid JSON format")
+		return
+	}
+
+	if validationErros := validator.Validate(req); len(validationErros) > 0 {
+		response.ValidationErrors(c, validationErros)
+		return
+	}
+
+	if err := h.service.MergeTags(c.Request.Context(), c.Param("name"), req.Into); err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	response.OK(c, "Tag merged successfully", gin.H{"name": domain.NormalizeTag(req.Into)})
+}
+
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	if err := h.service.DeleteTag(c.Request.Context(), c.Param("name")); err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	response.OK(c, "Tag deleted successfully", nil)
+}
+
+func respondTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTagNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, domain.ErrTagExists):
+		response.Conflict(c, "Tag already exists, merge into it instead")
+	case errors.Is(err, domain.ErrInvalidTag):
+		response.BadRequest(c, err.Error())
+	default:
+		response.InternalServerError(c, err.Error())
+	}
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/response"
+	"github.com/gamassss/url-shortener/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, req *domain.CreateWebhookRequest) (*domain.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, webhookID int64, status string, page, pageSize int) (*domain.DeliveryLog, error)
+	RetryDelivery(ctx context.Context, webhookID, deliveryID int64) error
+}
+
+type WebhookHandler struct {
+	service WebhookService
+}
+
+func NewWebhookHandler(service WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req domain.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid JSON format")
+		return
+	}
+
+	if validationErros := validator.Validate(req); len(validationErros) > 0 {
+		response.ValidationErrors(c, validationErros)
+		return
+	}
+
+	hook, err := h.service.CreateWebhook(c.Request.Context(), &req)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Created(c, "Webhook created successfully", hook)
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Webhooks retrieved successfully", hooks)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(c.Request.Context(), id); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Webhook deleted successfully", nil)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	page := 1
+	if pageParam := c.Query("page"); pageParam != "" {
+		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	pageSize := 50
+	if sizeParam := c.Query("page_size"); sizeParam != "" {
+		if s, err := strconv.Atoi(sizeParam); err == nil && s > 0 && s <= 500 {
+			pageSize = s
+		}
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliverySucceeded, domain.DeliveryDead:
+	default:
+		response.BadRequest(c, "Invalid status, expected pending, succeeded or dead")
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), id, status, page, pageSize)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Deliveries retrieved successfully", deliveries)
+}
+
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RetryDelivery(c.Request.Context(), id, deliveryID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	response.OK(c, "Delivery queued for retry", nil)
+}
+
+func idParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		response.BadRequest(c, "Invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, domain.ErrDeliveryNotFound):
+		response.NotFound(c, "Dead delivery not found")
+	default:
+		response.InternalServerError(c, err.Error())
+	}
+}
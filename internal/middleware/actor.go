@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"strings"
+
+	"github.com/gamassss/url-shortener/internal/audit"
+	"github.com/gamassss/url-shortener/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Actor stores who made the request and from where in the request context.
+// Callers identify themselves with an API key from apiKeys, which maps each
+// key to the actor it belongs to, sent as a bearer token. Requests without one
+// are anonymous, and an unknown key is rejected rather than treated as
+// anonymous.
+func Actor(apiKeys map[string]string) gin.HandlerFunc {
+	// keys are looked up by digest so a lookup's timing says nothing about them
+	actors := make(map[[sha256.Size]byte]string, len(apiKeys))
+	for key, actor := range apiKeys {
+		actors[sha256.Sum256([]byte(key))] = actor
+	}
+
+	return func(c *gin.Context) {
+		actor := audit.Actor{IP: c.ClientIP()}
+		if key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			id, known := actors[sha256.Sum256([]byte(strings.TrimSpace(key)))]
+			if !known {
+				response.Error(c, http.StatusUnauthorized, "Invalid API key")
+				c.Abort()
+				return
+			}
+			actor.ID = id
+		}
+
+		ctx := audit.WithActor(c.Request.Context(), actor)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
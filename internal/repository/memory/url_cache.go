@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/lru"
+)
+
+// urlOverhead approximates the memory a cached URL takes beyond its strings.
+const urlOverhead = 256
+
+// URLCache keeps recently redirected links in process memory, in front of the
+// shared Redis cache. Entries live for a short TTL, which bounds how stale a
+// link can be if an invalidation is missed. Cached links are shared between
+// callers and must not be modified.
+type URLCache struct {
+	entries *lru.Cache[*domain.URL]
+	ttl     time.Duration
+}
+
+// NewURLCache returns a cache holding up to maxBytes of links for ttl each.
+func NewURLCache(maxBytes int64, ttl time.Duration) *URLCache {
+	return &URLCache{
+		entries: lru.New[*domain.URL](maxBytes),
+		ttl:     ttl,
+	}
+}
+
+func (c *URLCache) Get(shortCode string) (*domain.URL, bool) {
+	return c.entries.Get(shortCode)
+}
+
+// Set caches url until the TTL passes or the link expires, whichever is first.
+func (c *URLCache) Set(url *domain.URL) {
+	expiresAt := time.Now().Add(c.ttl)
+	if url.ExpiresAt != nil && url.ExpiresAt.Before(expiresAt) {
+		expiresAt = *url.ExpiresAt
+	}
+
+	c.entries.Set(url.ShortCode, url, urlSize(url), expiresAt)
+}
+
+func (c *URLCache) Delete(shortCode string) {
+	c.entries.Delete(shortCode)
+}
+
+// Purge empties the cache, for when invalidations may have been missed.
+func (c *URLCache) Purge() {
+	c.entries.Purge()
+}
+
+// Stats returns the number of cached links and their approximate size.
+func (c *URLCache) Stats() (int, int64) {
+	return c.entries.Len(), c.entries.Bytes()
+}
+
+func urlSize(url *domain.URL) int64 {
+	size := urlOverhead + len(url.ShortCode) + len(url.OriginalURL) + len(url.Title) + len(url.Notes) + len(url.Folder) +
+		len(url.SocialCard.Title) + len(url.SocialCard.Description) + len(url.SocialCard.Image)
+	for _, tag := range url.Tags {
+		size += len(tag)
+	}
+	return int64(size)
+}
@@ -2,36 +2,98 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const exportFetchSize = 1000
+
 type AnalyticsRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	replicas *ReplicaSet
+}
+
+// NewAnalyticsRepository records clicks on db and runs every other query on
+// replicas when given any. replicas may be nil to run everything on db.
+func NewAnalyticsRepository(db *pgxpool.Pool, replicas *ReplicaSet) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db, replicas: replicas}
 }
 
-func NewAnalyticsRepository(db *pgxpool.Pool) *AnalyticsRepository {
-	return &AnalyticsRepository{db: db}
+func (r *AnalyticsRepository) reader() *pgxpool.Pool {
+	return readPool(r.db, r.replicas)
 }
 
-func (r *AnalyticsRepository) RecordClick(ctx context.Context, click *domain.ClickRequest) error {
+// RecordClick stores a click and returns it as a stream event. A human click
+// increments the link's click count in the same statement, and the event
+// carries the count that increment produced, so concurrent clicks each see a
+// different total.
+func (r *AnalyticsRepository) RecordClick(ctx context.Context, click *domain.ClickRequest) (*domain.ClickEvent, error) {
 	query := `
-		INSERT INTO url_clicks (url_id, user_agent, referer, ip_address, device_type)
-		VALUES ($1, $2, $3, $4, $5)
+		WITH inserted AS (
+			INSERT INTO url_clicks (url_id, user_agent, referer, ip_address, country_code, region, city, device_type, browser, browser_version, os, os_version, is_bot, bot_reason,
+				referrer_host, referrer_source, utm_source, utm_medium, utm_campaign, utm_term, utm_content, url_version)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, $12, $13, NULLIF($14, ''),
+				NULLIF($15, ''), $16, NULLIF($17, ''), NULLIF($18, ''), NULLIF($19, ''), NULLIF($20, ''), NULLIF($21, ''), GREATEST($22, 1))
+			RETURNING url_id, clicked_at, is_bot
+		),
+		counted AS (
+			UPDATE urls u
+			SET click_count = u.click_count + 1,
+				updated_at = NOW()
+			FROM inserted i
+			WHERE u.id = i.url_id AND NOT i.is_bot
+			RETURNING u.click_count
+		)
+		SELECT
+			i.clicked_at,
+			COALESCE((SELECT click_count FROM counted), u.click_count)
+		FROM inserted i
+		JOIN urls u ON u.id = i.url_id
 	`
-	_, err := r.db.Exec(ctx, query,
+
+	event := &domain.ClickEvent{
+		DeviceType:  click.DeviceType,
+		Referer:     click.Referer,
+		Source:      click.ReferrerSource,
+		CountryCode: click.CountryCode,
+	}
+	err := r.db.QueryRow(ctx, query,
 		click.URLID,
 		click.UserAgent,
 		click.Referer,
 		click.IPAddress,
+		click.CountryCode,
+		click.Region,
+		click.City,
 		click.DeviceType,
-	)
-	return err
+		click.Browser,
+		click.BrowserVersion,
+		click.OS,
+		click.OSVersion,
+		click.IsBot,
+		click.BotReason,
+		click.ReferrerHost,
+		click.ReferrerSource,
+		click.UTMSource,
+		click.UTMMedium,
+		click.UTMCampaign,
+		click.UTMTerm,
+		click.UTMContent,
+		click.URLVersion,
+	).Scan(&event.ClickedAt, &event.TotalClicks)
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
 }
 
-func (r *AnalyticsRepository) GetAnalytics(ctx context.Context, urlID int64, days int) (*domain.URLAnalytics, error) {
+func (r *AnalyticsRepository) GetAnalytics(ctx context.Context, urlID int64, q *domain.AnalyticsQuery) (*domain.URLAnalytics, error) {
 	analytics := &domain.URLAnalytics{}
 
 	query := `
@@ -40,8 +102,8 @@ func (r *AnalyticsRepository) GetAnalytics(ctx context.Context, urlID int64, day
 			u.original_url,
 			u.click_count,
 			u.created_at,
-			MAX(c.clicked_at) as last_clicked_at,
-			COUNT(DISTINCT c.ip_address) as unique_ips
+			MAX(c.clicked_at) FILTER (WHERE NOT c.is_bot OR $2) as last_clicked_at,
+			COUNT(c.id) FILTER (WHERE c.is_bot) as bot_clicks
 		FROM urls u
 		LEFT JOIN url_clicks c ON u.id = c.url_id
 		WHERE u.id = $1
@@ -49,86 +111,176 @@ func (r *AnalyticsRepository) GetAnalytics(ctx context.Context, urlID int64, day
 	`
 
 	var lastClickedAt *time.Time
-	err := r.db.QueryRow(ctx, query, urlID).Scan(
+	err := r.reader().QueryRow(ctx, query, urlID, q.IncludeBots).Scan(
 		&analytics.ShortCode,
 		&analytics.OriginalURL,
 		&analytics.TotalClicks,
 		&analytics.CreatedAt,
 		&lastClickedAt,
-		&analytics.UniqueIPs,
+		&analytics.BotClicks,
 	)
 	if err != nil {
 		return nil, err
 	}
 	analytics.LastClickedAt = lastClickedAt
 
-	clicksByDate, err := r.getClicksByDate(ctx, urlID, days)
+	// click_count only tracks human clicks
+	if q.IncludeBots {
+		analytics.TotalClicks += analytics.BotClicks
+	}
+
+	clicksByDate, err := r.getClicksByBucket(ctx, urlID, q)
 	if err != nil {
 		return nil, err
 	}
 	analytics.ClicksByDate = clicksByDate
 
-	topReferrers, err := r.getTopReferrers(ctx, urlID, 5)
+	topReferrers, err := r.getTopReferrers(ctx, urlID, q, 5)
 	if err != nil {
 		return nil, err
 	}
 	analytics.TopReferrers = topReferrers
 
-	deviceStats, err := r.getDeviceStats(ctx, urlID)
+	topSources, err := r.getTopSources(ctx, urlID, q, 5)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopSources = topSources
+
+	topHosts, err := r.getTopHosts(ctx, urlID, q, 5)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopHosts = topHosts
+
+	for _, breakdown := range []struct {
+		column string
+		dest   *[]domain.UTMValueStats
+	}{
+		{"utm_source", &analytics.UTM.Sources},
+		{"utm_medium", &analytics.UTM.Mediums},
+		{"utm_campaign", &analytics.UTM.Campaigns},
+	} {
+		if *breakdown.dest, err = r.getTopUTM(ctx, urlID, q, breakdown.column, 5); err != nil {
+			return nil, err
+		}
+	}
+
+	deviceStats, err := r.getDeviceStats(ctx, urlID, q)
 	if err != nil {
 		return nil, err
 	}
 	analytics.DeviceStats = *deviceStats
 
+	topBrowsers, err := r.getTopBrowsers(ctx, urlID, q, 5)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopBrowsers = topBrowsers
+
+	topOS, err := r.getTopOS(ctx, urlID, q, 5)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopOS = topOS
+
+	topCountries, err := r.getTopCountries(ctx, urlID, q, 5)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopCountries = topCountries
+
+	topCities, err := r.getTopCities(ctx, urlID, q, 5)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopCities = topCities
+
+	clicksByVersion, err := r.getClicksByVersion(ctx, urlID, q)
+	if err != nil {
+		return nil, err
+	}
+	analytics.ClicksByVersion = clicksByVersion
+
 	return analytics, nil
 }
 
-func (r *AnalyticsRepository) getClicksByDate(ctx context.Context, urlID int64, days int) ([]domain.ClicksByDate, error) {
+// getClicksByBucket counts clicks per bucket of the range, including empty
+// buckets. Clicks are grouped by their local time in the range's timezone.
+func (r *AnalyticsRepository) getClicksByBucket(ctx context.Context, urlID int64, q *domain.AnalyticsQuery) ([]domain.ClicksByDate, error) {
 	query := `
 		SELECT 
-			DATE(clicked_at) as date,
+			date_trunc($5, clicked_at AT TIME ZONE 'UTC' AT TIME ZONE $6) as bucket,
 			COUNT(*) as count
 		FROM url_clicks
 		WHERE url_id = $1 
-			AND clicked_at >= NOW() - INTERVAL '1 day' * $2
-		GROUP BY DATE(clicked_at)
-		ORDER BY date DESC
-		LIMIT 30
+			AND (NOT is_bot OR $2)
+			AND clicked_at >= $3 AND clicked_at < $4
+		GROUP BY bucket
 	`
 
-	rows, err := r.db.Query(ctx, query, urlID, days)
+	rows, err := r.reader().Query(ctx, query, urlID, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC(),
+		q.Range.Granularity, q.Range.Location.String())
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []domain.ClicksByDate
+	counts := make(map[string]int64)
 	for rows.Next() {
-		var cbd domain.ClicksByDate
-		var date time.Time
-		if err := rows.Scan(&date, &cbd.Count); err != nil {
+		var bucket time.Time
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
 			return nil, err
 		}
-		cbd.Date = date.Format("2006-01-02")
-		results = append(results, cbd)
+		counts[domain.BucketKey(bucket)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return results, rows.Err()
+	buckets := q.Range.Buckets()
+	results := make([]domain.ClicksByDate, len(buckets))
+	for i, bucket := range buckets {
+		results[i] = domain.ClicksByDate{
+			Date:  q.Range.FormatBucket(bucket),
+			Count: counts[domain.BucketKey(bucket)],
+		}
+	}
+
+	return results, nil
 }
 
-func (r *AnalyticsRepository) getTopReferrers(ctx context.Context, urlID int64, limit int) ([]domain.ReferrerStats, error) {
+// CountClicks counts the clicks in the half-open range [from, to).
+func (r *AnalyticsRepository) CountClicks(ctx context.Context, urlID int64, from, to time.Time, includeBots bool) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM url_clicks
+		WHERE url_id = $1
+			AND (NOT is_bot OR $2)
+			AND clicked_at >= $3 AND clicked_at < $4
+	`
+
+	var count int64
+	err := r.reader().QueryRow(ctx, query, urlID, includeBots, from.UTC(), to.UTC()).Scan(&count)
+	return count, err
+}
+
+func (r *AnalyticsRepository) getTopReferrers(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.ReferrerStats, error) {
 	query := `
 		SELECT 
 			COALESCE(NULLIF(referer, ''), 'Direct') as referer,
 			COUNT(*) as count
 		FROM url_clicks
 		WHERE url_id = $1
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
 		GROUP BY referer
 		ORDER BY count DESC
 		LIMIT $2
 	`
 
-	rows, err := r.db.Query(ctx, query, urlID, limit)
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -146,17 +298,282 @@ func (r *AnalyticsRepository) getTopReferrers(ctx context.Context, urlID int64,
 	return results, rows.Err()
 }
 
-func (r *AnalyticsRepository) getDeviceStats(ctx context.Context, urlID int64) (*domain.DeviceStats, error) {
+func (r *AnalyticsRepository) getTopSources(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.SourceStats, error) {
+	query := `
+		SELECT 
+			COALESCE(referrer_source, referrer_host, 'Direct') as source,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY source
+		ORDER BY count DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.SourceStats
+	for rows.Next() {
+		var ss domain.SourceStats
+		if err := rows.Scan(&ss.Source, &ss.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, ss)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *AnalyticsRepository) getTopHosts(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.HostStats, error) {
+	query := `
+		SELECT 
+			referrer_host,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1 AND referrer_host IS NOT NULL
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY referrer_host
+		ORDER BY count DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.HostStats
+	for rows.Next() {
+		var hs domain.HostStats
+		if err := rows.Scan(&hs.Host, &hs.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, hs)
+	}
+
+	return results, rows.Err()
+}
+
+// getTopUTM counts the most common values of one utm_* column, which must be
+// a fixed column name and never user input.
+func (r *AnalyticsRepository) getTopUTM(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, column string, limit int) ([]domain.UTMValueStats, error) {
+	query := fmt.Sprintf(`
+		SELECT 
+			%[1]s,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1 AND %[1]s IS NOT NULL
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY %[1]s
+		ORDER BY count DESC
+		LIMIT $2
+	`, column)
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.UTMValueStats
+	for rows.Next() {
+		var us domain.UTMValueStats
+		if err := rows.Scan(&us.Value, &us.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, us)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *AnalyticsRepository) getTopBrowsers(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.BrowserStats, error) {
+	query := `
+		SELECT 
+			COALESCE(browser, 'Other') as browser,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY COALESCE(browser, 'Other')
+		ORDER BY count DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.BrowserStats
+	for rows.Next() {
+		var bs domain.BrowserStats
+		if err := rows.Scan(&bs.Browser, &bs.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, bs)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *AnalyticsRepository) getTopOS(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.OSStats, error) {
+	query := `
+		SELECT 
+			COALESCE(os, 'Other') as os,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY COALESCE(os, 'Other')
+		ORDER BY count DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.OSStats
+	for rows.Next() {
+		var osStats domain.OSStats
+		if err := rows.Scan(&osStats.OS, &osStats.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, osStats)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *AnalyticsRepository) getTopCountries(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.CountryStats, error) {
+	query := `
+		SELECT 
+			country_code,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1 AND country_code IS NOT NULL
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY country_code
+		ORDER BY count DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.CountryStats
+	for rows.Next() {
+		var cs domain.CountryStats
+		if err := rows.Scan(&cs.CountryCode, &cs.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, cs)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *AnalyticsRepository) getTopCities(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, limit int) ([]domain.CityStats, error) {
+	query := `
+		SELECT 
+			city,
+			COALESCE(region, '') as region,
+			COALESCE(country_code, '') as country_code,
+			COUNT(*) as count
+		FROM url_clicks
+		WHERE url_id = $1 AND city IS NOT NULL
+			AND (NOT is_bot OR $3)
+			AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY city, region, country_code
+		ORDER BY count DESC
+		LIMIT $2
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, limit, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.CityStats
+	for rows.Next() {
+		var cs domain.CityStats
+		if err := rows.Scan(&cs.City, &cs.Region, &cs.CountryCode, &cs.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, cs)
+	}
+
+	return results, rows.Err()
+}
+
+// getClicksByVersion splits clicks by the version of the link that was live
+// when they were made, so destinations can be compared.
+func (r *AnalyticsRepository) getClicksByVersion(ctx context.Context, urlID int64, q *domain.AnalyticsQuery) ([]domain.VersionStats, error) {
+	query := `
+		SELECT 
+			c.url_version,
+			COALESCE(v.original_url, ''),
+			COUNT(*) as count
+		FROM url_clicks c
+		LEFT JOIN url_versions v ON v.url_id = c.url_id AND v.version = c.url_version
+		WHERE c.url_id = $1
+			AND (NOT c.is_bot OR $2)
+			AND c.clicked_at >= $3 AND c.clicked_at < $4
+		GROUP BY c.url_version, v.original_url
+		ORDER BY c.url_version
+	`
+
+	rows, err := r.reader().Query(ctx, query, urlID, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.VersionStats
+	for rows.Next() {
+		var vs domain.VersionStats
+		if err := rows.Scan(&vs.Version, &vs.OriginalURL, &vs.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, vs)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *AnalyticsRepository) getDeviceStats(ctx context.Context, urlID int64, q *domain.AnalyticsQuery) (*domain.DeviceStats, error) {
 	query := `
 		SELECT 
 			COALESCE(device_type, 'unknown') as device_type,
 			COUNT(*) as count
 		FROM url_clicks
 		WHERE url_id = $1
+			AND (NOT is_bot OR $2)
+			AND clicked_at >= $3 AND clicked_at < $4
 		GROUP BY device_type
 	`
 
-	rows, err := r.db.Query(ctx, query, urlID)
+	rows, err := r.reader().Query(ctx, query, urlID, q.IncludeBots, q.Range.From.UTC(), q.Range.To.UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -187,25 +604,251 @@ func (r *AnalyticsRepository) getDeviceStats(ctx context.Context, urlID int64) (
 	return stats, rows.Err()
 }
 
-func (r *AnalyticsRepository) GetClickHistory(ctx context.Context, urlID int64, page, pageSize int) (*domain.ClickHistory, error) {
+const clickColumns = `
+	c.id, c.url_id, u.short_code, c.url_version, c.clicked_at,
+	COALESCE(c.user_agent, ''), COALESCE(c.referer, ''), COALESCE(c.ip_address, ''),
+	COALESCE(c.country_code, ''), COALESCE(c.region, ''), COALESCE(c.city, ''), COALESCE(c.device_type, ''),
+	COALESCE(c.browser, ''), COALESCE(c.browser_version, ''), COALESCE(c.os, ''), COALESCE(c.os_version, ''),
+	c.is_bot, COALESCE(c.bot_reason, ''),
+	COALESCE(c.referrer_host, ''), COALESCE(c.referrer_source, c.referrer_host, 'Direct'),
+	COALESCE(c.utm_source, ''), COALESCE(c.utm_medium, ''), COALESCE(c.utm_campaign, ''),
+	COALESCE(c.utm_term, ''), COALESCE(c.utm_content, '')
+`
+
+// clickQuery builds the WHERE clause for filter. Cursors continue towards
+// older clicks when descending and towards newer ones otherwise.
+func clickQuery(filter *domain.ClickFilter, descending bool) (string, []any) {
+	var conditions []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.URLID != 0 {
+		conditions = append(conditions, "c.url_id = "+arg(filter.URLID))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "c.clicked_at >= "+arg(filter.From.UTC()))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "c.clicked_at < "+arg(filter.To.UTC()))
+	}
+	if filter.DeviceType != "" {
+		conditions = append(conditions, "c.device_type = "+arg(filter.DeviceType))
+	}
+	if filter.ReferrerHost != "" {
+		conditions = append(conditions, "c.referrer_host = "+arg(filter.ReferrerHost))
+	}
+	if filter.CountryCode != "" {
+		conditions = append(conditions, "c.country_code = "+arg(filter.CountryCode))
+	}
+	if !filter.IncludeBots {
+		conditions = append(conditions, "NOT c.is_bot")
+	}
+	if filter.After != nil {
+		op := ">"
+		if descending {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(c.clicked_at, c.id) %s (%s, %s)", op, arg(filter.After.ClickedAt.UTC()), arg(filter.After.ID)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func scanClick(row pgx.Row) (*domain.URLClick, error) {
+	var click domain.URLClick
+	err := row.Scan(
+		&click.ID,
+		&click.URLID,
+		&click.ShortCode,
+		&click.URLVersion,
+		&click.ClickedAt,
+		&click.UserAgent,
+		&click.Referer,
+		&click.IPAddress,
+		&click.CountryCode,
+		&click.Region,
+		&click.City,
+		&click.DeviceType,
+		&click.Browser,
+		&click.BrowserVersion,
+		&click.OS,
+		&click.OSVersion,
+		&click.IsBot,
+		&click.BotReason,
+		&click.ReferrerHost,
+		&click.ReferrerSource,
+		&click.UTMSource,
+		&click.UTMMedium,
+		&click.UTMCampaign,
+		&click.UTMTerm,
+		&click.UTMContent,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &click, nil
+}
+
+func (r *AnalyticsRepository) listClicks(ctx context.Context, query string, args ...any) ([]domain.URLClick, error) {
+	rows, err := r.reader().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clicks := []domain.URLClick{}
+	for rows.Next() {
+		click, err := scanClick(rows)
+		if err != nil {
+			return nil, err
+		}
+		clicks = append(clicks, *click)
+	}
+
+	return clicks, rows.Err()
+}
+
+// GetClickHistory pages through clicks by page number, newest first.
+func (r *AnalyticsRepository) GetClickHistory(ctx context.Context, filter *domain.ClickFilter, page, pageSize int) (*domain.ClickHistory, error) {
 	offset := (page - 1) * pageSize
+	where, args := clickQuery(filter, true)
 
 	var total int64
-	countQuery := `SELECT COUNT(*) FROM url_clicks WHERE url_id = $1`
-	err := r.db.QueryRow(ctx, countQuery, urlID).Scan(&total)
+	countQuery := `SELECT COUNT(*) FROM url_clicks c ` + where
+	err := r.reader().QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM url_clicks c
+		JOIN urls u ON u.id = c.url_id
+		%s
+		ORDER BY c.clicked_at DESC, c.id DESC
+		LIMIT $%d OFFSET $%d
+	`, clickColumns, where, len(args)+1, len(args)+2)
+
+	clicks, err := r.listClicks(ctx, query, append(args, pageSize, offset)...)
 	if err != nil {
 		return nil, err
 	}
 
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	return &domain.ClickHistory{
+		Clicks:     clicks,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetClickHistoryAfter returns the page of clicks that follows filter.After,
+// newest first. It seeks on (clicked_at, id) instead of counting and skipping
+// rows, so deep pages cost the same as the first one.
+func (r *AnalyticsRepository) GetClickHistoryAfter(ctx context.Context, filter *domain.ClickFilter, pageSize int) (*domain.ClickHistory, error) {
+	where, args := clickQuery(filter, true)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM url_clicks c
+		JOIN urls u ON u.id = c.url_id
+		%s
+		ORDER BY c.clicked_at DESC, c.id DESC
+		LIMIT $%d
+	`, clickColumns, where, len(args)+1)
+
+	clicks, err := r.listClicks(ctx, query, append(args, pageSize+1)...)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &domain.ClickHistory{PageSize: pageSize}
+	if len(clicks) > pageSize {
+		clicks = clicks[:pageSize]
+		last := clicks[len(clicks)-1]
+		history.NextCursor = domain.ClickCursor{ClickedAt: last.ClickedAt, ID: last.ID}.Encode()
+	}
+	history.Clicks = clicks
+
+	return history, nil
+}
+
+// ExportClicks passes every click matching filter to fn in (clicked_at, id)
+// order. Rows are read through a server-side cursor in batches, so exports of
+// any size run in constant memory.
+func (r *AnalyticsRepository) ExportClicks(ctx context.Context, filter *domain.ClickFilter, fn func(*domain.URLClick) error) error {
+	where, args := clickQuery(filter, false)
+
+	tx, err := r.reader().BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	declare := fmt.Sprintf(`
+		DECLARE click_export NO SCROLL CURSOR FOR
+		SELECT %s
+		FROM url_clicks c
+		JOIN urls u ON u.id = c.url_id
+		%s
+		ORDER BY c.clicked_at, c.id
+	`, clickColumns, where)
+	if _, err := tx.Exec(ctx, declare, args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM click_export", exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			click, err := scanClick(rows)
+			if err == nil {
+				err = fn(click)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// ListUnsourcedClicks returns up to limit clicks after afterID, in id order,
+// that have no referrer source, as recorded before sources were. Only ID and
+// ReferrerHost are set.
+func (r *AnalyticsRepository) ListUnsourcedClicks(ctx context.Context, afterID int64, limit int) ([]domain.URLClick, error) {
 	query := `
-		SELECT id, url_id, clicked_at, user_agent, referer, ip_address, device_type
+		SELECT id, COALESCE(referrer_host, '')
 		FROM url_clicks
-		WHERE url_id = $1
-		ORDER BY clicked_at DESC
-		LIMIT $2 OFFSET $3
+		WHERE id > $1 AND referrer_source IS NULL
+		ORDER BY id
+		LIMIT $2
 	`
 
-	rows, err := r.db.Query(ctx, query, urlID, pageSize, offset)
+	rows, err := r.db.Query(ctx, query, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -214,28 +857,338 @@ func (r *AnalyticsRepository) GetClickHistory(ctx context.Context, urlID int64,
 	var clicks []domain.URLClick
 	for rows.Next() {
 		var click domain.URLClick
-		err := rows.Scan(
-			&click.ID,
-			&click.URLID,
-			&click.ClickedAt,
-			&click.UserAgent,
-			&click.Referer,
-			&click.IPAddress,
-			&click.DeviceType,
-		)
-		if err != nil {
+		if err := rows.Scan(&click.ID, &click.ReferrerHost); err != nil {
 			return nil, err
 		}
 		clicks = append(clicks, click)
 	}
 
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return clicks, rows.Err()
+}
 
-	return &domain.ClickHistory{
-		Clicks:     clicks,
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-	}, rows.Err()
+// SetReferrerSources sets the referrer source of each click in clicks, by ID,
+// in a single statement.
+func (r *AnalyticsRepository) SetReferrerSources(ctx context.Context, clicks []domain.URLClick) error {
+	ids := make([]int64, len(clicks))
+	sources := make([]string, len(clicks))
+	for i, click := range clicks {
+		ids[i], sources[i] = click.ID, click.ReferrerSource
+	}
+
+	query := `
+		UPDATE url_clicks c
+		SET referrer_source = s.source
+		FROM unnest($1::bigint[], $2::text[]) AS s(id, source)
+		WHERE c.id = s.id
+	`
+
+	_, err := r.db.Exec(ctx, query, ids, sources)
+	return err
+}
+
+// FoldClickDeltas adds up to limit of the oldest clicks in url_click_deltas to
+// their hours in url_click_stats and deletes them, in one statement, and
+// returns how many it folded. Rows another fold is working on are skipped.
+func (r *AnalyticsRepository) FoldClickDeltas(ctx context.Context, limit int) (int64, error) {
+	query := `
+		WITH folded AS (
+			DELETE FROM url_click_deltas
+			WHERE id IN (
+				SELECT id
+				FROM url_click_deltas
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING url_id, hour, clicks, bot_clicks
+		), upserted AS (
+			INSERT INTO url_click_stats (url_id, hour, clicks, bot_clicks)
+			SELECT url_id, hour, SUM(clicks), SUM(bot_clicks)
+			FROM folded
+			GROUP BY url_id, hour
+			ON CONFLICT (url_id, hour) DO UPDATE
+			SET clicks     = url_click_stats.clicks + EXCLUDED.clicks,
+				bot_clicks = url_click_stats.bot_clicks + EXCLUDED.bot_clicks
+		)
+		SELECT COUNT(*) FROM folded
+	`
+
+	var folded int64
+	err := r.db.QueryRow(ctx, query, limit).Scan(&folded)
+	return folded, err
+}
+
+// rollupSpan splits a range into the whole UTC hours counted from the hourly
+// rollup and the partial hours at either end, which are counted from
+// url_clicks. The rollup is url_click_stats plus the clicks in
+// url_click_deltas not yet folded into it.
+type rollupSpan struct {
+	hoursFrom, hoursTo time.Time
+	headFrom, headTo   time.Time
+	tailFrom, tailTo   time.Time
+}
+
+func newRollupSpan(r *domain.TimeRange) rollupSpan {
+	from, to := r.From.UTC(), r.To.UTC()
+
+	hoursFrom := from.Truncate(time.Hour)
+	if hoursFrom.Before(from) {
+		hoursFrom = hoursFrom.Add(time.Hour)
+	}
+	hoursTo := to.Truncate(time.Hour)
+
+	// an hourly row can't be split between buckets, so timezones whose
+	// buckets start on the half hour are counted from url_clicks alone
+	if !hoursFrom.Before(hoursTo) || !wholeHourOffset(r.From, r.Location) || !wholeHourOffset(r.To, r.Location) {
+		return rollupSpan{hoursFrom: from, hoursTo: from, headFrom: from, headTo: to, tailFrom: to, tailTo: to}
+	}
+
+	return rollupSpan{
+		hoursFrom: hoursFrom,
+		hoursTo:   hoursTo,
+		headFrom:  from,
+		headTo:    hoursFrom,
+		tailFrom:  hoursTo,
+		tailTo:    to,
+	}
+}
+
+func wholeHourOffset(t time.Time, loc *time.Location) bool {
+	_, offset := t.In(loc).Zone()
+	return offset%3600 == 0
+}
+
+// rangeClicksQuery selects (url_id, at, clicks) rows for the clicks in span,
+// where at is the start of a rollup hour or the time of a single click.
+// condition, when set, further restricts url_id.
+func rangeClicksQuery(span rollupSpan, includeBots bool, condition string, arg func(any) string) string {
+	if condition != "" {
+		condition = " AND " + condition
+	}
+
+	bots := arg(includeBots)
+	hoursFrom, hoursTo := arg(span.hoursFrom), arg(span.hoursTo)
+	headFrom, headTo := arg(span.headFrom), arg(span.headTo)
+	tailFrom, tailTo := arg(span.tailFrom), arg(span.tailTo)
+
+	return fmt.Sprintf(`
+		SELECT url_id, hour AS at, clicks + CASE WHEN %[1]s THEN bot_clicks ELSE 0 END AS clicks
+		FROM url_click_stats
+		WHERE hour >= %[2]s AND hour < %[3]s%[8]s
+		UNION ALL
+		SELECT url_id, hour AS at, clicks + CASE WHEN %[1]s THEN bot_clicks ELSE 0 END AS clicks
+		FROM url_click_deltas
+		WHERE hour >= %[2]s AND hour < %[3]s%[8]s
+		UNION ALL
+		SELECT url_id, clicked_at AS at, 1 AS clicks
+		FROM url_clicks
+		WHERE (NOT is_bot OR %[1]s)
+			AND ((clicked_at >= %[4]s AND clicked_at < %[5]s) OR (clicked_at >= %[6]s AND clicked_at < %[7]s))%[8]s
+	`, bots, hoursFrom, hoursTo, headFrom, headTo, tailFrom, tailTo, condition)
+}
+
+// linkSetConditions filters urls aliased as u down to the links in links.
+func linkSetConditions(links *domain.LinkSet, arg func(any) string) []string {
+	var conditions []string
+	if len(links.ShortCodes) > 0 {
+		conditions = append(conditions, "u.short_code = ANY("+arg(links.ShortCodes)+")")
+	}
+	if links.CreatedFrom != nil {
+		conditions = append(conditions, "u.created_at >= "+arg(links.CreatedFrom.UTC()))
+	}
+	if links.CreatedTo != nil {
+		conditions = append(conditions, "u.created_at < "+arg(links.CreatedTo.UTC()))
+	}
+	if links.Folder != "" {
+		conditions = append(conditions, "u.folder = "+arg(links.Folder))
+	}
+	if links.Owner != "" {
+		conditions = append(conditions, "u.owner = "+arg(links.Owner))
+	}
+	if links.Tag != "" {
+		conditions = append(conditions, `u.id IN (
+			SELECT ut.url_id
+			FROM url_tags ut
+			JOIN tags t ON t.id = ut.tag_id
+			WHERE t.name = `+arg(links.Tag)+`
+		)`)
+	}
+	return conditions
+}
+
+// linkSetCondition restricts url_id to the links in links, or returns an
+// empty string when the set matches every link.
+func linkSetCondition(links *domain.LinkSet, arg func(any) string) string {
+	conditions := linkSetConditions(links, arg)
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "url_id IN (SELECT u.id FROM urls u WHERE " + strings.Join(conditions, " AND ") + ")"
+}
+
+func newArgs() (*[]any, func(any) string) {
+	var args []any
+	return &args, func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+}
+
+// GetOverview aggregates the clicks of every link in links over the range.
+func (r *AnalyticsRepository) GetOverview(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet) (*domain.AnalyticsOverview, error) {
+	overview := &domain.AnalyticsOverview{}
+
+	args, arg := newArgs()
+	clicks := rangeClicksQuery(newRollupSpan(q.Range), q.IncludeBots, linkSetCondition(links, arg), arg)
+	query := fmt.Sprintf(`
+		SELECT bucket, SUM(clicks)::BIGINT, COUNT(DISTINCT url_id)
+		FROM (
+			SELECT url_id, clicks, date_trunc(%s, at AT TIME ZONE 'UTC' AT TIME ZONE %s) AS bucket
+			FROM (%s) c
+		) b
+		GROUP BY GROUPING SETS ((bucket), ())
+	`, arg(q.Range.Granularity), arg(q.Range.Location.String()), clicks)
+
+	rows, err := r.reader().Query(ctx, query, *args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var bucket *time.Time
+		var count, activeLinks int64
+		if err := rows.Scan(&bucket, &count, &activeLinks); err != nil {
+			return nil, err
+		}
+		// the grand total row has no bucket
+		if bucket == nil {
+			overview.TotalClicks = count
+			overview.ActiveLinks = activeLinks
+			continue
+		}
+		counts[domain.BucketKey(*bucket)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	buckets := q.Range.Buckets()
+	overview.ClicksByDate = make([]domain.ClicksByDate, len(buckets))
+	for i, bucket := range buckets {
+		overview.ClicksByDate[i] = domain.ClicksByDate{
+			Date:  q.Range.FormatBucket(bucket),
+			Count: counts[domain.BucketKey(bucket)],
+		}
+	}
+
+	linksCreated, err := r.countLinksCreated(ctx, q.Range, links)
+	if err != nil {
+		return nil, err
+	}
+	overview.LinksCreated = linksCreated
+
+	return overview, nil
+}
+
+func (r *AnalyticsRepository) countLinksCreated(ctx context.Context, timeRange *domain.TimeRange, links *domain.LinkSet) (int64, error) {
+	args, arg := newArgs()
+	conditions := append([]string{
+		"u.created_at >= " + arg(timeRange.From.UTC()),
+		"u.created_at < " + arg(timeRange.To.UTC()),
+	}, linkSetConditions(links, arg)...)
+
+	query := "SELECT COUNT(*) FROM urls u WHERE " + strings.Join(conditions, " AND ")
+
+	var count int64
+	err := r.reader().QueryRow(ctx, query, *args...).Scan(&count)
+	return count, err
+}
+
+// CountOverviewClicks counts the clicks of every link in links over the range.
+func (r *AnalyticsRepository) CountOverviewClicks(ctx context.Context, timeRange *domain.TimeRange, includeBots bool, links *domain.LinkSet) (int64, error) {
+	args, arg := newArgs()
+	clicks := rangeClicksQuery(newRollupSpan(timeRange), includeBots, linkSetCondition(links, arg), arg)
+	query := fmt.Sprintf(`SELECT COALESCE(SUM(clicks), 0)::BIGINT FROM (%s) c`, clicks)
+
+	var count int64
+	err := r.reader().QueryRow(ctx, query, *args...).Scan(&count)
+	return count, err
+}
+
+// GetTopLinks returns the links in links with the most clicks over the range,
+// along with their clicks over the range before it.
+func (r *AnalyticsRepository) GetTopLinks(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet, limit int) ([]domain.LinkStats, error) {
+	args, arg := newArgs()
+	clicks := rangeClicksQuery(newRollupSpan(q.Range), q.IncludeBots, linkSetCondition(links, arg), arg)
+	query := fmt.Sprintf(`
+		SELECT u.id, u.short_code, u.original_url, u.created_at, t.clicks
+		FROM (
+			SELECT url_id, SUM(clicks)::BIGINT AS clicks
+			FROM (%s) c
+			GROUP BY url_id
+			ORDER BY clicks DESC, url_id
+			LIMIT %s
+		) t
+		JOIN urls u ON u.id = t.url_id
+		ORDER BY t.clicks DESC, t.url_id
+	`, clicks, arg(limit))
+
+	rows, err := r.reader().Query(ctx, query, *args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.LinkStats
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var ls domain.LinkStats
+		if err := rows.Scan(&id, &ls.ShortCode, &ls.OriginalURL, &ls.CreatedAt, &ls.Clicks); err != nil {
+			return nil, err
+		}
+		results = append(results, ls)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	previous, err := r.countClicksByLink(ctx, q.Range.Previous(), q.IncludeBots, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		results[i].PreviousClicks = previous[id]
+	}
+
+	return results, nil
+}
+
+func (r *AnalyticsRepository) countClicksByLink(ctx context.Context, timeRange *domain.TimeRange, includeBots bool, ids []int64) (map[int64]int64, error) {
+	args, arg := newArgs()
+	clicks := rangeClicksQuery(newRollupSpan(timeRange), includeBots, "url_id = ANY("+arg(ids)+")", arg)
+	query := fmt.Sprintf(`SELECT url_id, SUM(clicks)::BIGINT FROM (%s) c GROUP BY url_id`, clicks)
+
+	rows, err := r.reader().Query(ctx, query, *args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int64, len(ids))
+	for rows.Next() {
+		var id, count int64
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+
+	return counts, rows.Err()
 }
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditRepository stores audit events. The table only accepts inserts, so
+// events can't be changed or removed once recorded.
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (actor, action, target, before, after, client_ip, request_id)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING id, occurred_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		event.Actor,
+		event.Action,
+		event.Target,
+		nullJSON(event.Before),
+		nullJSON(event.After),
+		event.ClientIP,
+		event.RequestID,
+	).Scan(&event.ID, &event.OccurredAt)
+}
+
+// List returns a page of the events matching filter, newest first.
+func (r *AuditRepository) List(ctx context.Context, filter *domain.AuditFilter, page, pageSize int) (*domain.AuditLog, error) {
+	args, arg := newArgs()
+	var conditions []string
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = "+arg(filter.Actor))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+arg(filter.Action))
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, "target = "+arg(filter.Target))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "occurred_at >= "+arg(filter.From.UTC()))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "occurred_at < "+arg(filter.To.UTC()))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_events `+where, *args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT id, occurred_at, COALESCE(actor, ''), action, target, before, after,
+			COALESCE(client_ip, ''), COALESCE(request_id, '')
+		FROM audit_events
+		%s
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT %s OFFSET %s
+	`, where, arg(pageSize), arg(offset))
+
+	rows, err := r.db.Query(ctx, query, *args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.AuditEvent{}
+	for rows.Next() {
+		var event domain.AuditEvent
+		err := rows.Scan(&event.ID, &event.OccurredAt, &event.Actor, &event.Action, &event.Target,
+			&event.Before, &event.After, &event.ClientIP, &event.RequestID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize != 0 {
+		totalPages++
+	}
+
+	return &domain.AuditLog{
+		Events:     events,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// nullJSON stores an absent payload as NULL rather than an invalid empty
+// JSON document.
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
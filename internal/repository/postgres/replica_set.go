@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaCheckTimeout bounds each replica health check, so one unreachable
+// replica can't hold up checking the others.
+const replicaCheckTimeout = 2 * time.Second
+
+// replicaLagQuery returns how far a replica's replay is behind, in seconds. A
+// replica that has replayed everything it received isn't lagging however long
+// ago the primary last wrote, and a server that isn't a standby never lags.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END::float8
+`
+
+// ReplicaSet spreads read-only queries over the read replicas that are
+// reachable and at most maxLag behind the primary, and sends them to the
+// primary when none is. Replicas count as unhealthy until their first check.
+type ReplicaSet struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	maxLag   time.Duration
+	healthy  atomic.Pointer[[]*pgxpool.Pool]
+	next     atomic.Uint64
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy bool
+	checked bool
+}
+
+func NewReplicaSet(primary *pgxpool.Pool, replicas []*pgxpool.Pool, maxLag time.Duration) *ReplicaSet {
+	s := &ReplicaSet{primary: primary, maxLag: maxLag}
+	for _, pool := range replicas {
+		s.replicas = append(s.replicas, &replica{pool: pool})
+	}
+	s.healthy.Store(&[]*pgxpool.Pool{})
+	return s
+}
+
+// Reader returns the pool the next read-only query should run on, taking the
+// healthy replicas in turn.
+func (s *ReplicaSet) Reader() *pgxpool.Pool {
+	healthy := *s.healthy.Load()
+	if len(healthy) == 0 {
+		return s.primary
+	}
+	return healthy[s.next.Add(1)%uint64(len(healthy))]
+}
+
+// Healthy returns how many replicas are taking reads.
+func (s *ReplicaSet) Healthy() int {
+	return len(*s.healthy.Load())
+}
+
+// Run checks every replica at startup and then every interval until ctx is
+// cancelled.
+func (s *ReplicaSet) Run(ctx context.Context, interval time.Duration) {
+	s.check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+func (s *ReplicaSet) check(ctx context.Context) {
+	log := logger.Get()
+
+	for _, r := range s.replicas {
+		lag, err := r.lag(ctx)
+		healthy := err == nil && lag <= s.maxLag
+		if r.healthy == healthy && r.checked {
+			continue
+		}
+		r.healthy, r.checked = healthy, true
+
+		host := r.pool.Config().ConnConfig.Host
+		switch {
+		case healthy:
+			log.Info("Read replica available", "host", host, "lag", lag)
+		case err != nil:
+			log.Warn("Read replica unavailable", "host", host, "error", err)
+		default:
+			log.Warn("Read replica lagging", "host", host, "lag", lag, "max_lag", s.maxLag)
+		}
+	}
+
+	s.publish()
+}
+
+// publish makes the replicas that passed their last check take reads.
+func (s *ReplicaSet) publish() {
+	healthy := []*pgxpool.Pool{}
+	for _, r := range s.replicas {
+		if r.healthy {
+			healthy = append(healthy, r.pool)
+		}
+	}
+	s.healthy.Store(&healthy)
+}
+
+func (r *replica) lag(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	var seconds float64
+	if err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Close closes the replica pools. The primary is closed by its owner.
+func (s *ReplicaSet) Close() {
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+// readPool returns the pool a read-only query runs on: a replica when
+// replicas is set and one is healthy, and db otherwise.
+func readPool(db *pgxpool.Pool, replicas *ReplicaSet) *pgxpool.Pool {
+	if replicas == nil {
+		return db
+	}
+	return replicas.Reader()
+}
+
+// replicaUnavailable reports whether err means a replica couldn't answer,
+// rather than that it answered: it couldn't be reached, the connection broke,
+// the query timed out or was cancelled, or the replica is shutting down.
+func replicaUnavailable(err error) bool {
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	if pgconn.Timeout(err) || pgconn.SafeToRetry(err) || errors.As(err, &connectErr) || errors.As(err, &netErr) {
+		return true
+	}
+
+	// connection exceptions and operator intervention, which includes
+	// statement timeouts and queries cancelled by recovery conflicts
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57"))
+}
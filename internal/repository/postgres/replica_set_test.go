@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPool creates a pool without connecting to it.
+func newPool(t *testing.T, host string) *pgxpool.Pool {
+	pool, err := pgxpool.New(context.Background(), "postgres://user@"+host+"/urlshortener?connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestReplicaSet_RotatesHealthyReplicas(t *testing.T) {
+	primary := newPool(t, "primary:5432")
+	first, second, down := newPool(t, "replica-1:5432"), newPool(t, "replica-2:5432"), newPool(t, "replica-3:5432")
+	s := NewReplicaSet(primary, []*pgxpool.Pool{first, second, down}, time.Second)
+	s.replicas[0].healthy = true
+	s.replicas[1].healthy = true
+	s.publish()
+
+	seen := map[*pgxpool.Pool]int{}
+	for i := 0; i < 6; i++ {
+		seen[s.Reader()]++
+	}
+
+	assert.Equal(t, 3, seen[first])
+	assert.Equal(t, 3, seen[second])
+	assert.Zero(t, seen[down])
+	assert.Equal(t, 2, s.Healthy())
+}
+
+func TestReplicaSet_FallsBackToPrimary(t *testing.T) {
+	primary := newPool(t, "primary:5432")
+	s := NewReplicaSet(primary, []*pgxpool.Pool{newPool(t, "replica-1:5432")}, time.Second)
+
+	// replicas take no reads before their first check
+	assert.Same(t, primary, s.Reader())
+	assert.Same(t, primary, readPool(primary, nil))
+}
+
+func TestReplicaSet_CheckMarksUnreachableReplicaUnhealthy(t *testing.T) {
+	s := NewReplicaSet(newPool(t, "primary:5432"), []*pgxpool.Pool{newPool(t, "127.0.0.1:1")}, time.Second)
+	s.replicas[0].healthy = true
+	s.publish()
+
+	s.check(context.Background())
+
+	assert.Zero(t, s.Healthy())
+}
+
+func TestReplicaUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no rows", pgx.ErrNoRows, false},
+		{"wrapped no rows", fmt.Errorf("scan: %w", pgx.ErrNoRows), false},
+		{"query error", &pgconn.PgError{Code: "42P01"}, false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"statement timeout", &pgconn.PgError{Code: "57014"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"connection reset", &net.OpError{Op: "read", Err: io.ErrUnexpectedEOF}, true},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, replicaUnavailable(tt.err))
+		})
+	}
+}
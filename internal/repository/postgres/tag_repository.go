@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TagRepository manages tags as a whole. Tags live in their own table, so
+// none of these touch the urls rows that redirects read.
+type TagRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTagRepository(db *pgxpool.Pool) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+func (r *TagRepository) List(ctx context.Context) ([]domain.Tag, error) {
+	query := `
+		SELECT t.name, t.created_at, COUNT(ut.url_id)
+		FROM tags t
+		LEFT JOIN url_tags ut ON ut.tag_id = t.id
+		GROUP BY t.id, t.name, t.created_at
+		ORDER BY t.name
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []domain.Tag{}
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.Name, &tag.CreatedAt, &tag.LinkCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *TagRepository) Rename(ctx context.Context, name, newName string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE tags SET name = $2 WHERE name = $1`, name, newName)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrTagExists
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrTagNotFound
+	}
+	return nil
+}
+
+// Merge moves every link tagged source over to target and deletes source.
+func (r *TagRepository) Merge(ctx context.Context, source, target string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var sourceID, targetID int64
+		if err := tx.QueryRow(ctx, `SELECT id FROM tags WHERE name = $1`, source).Scan(&sourceID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrTagNotFound
+			}
+			return err
+		}
+		if err := tx.QueryRow(ctx, `SELECT id FROM tags WHERE name = $1`, target).Scan(&targetID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrTagNotFound
+			}
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO url_tags (url_id, tag_id)
+			SELECT url_id, $2 FROM url_tags WHERE tag_id = $1
+			ON CONFLICT DO NOTHING
+		`, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM tags WHERE id = $1`, sourceID)
+		return err
+	})
+}
+
+func (r *TagRepository) Delete(ctx context.Context, name string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM tags WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrTagNotFound
+	}
+	return nil
+}
@@ -2,40 +2,80 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type URLRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	replicas *ReplicaSet
 }
 
-func NewURLRepository(db *pgxpool.Pool) *URLRepository {
-	return &URLRepository{db: db}
+// NewURLRepository reads redirect lookups and link listings from replicas
+// when given any. replicas may be nil to run everything on db.
+func NewURLRepository(db *pgxpool.Pool, replicas *ReplicaSet) *URLRepository {
+	return &URLRepository{db: db, replicas: replicas}
 }
 
 func (r *URLRepository) Create(ctx context.Context, url *domain.URL) error {
-	query := `
-		INSERT INTO urls (short_code, original_url, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at, updated_at
-	`
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			INSERT INTO urls (short_code, original_url, expires_at, title, notes, folder, og_title, og_description, og_image, owner)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''))
+			RETURNING id, created_at, updated_at
+		`
+
+		card := url.SocialCard
+		err := tx.QueryRow(ctx, query, url.ShortCode, url.OriginalURL, url.ExpiresAt, url.Title, url.Notes, url.Folder,
+			card.Title, card.Description, card.Image, url.Owner).
+			Scan(&url.ID, &url.CreatedAt, &url.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		url.Version, err = recordVersion(ctx, tx, url.ID, domain.ChangeCreate, nil, "")
+		if err != nil {
+			return err
+		}
 
-	return r.db.QueryRow(ctx, query, url.ShortCode, url.OriginalURL, url.ExpiresAt).Scan(&url.ID, &url.CreatedAt, &url.UpdatedAt)
+		return setTags(ctx, tx, url.ID, url.Tags)
+	})
 }
 
+func (r *URLRepository) reader() *pgxpool.Pool {
+	return readPool(r.db, r.replicas)
+}
+
+// GetByShortCode reads an active link from a replica, and from the primary
+// when the replica can't be reached or times out. A code the replica hasn't
+// got isn't asked again, so lookups of unknown codes don't all reach the
+// primary too; new links are cached when they are created.
 func (r *URLRepository) GetByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	pool := r.reader()
+	url, err := getByShortCode(ctx, pool, shortCode)
+	if err != nil && pool != r.db && ctx.Err() == nil && replicaUnavailable(err) {
+		return getByShortCode(ctx, r.db, shortCode)
+	}
+	return url, err
+}
+
+func getByShortCode(ctx context.Context, db *pgxpool.Pool, shortCode string) (*domain.URL, error) {
 	var url domain.URL
 
 	query := `
-		SELECT id, short_code, original_url, click_count, created_at, updated_at, expires_at, is_active 
+		SELECT id, short_code, original_url, click_count, created_at, updated_at, expires_at, is_active, version
 		FROM urls
 		WHERE short_code = $1 AND is_active = true
 		AND (expires_at IS NULL OR expires_at > NOW())
 	`
 
-	row := r.db.QueryRow(ctx, query, shortCode)
+	row := db.QueryRow(ctx, query, shortCode)
 
 	err := row.Scan(
 		&url.ID,
@@ -46,6 +86,7 @@ func (r *URLRepository) GetByShortCode(ctx context.Context, shortCode string) (*
 		&url.UpdatedAt,
 		&url.ExpiresAt,
 		&url.IsActive,
+		&url.Version,
 	)
 
 	if err != nil {
@@ -54,3 +95,413 @@ func (r *URLRepository) GetByShortCode(ctx context.Context, shortCode string) (*
 
 	return &url, nil
 }
+
+// urlColumns selects a link from urls aliased as u, including its metadata,
+// social card, folder, owner and tags. Redirects need none of these, so GetByShortCode leaves
+// them out.
+const urlColumns = `
+	u.id, u.short_code, u.original_url, u.click_count, u.created_at, u.updated_at, u.expires_at, u.is_active, u.version,
+	COALESCE(u.title, ''), COALESCE(u.notes, ''), COALESCE(u.folder, ''), COALESCE(u.owner, ''),
+	COALESCE(u.og_title, ''), COALESCE(u.og_description, ''), COALESCE(u.og_image, ''),
+	COALESCE(u.preview_page_title, ''), COALESCE(u.preview_og_title, ''), COALESCE(u.preview_og_description, ''),
+	COALESCE(u.preview_og_image, ''), COALESCE(u.preview_favicon_url, ''), u.preview_fetched_at,
+	ARRAY(
+		SELECT t.name
+		FROM url_tags ut
+		JOIN tags t ON t.id = ut.tag_id
+		WHERE ut.url_id = u.id
+		ORDER BY t.name
+	)
+`
+
+func scanURL(row pgx.Row) (*domain.URL, error) {
+	var url domain.URL
+	var preview domain.LinkPreview
+	var fetchedAt *time.Time
+	err := row.Scan(
+		&url.ID,
+		&url.ShortCode,
+		&url.OriginalURL,
+		&url.ClickCount,
+		&url.CreatedAt,
+		&url.UpdatedAt,
+		&url.ExpiresAt,
+		&url.IsActive,
+		&url.Version,
+		&url.Title,
+		&url.Notes,
+		&url.Folder,
+		&url.Owner,
+		&url.SocialCard.Title,
+		&url.SocialCard.Description,
+		&url.SocialCard.Image,
+		&preview.PageTitle,
+		&preview.OGTitle,
+		&preview.OGDescription,
+		&preview.OGImage,
+		&preview.FaviconURL,
+		&fetchedAt,
+		&url.Tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if fetchedAt != nil {
+		preview.FetchedAt = *fetchedAt
+		url.Preview = &preview
+	}
+	return &url, nil
+}
+
+// GetDetails returns a link with its folder and tags, whether or not it is
+// still active.
+func (r *URLRepository) GetDetails(ctx context.Context, shortCode string) (*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls u WHERE u.short_code = $1`
+	return scanURL(r.db.QueryRow(ctx, query, shortCode))
+}
+
+// List returns the links in links, newest first.
+func (r *URLRepository) List(ctx context.Context, links *domain.LinkSet, page, pageSize int) (*domain.URLList, error) {
+	db := r.reader()
+	args, arg := newArgs()
+	where := ""
+	if conditions := linkSetConditions(links, arg); len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM urls u `+where, *args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM urls u
+		%s
+		ORDER BY u.created_at DESC, u.id DESC
+		LIMIT %s OFFSET %s
+	`, urlColumns, where, arg(pageSize), arg(offset))
+
+	rows, err := db.Query(ctx, query, *args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := []domain.URL{}
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize != 0 {
+		totalPages++
+	}
+
+	return &domain.URLList{
+		URLs:       urls,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// Update changes a link, leaving nil fields as they are. Changes to its
+// destination, expiry or active flag are recorded as a new version.
+func (r *URLRepository) Update(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error) {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			UPDATE urls
+			SET original_url = COALESCE($14, original_url),
+				expires_at = CASE
+					WHEN $15::int IS NULL THEN expires_at
+					WHEN $15 > 0 THEN NOW() + make_interval(hours => $15)
+				END,
+				expiry_notified_at = CASE WHEN $15::int IS NULL THEN expiry_notified_at END,
+				is_active = COALESCE($16, is_active),
+				title = CASE WHEN $2 THEN NULLIF($3, '') ELSE title END,
+				notes = CASE WHEN $4 THEN NULLIF($5, '') ELSE notes END,
+				folder = CASE WHEN $6 THEN NULLIF($7, '') ELSE folder END,
+				og_title = CASE WHEN $8 THEN NULLIF($9, '') ELSE og_title END,
+				og_description = CASE WHEN $10 THEN NULLIF($11, '') ELSE og_description END,
+				og_image = CASE WHEN $12 THEN NULLIF($13, '') ELSE og_image END,
+				updated_at = NOW()
+			WHERE short_code = $1
+			RETURNING id
+		`
+
+		setTitle, title := optional(req.Title)
+		setNotes, notes := optional(req.Notes)
+		setFolder, folder := optional(req.Folder)
+		setOGTitle, ogTitle := optional(req.OGTitle)
+		setOGDescription, ogDescription := optional(req.OGDescription)
+		setOGImage, ogImage := optional(req.OGImage)
+
+		var id int64
+		err := tx.QueryRow(ctx, query, shortCode, setTitle, title, setNotes, notes, setFolder, folder,
+			setOGTitle, ogTitle, setOGDescription, ogDescription, setOGImage, ogImage,
+			req.OriginalURL, req.ExpiryHours, req.IsActive).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := recordVersion(ctx, tx, id, domain.ChangeUpdate, nil, req.ChangedBy); err != nil {
+			return err
+		}
+
+		if req.Tags == nil {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM url_tags WHERE url_id = $1`, id); err != nil {
+			return err
+		}
+		return setTags(ctx, tx, id, *req.Tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetDetails(ctx, shortCode)
+}
+
+// Rollback points a link back at what it pointed to in version, recording the
+// result as a new version.
+func (r *URLRepository) Rollback(ctx context.Context, shortCode string, version int, changedBy string) (*domain.URL, error) {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var id int64
+		err := tx.QueryRow(ctx, `SELECT id FROM urls WHERE short_code = $1 FOR UPDATE`, shortCode).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE urls u
+			SET original_url = v.original_url,
+				expires_at = v.expires_at,
+				expiry_notified_at = CASE WHEN v.expires_at IS NOT DISTINCT FROM u.expires_at THEN u.expiry_notified_at END,
+				is_active = v.is_active,
+				updated_at = NOW()
+			FROM url_versions v
+			WHERE u.id = $1 AND v.url_id = u.id AND v.version = $2
+		`, id, version)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrVersionNotFound
+		}
+
+		_, err = recordVersion(ctx, tx, id, domain.ChangeRollback, &version, changedBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetDetails(ctx, shortCode)
+}
+
+// ListHot returns up to limit live links with the most clicks since since,
+// busiest first. Clicks are counted from the hourly rollup, so since is
+// rounded down to the hour.
+func (r *URLRepository) ListHot(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	query := `
+		SELECT u.id, u.short_code, u.original_url, u.click_count, u.created_at, u.updated_at, u.expires_at, u.is_active, u.version
+		FROM (
+			SELECT url_id, clicks + bot_clicks AS clicks FROM url_click_stats WHERE hour >= $1
+			UNION ALL
+			SELECT url_id, clicks + bot_clicks FROM url_click_deltas WHERE hour >= $1
+		) s
+		JOIN urls u ON u.id = s.url_id
+		WHERE u.is_active = true
+		AND (u.expires_at IS NULL OR u.expires_at > NOW())
+		GROUP BY u.id
+		ORDER BY SUM(s.clicks) DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, since.UTC().Truncate(time.Hour), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []*domain.URL
+	for rows.Next() {
+		var url domain.URL
+		if err := rows.Scan(
+			&url.ID,
+			&url.ShortCode,
+			&url.OriginalURL,
+			&url.ClickCount,
+			&url.CreatedAt,
+			&url.UpdatedAt,
+			&url.ExpiresAt,
+			&url.IsActive,
+			&url.Version,
+		); err != nil {
+			return nil, err
+		}
+		urls = append(urls, &url)
+	}
+	return urls, rows.Err()
+}
+
+func (r *URLRepository) CountShortCodes(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM urls`).Scan(&count)
+	return count, err
+}
+
+// EachShortCode calls fn with every short code, streaming them rather than
+// loading them all at once.
+func (r *URLRepository) EachShortCode(ctx context.Context, fn func(shortCode string)) error {
+	rows, err := r.db.Query(ctx, `SELECT short_code FROM urls`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var shortCode string
+	for rows.Next() {
+		if err := rows.Scan(&shortCode); err != nil {
+			return err
+		}
+		fn(shortCode)
+	}
+	return rows.Err()
+}
+
+// ListVersions returns the version history of a link, newest first.
+func (r *URLRepository) ListVersions(ctx context.Context, shortCode string) ([]domain.URLVersion, error) {
+	query := `
+		SELECT v.version, v.original_url, v.expires_at, v.is_active, v.change_type,
+			v.restored_version, COALESCE(v.changed_by, ''), v.created_at
+		FROM url_versions v
+		JOIN urls u ON u.id = v.url_id
+		WHERE u.short_code = $1
+		ORDER BY v.version DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []domain.URLVersion
+	for rows.Next() {
+		var v domain.URLVersion
+		err := rows.Scan(&v.Version, &v.OriginalURL, &v.ExpiresAt, &v.IsActive, &v.ChangeType,
+			&v.RestoredVersion, &v.ChangedBy, &v.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// every link has at least the version it was created with
+	if len(versions) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return versions, nil
+}
+
+// recordVersion snapshots the destination, expiry and active flag of a link
+// as its next version, unless they match the latest version. It returns the
+// link's current version.
+func recordVersion(ctx context.Context, tx pgx.Tx, urlID int64, changeType string, restoredVersion *int, changedBy string) (int, error) {
+	query := `
+		WITH latest AS (
+			SELECT version, original_url, expires_at, is_active
+			FROM url_versions
+			WHERE url_id = $1
+			ORDER BY version DESC
+			LIMIT 1
+		), inserted AS (
+			INSERT INTO url_versions (url_id, version, original_url, expires_at, is_active, change_type, restored_version, changed_by)
+			SELECT u.id, COALESCE(l.version, 0) + 1, u.original_url, u.expires_at, u.is_active, $2, $3::int, NULLIF($4, '')
+			FROM urls u
+			LEFT JOIN latest l ON true
+			WHERE u.id = $1
+				AND (l.version IS NULL
+					OR (u.original_url, u.expires_at, u.is_active) IS DISTINCT FROM (l.original_url, l.expires_at, l.is_active))
+			RETURNING version
+		)
+		UPDATE urls
+		SET version = inserted.version
+		FROM inserted
+		WHERE urls.id = $1
+		RETURNING urls.version
+	`
+
+	var version int
+	err := tx.QueryRow(ctx, query, urlID, changeType, restoredVersion, changedBy).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.QueryRow(ctx, `SELECT version FROM urls WHERE id = $1`, urlID).Scan(&version)
+	}
+	return version, err
+}
+
+// SavePreview stores the metadata fetched from a link's destination page. It
+// is only stored while the link still points at destination, so a slow fetch
+// of a destination that has since changed doesn't replace the new one's.
+func (r *URLRepository) SavePreview(ctx context.Context, urlID int64, destination string, preview *domain.LinkPreview) error {
+	query := `
+		UPDATE urls
+		SET preview_page_title = NULLIF($2, ''),
+			preview_og_title = NULLIF($3, ''),
+			preview_og_description = NULLIF($4, ''),
+			preview_og_image = NULLIF($5, ''),
+			preview_favicon_url = NULLIF($6, ''),
+			preview_fetched_at = $7
+		WHERE id = $1 AND original_url = $8
+	`
+
+	_, err := r.db.Exec(ctx, query, urlID, preview.PageTitle, preview.OGTitle, preview.OGDescription,
+		preview.OGImage, preview.FaviconURL, preview.FetchedAt.UTC(), destination)
+	return err
+}
+
+func optional(value *string) (bool, string) {
+	if value == nil {
+		return false, ""
+	}
+	return true, *value
+}
+
+// setTags adds tags to a link, creating the tags that don't exist yet.
+func setTags(ctx context.Context, tx pgx.Tx, urlID int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO tags (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING
+	`, tags)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO url_tags (url_id, tag_id)
+		SELECT $1, id FROM tags WHERE name = ANY($2)
+		ON CONFLICT DO NOTHING
+	`, urlID, tags)
+	return err
+}
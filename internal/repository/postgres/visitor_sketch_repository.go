@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type VisitorSketchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewVisitorSketchRepository(db *pgxpool.Pool) *VisitorSketchRepository {
+	return &VisitorSketchRepository{db: db}
+}
+
+func (r *VisitorSketchRepository) SaveSketch(ctx context.Context, sketch *domain.VisitorSketch) error {
+	day, err := parseSketchDay(sketch.Day)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO url_visitor_sketches (url_id, day, sketch)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (url_id, day) DO UPDATE
+		SET sketch = EXCLUDED.sketch,
+			updated_at = NOW()
+	`
+	_, err = r.db.Exec(ctx, query, sketch.URLID, day, sketch.Data)
+	return err
+}
+
+func (r *VisitorSketchRepository) GetSketches(ctx context.Context, urlID int64, days []string) ([]domain.VisitorSketch, error) {
+	query := `
+		SELECT COALESCE(TO_CHAR(day, 'YYYY-MM-DD'), '') as day, sketch
+		FROM url_visitor_sketches
+		WHERE url_id = $1
+			AND COALESCE(TO_CHAR(day, 'YYYY-MM-DD'), '') = ANY($2)
+	`
+
+	rows, err := r.db.Query(ctx, query, urlID, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sketches []domain.VisitorSketch
+	for rows.Next() {
+		sketch := domain.VisitorSketch{URLID: urlID}
+		if err := rows.Scan(&sketch.Day, &sketch.Data); err != nil {
+			return nil, err
+		}
+		sketches = append(sketches, sketch)
+	}
+
+	return sketches, rows.Err()
+}
+
+func parseSketchDay(day string) (*time.Time, error) {
+	if day == domain.AllTimeSketch {
+		return nil, nil
+	}
+
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRepository stores webhook subscriptions and their delivery queue.
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, hook *domain.Webhook) error {
+	query := `
+		INSERT INTO webhooks (url, secret, events, click_milestones)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, hook.URL, hook.Secret, hook.Events, hook.ClickMilestones).
+		Scan(&hook.ID, &hook.CreatedAt)
+}
+
+// List returns every webhook, oldest first, without their secrets.
+func (r *WebhookRepository) List(ctx context.Context) ([]domain.Webhook, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, url, events, click_milestones, created_at
+		FROM webhooks
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []domain.Webhook{}
+	for rows.Next() {
+		var hook domain.Webhook
+		if err := rows.Scan(&hook.ID, &hook.URL, &hook.Events, &hook.ClickMilestones, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// Get returns a webhook without its secret.
+func (r *WebhookRepository) Get(ctx context.Context, id int64) (*domain.Webhook, error) {
+	var hook domain.Webhook
+	err := r.db.QueryRow(ctx, `
+		SELECT id, url, events, click_milestones, created_at
+		FROM webhooks
+		WHERE id = $1
+	`, id).Scan(&hook.ID, &hook.URL, &hook.Events, &hook.ClickMilestones, &hook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// Delete removes a webhook along with its deliveries.
+func (r *WebhookRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Enqueue queues event for each of the webhooks. Webhooks that already have
+// an event with the same key queued are skipped.
+func (r *WebhookRepository) Enqueue(ctx context.Context, event *domain.WebhookEvent, webhookIDs []int64) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, event_key, payload)
+		SELECT id, $2::varchar, $3::varchar, $4::varchar, $5::jsonb
+		FROM unnest($1::bigint[]) AS id
+		ON CONFLICT (webhook_id, event_key) DO NOTHING
+	`, webhookIDs, event.ID, event.Type, event.Key, string(payload))
+	return err
+}
+
+// ClaimDeliveries leases up to limit due deliveries and counts the attempt.
+// A claimed delivery isn't due again until lease has passed, so it is only
+// retried that soon if the worker sending it goes away. Workers never claim
+// the same delivery.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.ClaimedDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id
+		AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload::text, d.attempts, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.ClaimedDelivery
+	for rows.Next() {
+		var d domain.ClaimedDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'succeeded', response_status = $2, last_error = NULL, delivered_at = NOW()
+		WHERE id = $1
+	`, id, responseStatus)
+	return err
+}
+
+// MarkFailed records a failed attempt. The delivery is retried at retryAt, or
+// left dead when retryAt is nil. responseStatus is 0 when the subscriber never
+// responded.
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id int64, responseStatus int, lastError string, retryAt *time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $4::timestamp IS NULL THEN 'dead' ELSE 'pending' END,
+			next_attempt_at = COALESCE($4, next_attempt_at),
+			response_status = NULLIF($2, 0),
+			last_error = $3
+		WHERE id = $1
+	`, id, responseStatus, lastError, utcTime(retryAt))
+	return err
+}
+
+// Retry queues a dead delivery again with a fresh set of attempts.
+func (r *WebhookRepository) Retry(ctx context.Context, webhookID, deliveryID int64) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND webhook_id = $2 AND status = 'dead'
+	`, deliveryID, webhookID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns a page of a webhook's deliveries, newest first,
+// optionally only those with the given status.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, status string, page, pageSize int) (*domain.DeliveryLog, error) {
+	args, arg := newArgs()
+	where := "WHERE webhook_id = " + arg(webhookID)
+	if status != "" {
+		where += " AND status = " + arg(status)
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_deliveries `+where, *args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT id, webhook_id, event_id, event_type, payload, status, attempts,
+			CASE WHEN status = 'pending' THEN next_attempt_at END,
+			COALESCE(last_error, ''), response_status, created_at, delivered_at
+		FROM webhook_deliveries
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT %s OFFSET %s
+	`, where, arg(pageSize), arg(offset))
+
+	rows, err := r.db.Query(ctx, query, *args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastError, &d.ResponseStatus, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize != 0 {
+		totalPages++
+	}
+
+	return &domain.DeliveryLog{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// ClaimExpiredLinks returns up to limit links that have expired since they
+// were last claimed, marking them so each expiry is only reported once.
+func (r *WebhookRepository) ClaimExpiredLinks(ctx context.Context, limit int) ([]domain.URL, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE urls
+		SET expiry_notified_at = NOW()
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE expires_at <= NOW() AND expiry_notified_at IS NULL
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, short_code, original_url, expires_at
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []domain.URL
+	for rows.Next() {
+		var url domain.URL
+		if err := rows.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.ExpiresAt); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrClickStreamClosed = errors.New("click stream closed")
+
+// ClickStream fans recorded clicks out to every replica over Redis Pub/Sub.
+type ClickStream struct {
+	client redis.UniversalClient
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewClickStream(client redis.UniversalClient) *ClickStream {
+	return &ClickStream{client: client, done: make(chan struct{})}
+}
+
+func clickChannel(urlID int64) string {
+	return fmt.Sprintf("clicks:{url:%d}", urlID)
+}
+
+func (s *ClickStream) Publish(ctx context.Context, urlID int64, event *domain.ClickEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Publish(ctx, clickChannel(urlID), data).Err()
+}
+
+// Subscribe delivers clicks for urlID until ctx is cancelled or the stream is
+// closed, after which the returned channel is closed.
+func (s *ClickStream) Subscribe(ctx context.Context, urlID int64) (<-chan *domain.ClickEvent, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrClickStreamClosed
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	pubsub := s.client.Subscribe(ctx, clickChannel(urlID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		s.wg.Done()
+		return nil, err
+	}
+
+	events := make(chan *domain.ClickEvent)
+	go func() {
+		defer s.wg.Done()
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event domain.ClickEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					continue
+				}
+
+				select {
+				case events <- &event:
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Close ends every open subscription and waits for them to unsubscribe.
+func (s *ClickStream) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
@@ -2,7 +2,6 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -10,42 +9,154 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// invalidationChannel carries the short codes of changed links to every
+	// replica, so they can drop their in-process copies.
+	invalidationChannel = "url:invalidations"
+	// createdChannel carries the short codes of new links to every replica,
+	// so they can add them to their short code filters.
+	createdChannel = "url:created"
+
+	// notFoundValue is cached in place of a link for short codes that don't
+	// exist.
+	notFoundValue = "-"
+)
+
 type URLCache struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-func NewURLCache(client *redis.Client) *URLCache {
+func NewURLCache(client redis.UniversalClient) *URLCache {
 	return &URLCache{client: client}
 }
 
-func (r *URLCache) GetURL(ctx context.Context, shortCode string) (*domain.URL, error) {
+// GetURL returns a cached link and how long it has left in the cache.
+func (r *URLCache) GetURL(ctx context.Context, shortCode string) (*domain.URL, time.Duration, error) {
 	key := fmt.Sprintf("url:%s", shortCode)
 
-	data, err := r.client.Get(ctx, key).Result()
+	pipe := r.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	_, _ = pipe.Exec(ctx)
+
+	data, err := get.Result()
 
 	if err == redis.Nil {
-		return nil, err
+		return nil, 0, nil
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	var url domain.URL
-	if err := json.Unmarshal([]byte(data), &url); err != nil {
-		return nil, err
+	if data == notFoundValue {
+		return nil, 0, domain.ErrURLNotFound
 	}
 
-	return &url, nil
+	url, err := decodeURL([]byte(data))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// negative when the key has no expiry
+	ttl := max(pttl.Val(), 0)
+
+	return url, ttl, nil
 }
 
 func (r *URLCache) SetURL(ctx context.Context, url *domain.URL, ttl time.Duration) error {
 	key := fmt.Sprintf("url:%s", url.ShortCode)
 
-	data, err := json.Marshal(url)
-	if err != nil {
+	return r.client.Set(ctx, key, encodeURL(url), ttl).Err()
+}
+
+// SetURLs caches many links in one pipeline, each for ttl(url).
+func (r *URLCache) SetURLs(ctx context.Context, urls []*domain.URL, ttl func(url *domain.URL) time.Duration) error {
+	pipe := r.client.Pipeline()
+	for _, url := range urls {
+		pipe.Set(ctx, fmt.Sprintf("url:%s", url.ShortCode), encodeURL(url), ttl(url))
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SetNotFound caches that shortCode doesn't exist. It never replaces a cached
+// link, so a lookup that raced with the link being created can't hide it.
+func (r *URLCache) SetNotFound(ctx context.Context, shortCode string, ttl time.Duration) error {
+	key := fmt.Sprintf("url:%s", shortCode)
+
+	return r.client.SetNX(ctx, key, notFoundValue, ttl).Err()
+}
+
+// AddURL caches a new link, replacing any cached not-found for its code, and
+// tells every replica it exists.
+func (r *URLCache) AddURL(ctx context.Context, url *domain.URL, ttl time.Duration) error {
+	key := fmt.Sprintf("url:%s", url.ShortCode)
+
+	if err := r.client.Set(ctx, key, encodeURL(url), ttl).Err(); err != nil {
 		return err
 	}
+	return r.client.Publish(ctx, createdChannel, url.ShortCode).Err()
+}
 
-	return r.client.Set(ctx, key, data, ttl).Err()
+// DeleteURL removes a link from the cache and tells every replica to drop its
+// in-process copy. The two are separate calls rather than a transaction
+// because Redis Cluster routes the channel to a different slot than the key.
+func (r *URLCache) DeleteURL(ctx context.Context, shortCode string) error {
+	key := fmt.Sprintf("url:%s", shortCode)
+
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, invalidationChannel, shortCode).Err()
+}
+
+// WatchInvalidations calls invalidate with the short code of every link any
+// replica deletes, until ctx is cancelled. reset is called each time the
+// subscription drops and each time it is established again, since
+// invalidations sent while it was down are lost.
+func (r *URLCache) WatchInvalidations(ctx context.Context, invalidate func(shortCode string), reset func()) {
+	r.watch(ctx, invalidationChannel, invalidate, reset)
+}
+
+// WatchCreated calls created with the short code of every link any replica
+// creates, until ctx is cancelled. reset is called each time the subscription
+// drops and each time it is established again, since announcements sent while
+// it was down are lost.
+func (r *URLCache) WatchCreated(ctx context.Context, created func(shortCode string), reset func()) {
+	r.watch(ctx, createdChannel, created, reset)
+}
+
+func (r *URLCache) watch(ctx context.Context, channel string, fn func(shortCode string), reset func()) {
+	pubsub := r.client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	subscribed := false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if subscribed {
+				subscribed = false
+				reset()
+			}
+			// the next Receive reconnects and subscribes again
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+				continue
+			}
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				subscribed = true
+				reset()
+			}
+		case *redis.Message:
+			fn(m.Payload)
+		}
+	}
 }
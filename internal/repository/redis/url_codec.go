@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+)
+
+// Cached links hold only what a redirect needs, in a compact binary encoding:
+//
+//	version byte, flags byte,
+//	uvarint id, uvarint version, varint created_at (Unix microseconds),
+//	[varint expires_at (Unix microseconds), when flagExpires is set],
+//	uvarint short code length, short code, original URL (the rest)
+//
+// The created time is kept because redirects use it to flag clicks that come
+// too soon after a link was created as bots.
+//
+// The leading version byte lets a new format roll out while replicas still
+// write the old one. Entries in an unknown format are reported as invalid, so
+// the link is read from the database and cached again.
+const (
+	encodingV1 byte = 1
+
+	flagActive  byte = 1 << 0
+	flagExpires byte = 1 << 1
+
+	// legacy entries are JSON objects
+	jsonPrefix byte = '{'
+)
+
+func encodeURL(url *domain.URL) []byte {
+	var flags byte
+	if url.IsActive {
+		flags |= flagActive
+	}
+	if url.ExpiresAt != nil {
+		flags |= flagExpires
+	}
+
+	buf := make([]byte, 0, 2+4*binary.MaxVarintLen64+len(url.ShortCode)+len(url.OriginalURL))
+	buf = append(buf, encodingV1, flags)
+	buf = binary.AppendUvarint(buf, uint64(url.ID))
+	buf = binary.AppendUvarint(buf, uint64(url.Version))
+	buf = binary.AppendVarint(buf, url.CreatedAt.UnixMicro())
+	if url.ExpiresAt != nil {
+		buf = binary.AppendVarint(buf, url.ExpiresAt.UnixMicro())
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(url.ShortCode)))
+	buf = append(buf, url.ShortCode...)
+	buf = append(buf, url.OriginalURL...)
+	return buf
+}
+
+func decodeURL(data []byte) (*domain.URL, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty", domain.ErrInvalidCacheEntry)
+	}
+
+	switch data[0] {
+	case encodingV1:
+		return decodeURLV1(data[1:])
+	case jsonPrefix:
+		var url domain.URL
+		if err := json.Unmarshal(data, &url); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidCacheEntry, err)
+		}
+		return &url, nil
+	}
+	return nil, fmt.Errorf("%w: unknown encoding %d", domain.ErrInvalidCacheEntry, data[0])
+}
+
+func decodeURLV1(data []byte) (*domain.URL, error) {
+	d := decoder{data: data}
+
+	flags := d.byte()
+	url := &domain.URL{
+		ID:        int64(d.uvarint()),
+		Version:   int(d.uvarint()),
+		CreatedAt: time.UnixMicro(d.varint()).UTC(),
+		IsActive:  flags&flagActive != 0,
+	}
+	if flags&flagExpires != 0 {
+		expiresAt := time.UnixMicro(d.varint()).UTC()
+		url.ExpiresAt = &expiresAt
+	}
+	url.ShortCode = string(d.bytes(d.uvarint()))
+	url.OriginalURL = string(d.rest())
+
+	if d.err {
+		return nil, fmt.Errorf("%w: truncated", domain.ErrInvalidCacheEntry)
+	}
+	return url, nil
+}
+
+// decoder reads fields in order, and records rather than returns running out
+// of data, which is checked once at the end.
+type decoder struct {
+	data []byte
+	err  bool
+}
+
+func (d *decoder) byte() byte {
+	if len(d.data) == 0 {
+		d.err = true
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = true
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = true
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if uint64(len(d.data)) < n {
+		d.err = true
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) rest() []byte {
+	b := d.data
+	d.data = nil
+	return b
+}
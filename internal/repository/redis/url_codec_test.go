@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadTestURL is shaped like a hot link from tests/load/generate-data, as it
+// is cached after a redirect loads it from the database.
+func loadTestURL(i int) *domain.URL {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	return &domain.URL{
+		ID:          int64(i),
+		ShortCode:   fmt.Sprintf("hot_%06d", i),
+		OriginalURL: fmt.Sprintf("https://youtube.com/watch?v=%06d", i),
+		ClickCount:  int64(i * 37),
+		CreatedAt:   created,
+		UpdatedAt:   created,
+		IsActive:    true,
+		Version:     1,
+	}
+}
+
+func TestDecodeURL_RoundTrip(t *testing.T) {
+	url := loadTestURL(42)
+	expires := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	url.ExpiresAt = &expires
+	url.Version = 3
+
+	result, err := decodeURL(encodeURL(url))
+	require.NoError(t, err)
+
+	assert.Equal(t, url.ID, result.ID)
+	assert.Equal(t, url.ShortCode, result.ShortCode)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+	assert.Equal(t, url.Version, result.Version)
+	assert.True(t, result.IsActive)
+	assert.Equal(t, url.CreatedAt.Truncate(time.Microsecond), result.CreatedAt)
+	require.NotNil(t, result.ExpiresAt)
+	assert.Equal(t, expires, *result.ExpiresAt)
+	assert.Zero(t, result.ClickCount)
+}
+
+func TestDecodeURL_NoExpiryInactive(t *testing.T) {
+	url := loadTestURL(1)
+	url.IsActive = false
+
+	result, err := decodeURL(encodeURL(url))
+	require.NoError(t, err)
+
+	assert.False(t, result.IsActive)
+	assert.Nil(t, result.ExpiresAt)
+}
+
+func TestDecodeURL_LegacyJSON(t *testing.T) {
+	url := loadTestURL(7)
+	data, err := json.Marshal(url)
+	require.NoError(t, err)
+
+	result, err := decodeURL(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, url.ID, result.ID)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+}
+
+func TestDecodeURL_Invalid(t *testing.T) {
+	encoded := encodeURL(loadTestURL(1))
+
+	tests := map[string][]byte{
+		"empty":            nil,
+		"unknown encoding": append([]byte{99}, encoded[1:]...),
+		"truncated":        encoded[:5],
+		"not JSON":         []byte("{not json"),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeURL(data)
+			assert.ErrorIs(t, err, domain.ErrInvalidCacheEntry)
+		})
+	}
+}
+
+func BenchmarkEncodeURL(b *testing.B) {
+	url := loadTestURL(123456)
+
+	b.Run("json", func(b *testing.B) {
+		data, _ := json.Marshal(url)
+		b.ReportMetric(float64(len(data)), "bytes/entry")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = json.Marshal(url)
+		}
+	})
+	b.Run("binary", func(b *testing.B) {
+		b.ReportMetric(float64(len(encodeURL(url))), "bytes/entry")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			encodeURL(url)
+		}
+	})
+}
+
+func BenchmarkDecodeURL(b *testing.B) {
+	url := loadTestURL(123456)
+
+	b.Run("json", func(b *testing.B) {
+		data, _ := json.Marshal(url)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var decoded domain.URL
+			_ = json.Unmarshal(data, &decoded)
+		}
+	})
+	b.Run("binary", func(b *testing.B) {
+		data := encodeURL(url)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = decodeURL(data)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// sketchTTL keeps daily sketches for the week-long ranges counted from
+	// Redis. The all-time sketch never expires, since every count of it would
+	// otherwise depend on the last flush having finished before it did.
+	sketchTTL      = 8 * 24 * time.Hour
+	dirtySketchKey = "hll:dirty"
+)
+
+type VisitorSketchCache struct {
+	client redis.UniversalClient
+}
+
+func NewVisitorSketchCache(client redis.UniversalClient) *VisitorSketchCache {
+	return &VisitorSketchCache{client: client}
+}
+
+// sketchKey keeps every sketch of a link in the same hash slot so that
+// PFCOUNT and PFMERGE over several days stay single-slot operations.
+func sketchKey(urlID int64, day string) string {
+	if day == domain.AllTimeSketch {
+		return fmt.Sprintf("hll:{url:%d}", urlID)
+	}
+	return fmt.Sprintf("hll:{url:%d}:%s", urlID, day)
+}
+
+func dirtyMember(urlID int64, day string) string {
+	return fmt.Sprintf("%d:%s", urlID, day)
+}
+
+func (r *VisitorSketchCache) Add(ctx context.Context, urlID int64, day, visitor string) error {
+	totalKey := sketchKey(urlID, domain.AllTimeSketch)
+	dayKey := sketchKey(urlID, day)
+
+	pipe := r.client.Pipeline()
+	pipe.PFAdd(ctx, totalKey, visitor)
+	pipe.PFAdd(ctx, dayKey, visitor)
+	expireSketch(ctx, pipe, totalKey, domain.AllTimeSketch)
+	expireSketch(ctx, pipe, dayKey, day)
+	pipe.SAdd(ctx, dirtySketchKey, dirtyMember(urlID, domain.AllTimeSketch), dirtyMember(urlID, day))
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *VisitorSketchCache) Count(ctx context.Context, urlID int64, days []string) (int64, error) {
+	if len(days) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(days))
+	for i, day := range days {
+		keys[i] = sketchKey(urlID, day)
+	}
+
+	return r.client.PFCount(ctx, keys...).Result()
+}
+
+func (r *VisitorSketchCache) CountEach(ctx context.Context, urlID int64, days []string) ([]int64, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(days))
+	for i, day := range days {
+		cmds[i] = pipe.PFCount(ctx, sketchKey(urlID, day))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(days))
+	for i, cmd := range cmds {
+		counts[i] = cmd.Val()
+	}
+
+	return counts, nil
+}
+
+func (r *VisitorSketchCache) Missing(ctx context.Context, urlID int64, days []string) ([]string, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(days))
+	for i, day := range days {
+		cmds[i] = pipe.Exists(ctx, sketchKey(urlID, day))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for i, cmd := range cmds {
+		if cmd.Val() == 0 {
+			missing = append(missing, days[i])
+		}
+	}
+
+	return missing, nil
+}
+
+func (r *VisitorSketchCache) Get(ctx context.Context, urlID int64, day string) ([]byte, error) {
+	data, err := r.client.Get(ctx, sketchKey(urlID, day)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// Merge folds a persisted sketch into the cached one. HyperLogLog merges are
+// idempotent, so merging the same sketch twice never inflates the count.
+func (r *VisitorSketchCache) Merge(ctx context.Context, sketch *domain.VisitorSketch) error {
+	key := sketchKey(sketch.URLID, sketch.Day)
+	tmpKey := key + ":merge"
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, tmpKey, sketch.Data, time.Minute)
+	pipe.PFMerge(ctx, key, key, tmpKey)
+	pipe.Del(ctx, tmpKey)
+	expireSketch(ctx, pipe, key, sketch.Day)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// expireSketch sets the expiry of a daily sketch, and clears any left on the
+// all-time sketch by earlier versions.
+func expireSketch(ctx context.Context, pipe redis.Pipeliner, key, day string) {
+	if day == domain.AllTimeSketch {
+		pipe.Persist(ctx, key)
+		return
+	}
+	pipe.Expire(ctx, key, sketchTTL)
+}
+
+func (r *VisitorSketchCache) MarkDirty(ctx context.Context, urlID int64, day string) error {
+	return r.client.SAdd(ctx, dirtySketchKey, dirtyMember(urlID, day)).Err()
+}
+
+func (r *VisitorSketchCache) PopDirty(ctx context.Context, count int64) ([]domain.VisitorSketch, error) {
+	members, err := r.client.SPopN(ctx, dirtySketchKey, count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sketches := make([]domain.VisitorSketch, 0, len(members))
+	for _, member := range members {
+		id, day, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+		urlID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		sketches = append(sketches, domain.VisitorSketch{URLID: urlID, Day: day})
+	}
+
+	return sketches, nil
+}
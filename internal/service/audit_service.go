@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gamassss/url-shortener/internal/audit"
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+)
+
+type AuditRepository interface {
+	Record(ctx context.Context, event *domain.AuditEvent) error
+	List(ctx context.Context, filter *domain.AuditFilter, page, pageSize int) (*domain.AuditLog, error)
+}
+
+type AuditSink interface {
+	Write(event *domain.AuditEvent) error
+}
+
+// Auditor records administrative actions. before and after are the target's
+// state on either side of the action, and nil when it didn't exist.
+type Auditor interface {
+	Record(ctx context.Context, action, target string, before, after any)
+}
+
+type AuditService struct {
+	repo AuditRepository
+	sink AuditSink
+}
+
+// NewAuditService creates the service. sink may be nil, in which case events
+// are only stored in the database.
+func NewAuditService(repo AuditRepository, sink AuditSink) *AuditService {
+	return &AuditService{repo: repo, sink: sink}
+}
+
+// Record stores an audit event for the actor and request in ctx. The action
+// has already happened by the time it is audited, so failures are logged
+// rather than returned.
+func (s *AuditService) Record(ctx context.Context, action, target string, before, after any) {
+	log := logger.FromContext(ctx)
+	actor := audit.ActorFromContext(ctx)
+
+	event := &domain.AuditEvent{
+		Actor:     actor.ID,
+		Action:    action,
+		Target:    target,
+		ClientIP:  actor.IP,
+		RequestID: logger.RequestIDFromContext(ctx),
+	}
+
+	var err error
+	if event.Before, err = marshalPayload(before); err != nil {
+		log.Error("Failed to encode audit payload", "action", action, "target", target, "error", err)
+		return
+	}
+	if event.After, err = marshalPayload(after); err != nil {
+		log.Error("Failed to encode audit payload", "action", action, "target", target, "error", err)
+		return
+	}
+
+	if err := s.repo.Record(context.WithoutCancel(ctx), event); err != nil {
+		log.Error("Failed to record audit event", "action", action, "target", target, "error", err)
+	}
+
+	if s.sink != nil {
+		if err := s.sink.Write(event); err != nil {
+			log.Error("Failed to write audit event to sink", "action", action, "target", target, "error", err)
+		}
+	}
+}
+
+func (s *AuditService) ListEvents(ctx context.Context, filter *domain.AuditFilter, page, pageSize int) (*domain.AuditLog, error) {
+	events, err := s.repo.List(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+func marshalPayload(v any) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return data, nil
+}
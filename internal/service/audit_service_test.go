@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gamassss/url-shortener/internal/audit"
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuditRecord_CapturesActorAndRequest(t *testing.T) {
+	mockRepo := new(mocks.MockAuditRepository)
+	mockSink := new(mocks.MockAuditSink)
+	service := NewAuditService(mockRepo, mockSink)
+
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+	ctx = audit.WithActor(ctx, audit.Actor{ID: "alice", IP: "203.0.113.10"})
+
+	after := &domain.URL{ShortCode: "abc1234", OriginalURL: "https://example.com"}
+
+	var recorded *domain.AuditEvent
+	mockRepo.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*domain.AuditEvent)
+	}).Return(nil).Once()
+	mockSink.On("Write", mock.Anything).Return(nil).Once()
+
+	service.Record(ctx, domain.AuditURLCreate, "abc1234", (*domain.URL)(nil), after)
+
+	if assert.NotNil(t, recorded) {
+		assert.Equal(t, "alice", recorded.Actor)
+		assert.Equal(t, "203.0.113.10", recorded.ClientIP)
+		assert.Equal(t, "req-1", recorded.RequestID)
+		assert.Equal(t, domain.AuditURLCreate, recorded.Action)
+		assert.Equal(t, "abc1234", recorded.Target)
+		assert.Nil(t, recorded.Before, "a missing before state should be left out")
+		assert.Contains(t, string(recorded.After), `"original_url":"https://example.com"`)
+	}
+	mockSink.AssertCalled(t, "Write", recorded)
+}
+
+func TestAuditRecord_RepositoryErrorStillWritesSink(t *testing.T) {
+	mockRepo := new(mocks.MockAuditRepository)
+	mockSink := new(mocks.MockAuditSink)
+	service := NewAuditService(mockRepo, mockSink)
+	ctx := context.Background()
+
+	mockRepo.On("Record", mock.Anything, mock.Anything).Return(errors.New("db down")).Once()
+	mockSink.On("Write", mock.MatchedBy(func(event *domain.AuditEvent) bool {
+		return event.Action == domain.AuditTagDelete && event.Target == "promo"
+	})).Return(nil).Once()
+
+	service.Record(ctx, domain.AuditTagDelete, "promo", map[string]string{"name": "promo"}, nil)
+
+	mockSink.AssertExpectations(t)
+}
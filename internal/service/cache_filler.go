@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// CacheFiller writes to the Redis cache off the request path with a fixed
+// number of workers, so a burst of cache misses can't pile up goroutines.
+type CacheFiller struct {
+	jobs chan func(ctx context.Context)
+}
+
+func NewCacheFiller(queueSize int) *CacheFiller {
+	return &CacheFiller{jobs: make(chan func(ctx context.Context), queueSize)}
+}
+
+// Enqueue schedules job without blocking, and reports false when the queue is
+// full and job was dropped.
+func (f *CacheFiller) Enqueue(job func(ctx context.Context)) bool {
+	select {
+	case f.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run runs queued jobs with the given number of workers until ctx is
+// cancelled.
+func (f *CacheFiller) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-f.jobs:
+					job(ctx)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheFiller_DropsWhenFull(t *testing.T) {
+	filler := NewCacheFiller(1)
+
+	assert.True(t, filler.Enqueue(func(ctx context.Context) {}))
+	assert.False(t, filler.Enqueue(func(ctx context.Context) {}))
+}
+
+func TestCacheFiller_Run(t *testing.T) {
+	filler := NewCacheFiller(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		filler.Enqueue(func(ctx context.Context) { done <- struct{}{} })
+	}
+
+	go filler.Run(ctx, 2)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("queued job did not run")
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+	"golang.org/x/sync/errgroup"
+)
+
+type HotURLSource interface {
+	ListHot(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error)
+}
+
+type CacheLoader interface {
+	SetURLs(ctx context.Context, urls []*domain.URL, ttl func(url *domain.URL) time.Duration) error
+	Available() bool
+}
+
+// WarmupPlan bounds a cache warm-up. TopN links with the most clicks in the
+// last Window are written BatchSize to a pipeline, Concurrency pipelines at a
+// time, for at most Budget.
+type WarmupPlan struct {
+	TopN        int
+	Window      time.Duration
+	BatchSize   int
+	Concurrency int
+	Budget      time.Duration
+}
+
+// CacheWarmer preloads the busiest links into Redis, so the first minutes
+// after a deploy or a Redis flush don't all go to the database.
+type CacheWarmer struct {
+	source HotURLSource
+	cache  CacheLoader
+	plan   WarmupPlan
+	ready  atomic.Bool
+}
+
+func NewCacheWarmer(source HotURLSource, cache CacheLoader, plan WarmupPlan) *CacheWarmer {
+	plan.BatchSize = max(plan.BatchSize, 1)
+	plan.Concurrency = max(plan.Concurrency, 1)
+	return &CacheWarmer{source: source, cache: cache, plan: plan}
+}
+
+// Ready reports whether the warm-up at startup has finished, whether or not
+// it warmed every link.
+func (w *CacheWarmer) Ready() bool {
+	return w.ready.Load()
+}
+
+// Run warms the cache at startup and then every interval until ctx is
+// cancelled. An interval of 0 warms only at startup.
+func (w *CacheWarmer) Run(ctx context.Context, interval time.Duration) {
+	w.warmAndLog(ctx)
+	w.ready.Store(true)
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.warmAndLog(ctx)
+		}
+	}
+}
+
+func (w *CacheWarmer) warmAndLog(ctx context.Context) {
+	log := logger.Get()
+	start := time.Now()
+
+	warmed, err := w.Warm(ctx)
+	if errors.Is(err, errCacheUnavailable) {
+		log.Info("Skipped cache warm-up while Redis is unavailable")
+		return
+	}
+	if err != nil {
+		log.Warn("Cache warm-up incomplete", "links", warmed, "duration", time.Since(start), "error", err)
+		return
+	}
+	log.Info("Warmed cache", "links", warmed, "duration", time.Since(start))
+}
+
+// Warm writes the busiest links to the cache and returns how many were
+// written before it finished, failed or ran out of budget. Nothing is read
+// from the database while the cache is unavailable.
+func (w *CacheWarmer) Warm(ctx context.Context) (int, error) {
+	if !w.cache.Available() {
+		return 0, errCacheUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.plan.Budget)
+	defer cancel()
+
+	urls, err := w.source.ListHot(ctx, time.Now().Add(-w.plan.Window), w.plan.TopN)
+	if err != nil {
+		return 0, err
+	}
+
+	var warmed atomic.Int64
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(w.plan.Concurrency)
+
+	for start := 0; start < len(urls) && gctx.Err() == nil; start += w.plan.BatchSize {
+		batch := urls[start:min(start+w.plan.BatchSize, len(urls))]
+		g.Go(func() error {
+			if err := w.cache.SetURLs(gctx, batch, cacheTTL); err != nil {
+				return err
+			}
+			warmed.Add(int64(len(batch)))
+			return nil
+		})
+	}
+
+	err = g.Wait()
+	if err == nil {
+		// running out of budget between batches stops without an error
+		err = ctx.Err()
+	}
+	return int(warmed.Load()), err
+}
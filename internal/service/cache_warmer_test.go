@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/breaker"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHotURLSource struct {
+	urls  []*domain.URL
+	since time.Time
+	limit int
+}
+
+func (s *fakeHotURLSource) ListHot(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	s.since, s.limit = since, limit
+	return s.urls[:min(limit, len(s.urls))], nil
+}
+
+type fakeCacheLoader struct {
+	mu          sync.Mutex
+	batches     [][]*domain.URL
+	err         error
+	delay       time.Duration
+	unavailable bool
+}
+
+func (c *fakeCacheLoader) Available() bool {
+	return !c.unavailable
+}
+
+func (c *fakeCacheLoader) SetURLs(ctx context.Context, urls []*domain.URL, ttl func(url *domain.URL) time.Duration) error {
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.batches = append(c.batches, urls)
+	return nil
+}
+
+func hotURLs(n int) []*domain.URL {
+	urls := make([]*domain.URL, n)
+	for i := range urls {
+		urls[i] = &domain.URL{ID: int64(i + 1), ShortCode: fmt.Sprintf("hot%d", i), OriginalURL: "https://example.com", IsActive: true}
+	}
+	return urls
+}
+
+func TestCacheWarmer_WarmsInBatches(t *testing.T) {
+	source := &fakeHotURLSource{urls: hotURLs(25)}
+	cache := &fakeCacheLoader{}
+	warmer := NewCacheWarmer(source, cache, WarmupPlan{
+		TopN:        20,
+		Window:      24 * time.Hour,
+		BatchSize:   8,
+		Concurrency: 2,
+		Budget:      time.Second,
+	})
+
+	warmed, err := warmer.Warm(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 20, warmed)
+	assert.Equal(t, 20, source.limit)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), source.since, time.Minute)
+
+	sizes := make([]int, 0, len(cache.batches))
+	for _, batch := range cache.batches {
+		sizes = append(sizes, len(batch))
+	}
+	assert.ElementsMatch(t, []int{8, 8, 4}, sizes)
+}
+
+func TestCacheWarmer_StopsAtBudget(t *testing.T) {
+	cache := &fakeCacheLoader{delay: 30 * time.Millisecond}
+	warmer := NewCacheWarmer(&fakeHotURLSource{urls: hotURLs(100)}, cache, WarmupPlan{
+		TopN:        100,
+		BatchSize:   10,
+		Concurrency: 1,
+		Budget:      50 * time.Millisecond,
+	})
+
+	start := time.Now()
+	warmed, err := warmer.Warm(context.Background())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, warmed, 100)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestCacheWarmer_CacheError(t *testing.T) {
+	cache := &fakeCacheLoader{err: errors.New("connection refused")}
+	warmer := NewCacheWarmer(&fakeHotURLSource{urls: hotURLs(10)}, cache, WarmupPlan{
+		TopN:   10,
+		Budget: time.Second,
+	})
+
+	warmed, err := warmer.Warm(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, warmed)
+}
+
+func TestCacheWarmer_ReadyAfterFirstWarmup(t *testing.T) {
+	warmer := NewCacheWarmer(&fakeHotURLSource{urls: hotURLs(3)}, &fakeCacheLoader{}, WarmupPlan{
+		TopN:   3,
+		Budget: time.Second,
+	})
+	assert.False(t, warmer.Ready())
+
+	warmer.Run(context.Background(), 0)
+
+	assert.True(t, warmer.Ready())
+}
+
+func TestCacheWarmer_SkipsWhileCacheUnavailable(t *testing.T) {
+	source := &fakeHotURLSource{urls: hotURLs(10)}
+	warmer := NewCacheWarmer(source, &fakeCacheLoader{unavailable: true}, WarmupPlan{
+		TopN:   10,
+		Budget: time.Second,
+	})
+
+	warmed, err := warmer.Warm(context.Background())
+
+	assert.ErrorIs(t, err, errCacheUnavailable)
+	assert.Equal(t, 0, warmed)
+	assert.Zero(t, source.limit, "hot links should not be read")
+}
+
+func TestCacheWarmer_ThroughOpenBreaker(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, time.Minute, nil), 50*time.Millisecond)
+	warmer := NewCacheWarmer(&fakeHotURLSource{urls: hotURLs(10)}, cache, WarmupPlan{
+		TopN:      10,
+		BatchSize: 5,
+		Budget:    time.Second,
+	})
+
+	mockCacheRepo.On("SetURLs", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+
+	_, err := warmer.Warm(context.Background())
+	assert.Error(t, err)
+	assert.False(t, cache.Available())
+
+	warmed, err := warmer.Warm(context.Background())
+	assert.ErrorIs(t, err, errCacheUnavailable)
+	assert.Equal(t, 0, warmed)
+	mockCacheRepo.AssertNumberOfCalls(t, "SetURLs", 1)
+}
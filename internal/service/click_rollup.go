@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/logger"
+)
+
+type ClickDeltaFolder interface {
+	FoldClickDeltas(ctx context.Context, limit int) (int64, error)
+}
+
+// ClickRollup keeps the hourly click rollup up to date. Clicks are appended
+// to a delta table rather than added to their hour's row, so clicks on a busy
+// link don't all wait on one row lock, and are folded into the rollup here.
+type ClickRollup struct {
+	folder    ClickDeltaFolder
+	batchSize int
+}
+
+func NewClickRollup(folder ClickDeltaFolder, batchSize int) *ClickRollup {
+	return &ClickRollup{folder: folder, batchSize: max(batchSize, 1)}
+}
+
+// Fold folds batches of clicks until less than a full batch was left, and
+// returns how many it folded.
+func (r *ClickRollup) Fold(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		folded, err := r.folder.FoldClickDeltas(ctx, r.batchSize)
+		total += folded
+		if err != nil || folded < int64(r.batchSize) {
+			return total, err
+		}
+	}
+}
+
+// Run folds clicks every interval until ctx is cancelled.
+func (r *ClickRollup) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log := logger.Get()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Fold(ctx); err != nil {
+				log.Error("Failed to fold clicks into the rollup", "error", err)
+			}
+		}
+	}
+}
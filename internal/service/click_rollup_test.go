@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClickDeltaFolder struct {
+	pending int64
+	calls   int
+	err     error
+}
+
+func (f *fakeClickDeltaFolder) FoldClickDeltas(ctx context.Context, limit int) (int64, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, f.err
+	}
+	folded := min(f.pending, int64(limit))
+	f.pending -= folded
+	return folded, nil
+}
+
+func TestClickRollup_FoldsUntilBatchIsShort(t *testing.T) {
+	folder := &fakeClickDeltaFolder{pending: 250}
+	rollup := NewClickRollup(folder, 100)
+
+	folded, err := rollup.Fold(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), folded)
+	assert.Equal(t, 3, folder.calls)
+}
+
+func TestClickRollup_FullLastBatch(t *testing.T) {
+	folder := &fakeClickDeltaFolder{pending: 200}
+	rollup := NewClickRollup(folder, 100)
+
+	folded, err := rollup.Fold(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), folded)
+	// a full batch may have more behind it, so one more fold finds nothing
+	assert.Equal(t, 3, folder.calls)
+}
+
+func TestClickRollup_Error(t *testing.T) {
+	folder := &fakeClickDeltaFolder{pending: 50, err: errors.New("database down")}
+	rollup := NewClickRollup(folder, 100)
+
+	_, err := rollup.Fold(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, folder.calls)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+	"github.com/gamassss/url-shortener/pkg/metadata"
+)
+
+type PageFetcher interface {
+	Fetch(ctx context.Context, pageURL string) (*metadata.Page, error)
+}
+
+type PreviewRepository interface {
+	SavePreview(ctx context.Context, urlID int64, destination string, preview *domain.LinkPreview) error
+}
+
+type previewJob struct {
+	urlID     int64
+	shortCode string
+	url       string
+}
+
+// PreviewService fetches the title, Open Graph tags and favicon of new links'
+// destination pages in the background, so creating a link never waits on a
+// third-party site.
+type PreviewService struct {
+	fetcher PageFetcher
+	repo    PreviewRepository
+	jobs    chan previewJob
+}
+
+func NewPreviewService(fetcher PageFetcher, repo PreviewRepository, queueSize int) *PreviewService {
+	return &PreviewService{
+		fetcher: fetcher,
+		repo:    repo,
+		jobs:    make(chan previewJob, queueSize),
+	}
+}
+
+// Enqueue schedules a preview fetch for url without blocking. When the queue
+// is full the link is skipped and keeps no preview.
+func (s *PreviewService) Enqueue(ctx context.Context, url *domain.URL) {
+	select {
+	case s.jobs <- previewJob{urlID: url.ID, shortCode: url.ShortCode, url: url.OriginalURL}:
+	default:
+		logger.FromContext(ctx).Warn("Preview queue full, skipping link", "short_code", url.ShortCode)
+	}
+}
+
+// Run fetches queued previews with the given number of workers until ctx is
+// cancelled.
+func (s *PreviewService) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.jobs:
+					s.fetch(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *PreviewService) fetch(ctx context.Context, job previewJob) {
+	log := logger.Get()
+
+	page, err := s.fetcher.Fetch(ctx, job.url)
+	if err != nil {
+		log.Warn("Failed to fetch link preview", "short_code", job.shortCode, "error", err)
+		return
+	}
+
+	preview := &domain.LinkPreview{
+		PageTitle:     page.Title,
+		OGTitle:       page.OGTitle,
+		OGDescription: page.OGDescription,
+		OGImage:       page.OGImage,
+		FaviconURL:    page.FaviconURL,
+		FetchedAt:     time.Now(),
+	}
+	if err := s.repo.SavePreview(ctx, job.urlID, job.url, preview); err != nil {
+		log.Error("Failed to save link preview", "short_code", job.shortCode, "error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/metadata"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPreviewService_FetchesAndSaves(t *testing.T) {
+	mockFetcher := new(mocks.MockPageFetcher)
+	mockRepo := new(mocks.MockPreviewRepository)
+	service := NewPreviewService(mockFetcher, mockRepo, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	saved := make(chan *domain.LinkPreview, 1)
+	mockFetcher.On("Fetch", mock.Anything, "https://example.com/sale").
+		Return(&metadata.Page{Title: "Sale", OGImage: "https://example.com/sale.png"}, nil).Once()
+	mockRepo.On("SavePreview", mock.Anything, int64(7), "https://example.com/sale", mock.Anything).
+		Run(func(args mock.Arguments) { saved <- args.Get(3).(*domain.LinkPreview) }).
+		Return(nil).Once()
+
+	go service.Run(ctx, 2)
+	service.Enqueue(ctx, &domain.URL{ID: 7, ShortCode: "abc123", OriginalURL: "https://example.com/sale"})
+
+	select {
+	case preview := <-saved:
+		assert.Equal(t, "Sale", preview.PageTitle)
+		assert.Equal(t, "https://example.com/sale.png", preview.OGImage)
+		assert.False(t, preview.FetchedAt.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("preview was not saved")
+	}
+}
+
+func TestPreviewService_SkipsFailedFetches(t *testing.T) {
+	mockFetcher := new(mocks.MockPageFetcher)
+	mockRepo := new(mocks.MockPreviewRepository)
+	service := NewPreviewService(mockFetcher, mockRepo, 10)
+
+	mockFetcher.On("Fetch", mock.Anything, "https://example.com").Return(nil, errors.New("timeout")).Once()
+
+	service.fetch(context.Background(), previewJob{urlID: 1, url: "https://example.com"})
+
+	mockRepo.AssertNotCalled(t, "SavePreview")
+}
+
+func TestPreviewService_EnqueueNeverBlocks(t *testing.T) {
+	service := NewPreviewService(nil, nil, 1)
+	url := &domain.URL{ID: 1, OriginalURL: "https://example.com"}
+
+	done := make(chan struct{})
+	go func() {
+		service.Enqueue(context.Background(), url)
+		service.Enqueue(context.Background(), url)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Enqueue blocked on a full queue")
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/logger"
+)
+
+// ReplicaLagCache deletes a changed link from the cache a second time once
+// read replicas have caught up. A cache miss in between may have loaded the
+// old link from a lagging replica and cached it, which would otherwise be
+// served until it expired.
+type ReplicaLagCache struct {
+	CacheRepository
+	lag time.Duration
+}
+
+func NewReplicaLagCache(cache CacheRepository, lag time.Duration) *ReplicaLagCache {
+	return &ReplicaLagCache{CacheRepository: cache, lag: lag}
+}
+
+func (c *ReplicaLagCache) DeleteURL(ctx context.Context, shortCode string) error {
+	time.AfterFunc(c.lag, func() {
+		if err := c.CacheRepository.DeleteURL(context.Background(), shortCode); err != nil {
+			logger.Get().Warn("Failed to invalidate cache after replica lag", "short_code", shortCode, "error", err)
+		}
+	})
+	return c.CacheRepository.DeleteURL(ctx, shortCode)
+}
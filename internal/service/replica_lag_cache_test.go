@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReplicaLagCache_DeletesAgainAfterLag(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewReplicaLagCache(mockCacheRepo, 20*time.Millisecond)
+
+	deleted := make(chan struct{}, 2)
+	mockCacheRepo.On("DeleteURL", mock.Anything, "abc123").Return(nil).
+		Run(func(mock.Arguments) { deleted <- struct{}{} })
+
+	assert.NoError(t, cache.DeleteURL(context.Background(), "abc123"))
+	<-deleted
+
+	select {
+	case <-deleted:
+	case <-time.After(time.Second):
+		t.Fatal("link wasn't deleted again after the replica lag")
+	}
+	mockCacheRepo.AssertNumberOfCalls(t, "DeleteURL", 2)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/breaker"
+)
+
+var errCacheUnavailable = errors.New("cache unavailable")
+
+// setURLsPerTimeout is how many links a pipeline may write per timeout, so
+// warming a batch isn't held to the limit of a single lookup.
+const setURLsPerTimeout = 100
+
+type CircuitBreaker interface {
+	Allow() bool
+	Success()
+	Failure()
+	State() breaker.State
+}
+
+// ResilientCache guards a CacheRepository with a circuit breaker and a timeout
+// per call, so a slow or unreachable Redis costs redirects at most one timeout
+// before they go straight to the database. The breaker lets a probe through
+// after its cooldown, and caching resumes once one succeeds.
+//
+// Invalidations that fail are remembered and replayed before Redis is read or
+// written again, so a link changed while Redis was unreachable isn't served
+// from its old entry once it is back.
+type ResilientCache struct {
+	cache   CacheRepository
+	breaker CircuitBreaker
+	timeout time.Duration
+
+	flushing   sync.Mutex
+	mu         sync.Mutex
+	pending    map[string]struct{}
+	hasPending atomic.Bool
+}
+
+func NewResilientCache(cache CacheRepository, breaker CircuitBreaker, timeout time.Duration) *ResilientCache {
+	return &ResilientCache{cache: cache, breaker: breaker, timeout: timeout, pending: make(map[string]struct{})}
+}
+
+// Available reports whether Redis is currently being used.
+func (c *ResilientCache) Available() bool {
+	return c.breaker.State() != breaker.Open
+}
+
+func (c *ResilientCache) GetURL(ctx context.Context, shortCode string) (*domain.URL, time.Duration, error) {
+	if !c.breaker.Allow() {
+		return nil, 0, errCacheUnavailable
+	}
+	if err := c.flush(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	url, ttl, err := c.cache.GetURL(ctx, shortCode)
+	c.record(err)
+	return url, ttl, err
+}
+
+func (c *ResilientCache) SetURL(ctx context.Context, url *domain.URL, ttl time.Duration) error {
+	return c.guard(ctx, func(ctx context.Context) error {
+		return c.cache.SetURL(ctx, url, ttl)
+	})
+}
+
+func (c *ResilientCache) SetNotFound(ctx context.Context, shortCode string, ttl time.Duration) error {
+	return c.guard(ctx, func(ctx context.Context) error {
+		return c.cache.SetNotFound(ctx, shortCode, ttl)
+	})
+}
+
+// SetURLs caches many links in one pipeline, for the cache warmer.
+func (c *ResilientCache) SetURLs(ctx context.Context, urls []*domain.URL, ttl func(url *domain.URL) time.Duration) error {
+	if !c.breaker.Allow() {
+		return errCacheUnavailable
+	}
+	if err := c.flush(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout*time.Duration(1+len(urls)/setURLsPerTimeout))
+	defer cancel()
+
+	err := c.cache.SetURLs(ctx, urls, ttl)
+	c.record(err)
+	return err
+}
+
+// DeleteURL is tried even while the breaker is open, since a skipped
+// invalidation would leave a stale link cached once Redis is back. One that
+// fails is replayed before Redis is used again.
+func (c *ResilientCache) DeleteURL(ctx context.Context, shortCode string) error {
+	err := c.try(ctx, func(ctx context.Context) error {
+		return c.cache.DeleteURL(ctx, shortCode)
+	})
+
+	c.mu.Lock()
+	if err != nil {
+		c.pending[shortCode] = struct{}{}
+	} else {
+		delete(c.pending, shortCode)
+	}
+	c.hasPending.Store(len(c.pending) > 0)
+	c.mu.Unlock()
+	return err
+}
+
+// AddURL is tried even while the breaker is open, since it also announces the
+// new code to the other replicas' short code filters.
+func (c *ResilientCache) AddURL(ctx context.Context, url *domain.URL, ttl time.Duration) error {
+	return c.try(ctx, func(ctx context.Context) error {
+		return c.cache.AddURL(ctx, url, ttl)
+	})
+}
+
+func (c *ResilientCache) guard(ctx context.Context, call func(ctx context.Context) error) error {
+	if !c.breaker.Allow() {
+		return errCacheUnavailable
+	}
+	if err := c.flush(ctx); err != nil {
+		return err
+	}
+	return c.try(ctx, call)
+}
+
+// flush replays the invalidations that failed. Until they have all gone
+// through, Redis may hold links that have since changed, so nothing else is
+// read from or written to it.
+func (c *ResilientCache) flush(ctx context.Context) error {
+	if !c.hasPending.Load() {
+		return nil
+	}
+
+	// one caller replays them while the rest wait for it
+	c.flushing.Lock()
+	defer c.flushing.Unlock()
+
+	c.mu.Lock()
+	codes := make([]string, 0, len(c.pending))
+	for code := range c.pending {
+		codes = append(codes, code)
+	}
+	c.mu.Unlock()
+
+	for _, code := range codes {
+		if err := c.DeleteURL(ctx, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ResilientCache) try(ctx context.Context, call func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	err := call(ctx)
+	c.record(err)
+	return err
+}
+
+// record counts err against Redis unless it is an answer rather than a
+// failure. An entry that can't be decoded still came from a healthy Redis.
+func (c *ResilientCache) record(err error) {
+	if err == nil || errors.Is(err, domain.ErrURLNotFound) || errors.Is(err, domain.ErrInvalidCacheEntry) {
+		c.breaker.Success()
+		return
+	}
+	c.breaker.Failure()
+}
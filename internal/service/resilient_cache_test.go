@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/breaker"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResilientCache_OpensAfterFailures(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(2, time.Minute, nil), 50*time.Millisecond)
+	ctx := context.Background()
+
+	mockCacheRepo.On("GetURL", mock.Anything, "abc123").
+		Return(nil, 0, errors.New("i/o timeout")).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, _, err := cache.GetURL(ctx, "abc123")
+		assert.Error(t, err)
+	}
+	assert.False(t, cache.Available())
+
+	_, _, err := cache.GetURL(ctx, "abc123")
+	assert.ErrorIs(t, err, errCacheUnavailable)
+	assert.ErrorIs(t, cache.SetURL(ctx, &domain.URL{ShortCode: "abc123"}, time.Hour), errCacheUnavailable)
+	mockCacheRepo.AssertNumberOfCalls(t, "GetURL", 2)
+	mockCacheRepo.AssertNotCalled(t, "SetURL")
+}
+
+func TestResilientCache_AnswersAreNotFailures(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, time.Minute, nil), 50*time.Millisecond)
+	ctx := context.Background()
+
+	mockCacheRepo.On("GetURL", mock.Anything, "missing").Return(nil, 0, nil).Once()
+	mockCacheRepo.On("GetURL", mock.Anything, "cold_123").Return(nil, 0, domain.ErrURLNotFound).Once()
+	mockCacheRepo.On("GetURL", mock.Anything, "hot_000001").Return(nil, 0, domain.ErrInvalidCacheEntry).Once()
+
+	_, _, err := cache.GetURL(ctx, "missing")
+	assert.NoError(t, err)
+	_, _, err = cache.GetURL(ctx, "cold_123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	_, _, err = cache.GetURL(ctx, "hot_000001")
+	assert.ErrorIs(t, err, domain.ErrInvalidCacheEntry)
+
+	assert.True(t, cache.Available())
+}
+
+func TestResilientCache_TimesOutSlowCalls(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, time.Minute, nil), 10*time.Millisecond)
+
+	mockCacheRepo.On("GetURL", mock.Anything, "abc123").Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, 0, context.DeadlineExceeded).Once()
+
+	start := time.Now()
+	_, _, err := cache.GetURL(context.Background(), "abc123")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, cache.Available())
+}
+
+func TestResilientCache_InvalidatesWhileOpen(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, time.Minute, nil), 50*time.Millisecond)
+	ctx := context.Background()
+
+	mockCacheRepo.On("GetURL", mock.Anything, "abc123").Return(nil, 0, errors.New("connection refused")).Once()
+	mockCacheRepo.On("DeleteURL", mock.Anything, "abc123").Return(nil).Once()
+
+	_, _, _ = cache.GetURL(ctx, "abc123")
+	assert.False(t, cache.Available())
+
+	assert.NoError(t, cache.DeleteURL(ctx, "abc123"))
+
+	mockCacheRepo.AssertExpectations(t)
+	assert.True(t, cache.Available(), "a successful call shows Redis is back")
+}
+
+func TestResilientCache_ReplaysFailedInvalidationsBeforeReading(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	// no cooldown, so every call after a failure is a probe
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, 0, nil), 50*time.Millisecond)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com/new", IsActive: true}
+	mockCacheRepo.On("DeleteURL", mock.Anything, "abc123").Return(errors.New("connection refused")).Twice()
+	mockCacheRepo.On("DeleteURL", mock.Anything, "abc123").Return(nil).Once()
+	mockCacheRepo.On("GetURL", mock.Anything, "abc123").Return(url, time.Hour, nil).Once()
+
+	assert.Error(t, cache.DeleteURL(ctx, "abc123"))
+
+	// the replay fails, so the possibly stale entry is not read
+	_, _, err := cache.GetURL(ctx, "abc123")
+	assert.Error(t, err)
+	mockCacheRepo.AssertNotCalled(t, "GetURL")
+
+	result, _, err := cache.GetURL(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+	mockCacheRepo.AssertExpectations(t)
+
+	// once replayed it is not sent again
+	mockCacheRepo.On("SetURL", mock.Anything, url, time.Hour).Return(nil).Once()
+	assert.NoError(t, cache.SetURL(ctx, url, time.Hour))
+	mockCacheRepo.AssertNumberOfCalls(t, "DeleteURL", 3)
+}
+
+func TestUpdateURL_FailedInvalidationIsNotServedWhenRedisIsBack(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, 0, nil), 50*time.Millisecond)
+	service := NewShortenerService(mockURLRepo, cache, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	destination := "https://example.com/new"
+	req := &domain.UpdateURLRequest{OriginalURL: &destination}
+	updated := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: destination, IsActive: true}
+	mockURLRepo.On("Update", ctx, "abc123", req).Return(updated, nil).Once()
+	mockCacheRepo.On("DeleteURL", mock.Anything, "abc123").Return(errors.New("connection refused")).Once()
+
+	_, err := service.UpdateURL(ctx, "abc123", req)
+	assert.NoError(t, err)
+
+	// Redis is back; the old entry is deleted before anything is read
+	mockCacheRepo.On("DeleteURL", mock.Anything, "abc123").Return(nil).Once()
+	mockCacheRepo.On("GetURL", mock.Anything, "abc123").Return(nil, 0, nil).Once()
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").Return(updated, nil).Once()
+	mockCacheRepo.On("SetURL", mock.Anything, updated, 24*time.Hour).Return(nil).Once()
+
+	result, _, err := service.GetOriginalURL(ctx, "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated.OriginalURL, result.OriginalURL)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestGetOriginalURL_RedisDownFallsBackToDB(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	cache := NewResilientCache(mockCacheRepo, breaker.New(1, time.Minute, nil), 50*time.Millisecond)
+	service := NewShortenerService(mockURLRepo, cache, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	url := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	mockCacheRepo.On("GetURL", mock.Anything, "abc123").Return(nil, 0, errors.New("connection refused")).Once()
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").Return(url, nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		result, tier, err := service.GetOriginalURL(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, url.OriginalURL, result.OriginalURL)
+		assert.Equal(t, domain.CacheTierMiss, tier)
+	}
+
+	mockCacheRepo.AssertNumberOfCalls(t, "GetURL", 1)
+	mockCacheRepo.AssertNotCalled(t, "SetURL")
+	mockURLRepo.AssertExpectations(t)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/logger"
+	"github.com/gamassss/url-shortener/pkg/bloom"
+)
+
+// filterRetryDelay is how long to wait before retrying a failed rebuild
+const filterRetryDelay = 30 * time.Second
+
+type ShortCodeSource interface {
+	CountShortCodes(ctx context.Context) (int64, error)
+	EachShortCode(ctx context.Context, fn func(shortCode string)) error
+}
+
+// ShortCodeFilter is a Bloom filter of every existing short code, so lookups
+// of codes that definitely don't exist can be answered without Redis or
+// Postgres. Until it has been built, and while available reports that new
+// codes may not be reaching it, it lets every code through.
+type ShortCodeFilter struct {
+	source            ShortCodeSource
+	available         func() bool
+	capacity          uint64
+	falsePositiveRate float64
+	rebuilds          chan struct{}
+
+	mu      sync.RWMutex
+	current *bloom.Filter
+	next    *bloom.Filter
+	resets  int
+}
+
+// NewShortCodeFilter creates a filter sized for at least capacity codes, or
+// twice the existing number when that is larger. available may be nil.
+func NewShortCodeFilter(source ShortCodeSource, capacity uint64, falsePositiveRate float64, available func() bool) *ShortCodeFilter {
+	return &ShortCodeFilter{
+		source:            source,
+		available:         available,
+		capacity:          capacity,
+		falsePositiveRate: falsePositiveRate,
+		rebuilds:          make(chan struct{}, 1),
+	}
+}
+
+// MightExist reports whether shortCode may exist. False means it definitely
+// doesn't.
+func (f *ShortCodeFilter) MightExist(shortCode string) bool {
+	// codes created by other replicas are announced over Redis
+	if f.available != nil && !f.available() {
+		return true
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.current == nil || f.current.Test(shortCode)
+}
+
+// Add records a new short code, including in a filter being rebuilt.
+func (f *ShortCodeFilter) Add(shortCode string) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.current != nil {
+		f.current.Add(shortCode)
+	}
+	if f.next != nil {
+		f.next.Add(shortCode)
+	}
+}
+
+// Reset stops trusting the filter and schedules a rebuild. It is called
+// whenever codes created by other replicas may have been missed.
+func (f *ShortCodeFilter) Reset() {
+	f.mu.Lock()
+	f.current = nil
+	f.resets++
+	f.mu.Unlock()
+
+	select {
+	case f.rebuilds <- struct{}{}:
+	default:
+	}
+}
+
+// Run rebuilds the filter whenever it is reset, and every interval in case an
+// announcement was lost without the subscription noticing, until ctx is
+// cancelled. Resets during a rebuild are coalesced into one more rebuild. An
+// interval of 0 only rebuilds on reset.
+func (f *ShortCodeFilter) Run(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	log := logger.Get()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.rebuilds:
+		case <-tick:
+			// the current filter stays trusted until its replacement is built
+		}
+
+		start := time.Now()
+		count, err := f.rebuild(ctx)
+		if err != nil {
+			log.Error("Failed to build short code filter", "error", err)
+			time.AfterFunc(filterRetryDelay, f.Reset)
+			continue
+		}
+		log.Info("Built short code filter", "short_codes", count, "duration", time.Since(start))
+	}
+}
+
+func (f *ShortCodeFilter) rebuild(ctx context.Context) (int64, error) {
+	count, err := f.source.CountShortCodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	next := bloom.New(max(f.capacity, uint64(count)*2), f.falsePositiveRate)
+	f.mu.Lock()
+	f.next = next
+	resets := f.resets
+	f.mu.Unlock()
+
+	err = f.source.EachShortCode(ctx, next.Add)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next = nil
+	if err != nil {
+		return 0, err
+	}
+	// a reset during the rebuild means codes may have been missed; the rebuild
+	// it scheduled will install its filter instead
+	if f.resets == resets {
+		f.current = next
+	}
+	return count, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/breaker"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeShortCodeSource struct {
+	codes []string
+	err   error
+	// during runs while the filter is being built
+	during func()
+}
+
+func (s *fakeShortCodeSource) CountShortCodes(ctx context.Context) (int64, error) {
+	return int64(len(s.codes)), nil
+}
+
+func (s *fakeShortCodeSource) EachShortCode(ctx context.Context, fn func(shortCode string)) error {
+	for _, code := range s.codes {
+		fn(code)
+	}
+	if s.during != nil {
+		s.during()
+	}
+	return s.err
+}
+
+func TestShortCodeFilter_PassesEverythingUntilBuilt(t *testing.T) {
+	filter := NewShortCodeFilter(&fakeShortCodeSource{}, 100, 0.01, nil)
+
+	assert.True(t, filter.MightExist("anything"))
+}
+
+func TestShortCodeFilter_Rebuild(t *testing.T) {
+	source := &fakeShortCodeSource{codes: []string{"abc1234", "mylink"}}
+	filter := NewShortCodeFilter(source, 100, 0.01, nil)
+	source.during = func() { filter.Add("created") }
+
+	_, err := filter.rebuild(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, filter.MightExist("abc1234"))
+	assert.True(t, filter.MightExist("created"), "codes added during a rebuild are kept")
+	assert.False(t, filter.MightExist("cold_123"))
+}
+
+func TestShortCodeFilter_ResetDuringRebuildDiscardsIt(t *testing.T) {
+	source := &fakeShortCodeSource{codes: []string{"abc1234"}}
+	filter := NewShortCodeFilter(source, 100, 0.01, nil)
+	source.during = func() { filter.Reset() }
+
+	_, err := filter.rebuild(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, filter.MightExist("cold_123"), "filter should not be trusted until rebuilt again")
+}
+
+func TestShortCodeFilter_FailedRebuildKeepsPassingThrough(t *testing.T) {
+	source := &fakeShortCodeSource{codes: []string{"abc1234"}, err: errors.New("db down")}
+	filter := NewShortCodeFilter(source, 100, 0.01, nil)
+
+	_, err := filter.rebuild(context.Background())
+
+	assert.Error(t, err)
+	assert.True(t, filter.MightExist("cold_123"))
+}
+
+func TestShortCodeFilter_LostAnnouncementRecoveredByRebuild(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockURLRepo := new(mocks.MockURLRepository)
+	database := &fakeShortCodeSource{}
+
+	filterB := NewShortCodeFilter(database, 100, 0.01, nil)
+	_, err := filterB.rebuild(ctx)
+	require.NoError(t, err)
+
+	// replica A creates the link, but the announcement never reaches replica B
+	cacheA := new(mocks.MockCacheRepository)
+	replicaA := NewShortenerService(mockURLRepo, cacheA, nil, nil, nil, ShortenerOptions{})
+	mockURLRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		database.codes = append(database.codes, args.Get(1).(*domain.URL).ShortCode)
+	}).Return(nil).Once()
+	cacheA.On("AddURL", ctx, mock.Anything, mock.Anything).Return(errors.New("publish failed")).Once()
+
+	_, err = replicaA.ShortenURL(ctx, &domain.CreatedURLRequest{OriginalURL: "https://example.com", CustomAlias: "mylink"})
+	require.NoError(t, err)
+	require.False(t, filterB.MightExist("mylink"))
+
+	go filterB.Run(ctx, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return filterB.MightExist("mylink") }, time.Second, 5*time.Millisecond)
+
+	cacheB := new(mocks.MockCacheRepository)
+	replicaB := NewShortenerService(mockURLRepo, cacheB, nil, nil, nil, ShortenerOptions{Codes: filterB})
+	url := &domain.URL{ID: 1, ShortCode: "mylink", OriginalURL: "https://example.com", IsActive: true}
+	cacheB.On("GetURL", ctx, "mylink").Return(nil, 0, nil).Once()
+	mockURLRepo.On("GetByShortCode", mock.Anything, "mylink").Return(url, nil).Once()
+	cacheB.On("SetURL", mock.Anything, url, 24*time.Hour).Return(nil).Once()
+
+	result, _, err := replicaB.GetOriginalURL(ctx, "mylink")
+
+	assert.NoError(t, err)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+}
+
+func TestShortCodeFilter_SkippedWhileRedisIsDown(t *testing.T) {
+	ctx := context.Background()
+	mockURLRepo := new(mocks.MockURLRepository)
+	database := &fakeShortCodeSource{}
+
+	// replica B's Redis breaker is open, so announcements can't reach it
+	cacheB := new(mocks.MockCacheRepository)
+	redisBreaker := breaker.New(1, time.Minute, nil)
+	redisBreaker.Failure()
+	resilientB := NewResilientCache(cacheB, redisBreaker, 50*time.Millisecond)
+	filterB := NewShortCodeFilter(database, 100, 0.01, resilientB.Available)
+	_, err := filterB.rebuild(ctx)
+	require.NoError(t, err)
+	replicaB := NewShortenerService(mockURLRepo, resilientB, nil, nil, nil, ShortenerOptions{Codes: filterB})
+
+	cacheA := new(mocks.MockCacheRepository)
+	replicaA := NewShortenerService(mockURLRepo, cacheA, nil, nil, nil, ShortenerOptions{})
+	mockURLRepo.On("Create", ctx, mock.Anything).Return(nil).Once()
+	cacheA.On("AddURL", ctx, mock.Anything, mock.Anything).Return(errors.New("publish failed")).Once()
+
+	_, err = replicaA.ShortenURL(ctx, &domain.CreatedURLRequest{OriginalURL: "https://example.com", CustomAlias: "mylink"})
+	require.NoError(t, err)
+
+	url := &domain.URL{ID: 1, ShortCode: "mylink", OriginalURL: "https://example.com", IsActive: true}
+	mockURLRepo.On("GetByShortCode", mock.Anything, "mylink").Return(url, nil).Once()
+
+	result, _, err := replicaB.GetOriginalURL(ctx, "mylink")
+
+	assert.NoError(t, err)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+	cacheB.AssertNotCalled(t, "GetURL")
+	assert.Zero(t, replicaB.CacheStats().Filtered)
+}
@@ -4,42 +4,162 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
 	"github.com/gamassss/url-shortener/pkg/generator"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/sync/singleflight"
 )
 
 type URLRepository interface {
 	Create(ctx context.Context, url *domain.URL) error
 	GetByShortCode(ctx context.Context, shortCode string) (*domain.URL, error)
+	GetDetails(ctx context.Context, shortCode string) (*domain.URL, error)
+	List(ctx context.Context, links *domain.LinkSet, page, pageSize int) (*domain.URLList, error)
+	Update(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error)
+	Rollback(ctx context.Context, shortCode string, version int, changedBy string) (*domain.URL, error)
+	ListVersions(ctx context.Context, shortCode string) ([]domain.URLVersion, error)
+}
+
+// LocalCache is the in-process cache in front of CacheRepository.
+type LocalCache interface {
+	Get(shortCode string) (*domain.URL, bool)
+	Set(url *domain.URL)
+	Delete(shortCode string)
+	Stats() (int, int64)
+}
+
+// ShortCodeIndex tracks which short codes exist, so lookups of codes that
+// definitely don't can skip both Redis and the database.
+type ShortCodeIndex interface {
+	MightExist(shortCode string) bool
+	Add(shortCode string)
+}
+
+// CacheFillQueue runs cache writes in the background.
+type CacheFillQueue interface {
+	Enqueue(job func(ctx context.Context)) bool
 }
 
 type CacheRepository interface {
-	GetURL(ctx context.Context, shortCode string) (*domain.URL, error)
+	GetURL(ctx context.Context, shortCode string) (*domain.URL, time.Duration, error)
 	SetURL(ctx context.Context, url *domain.URL, ttl time.Duration) error
+	DeleteURL(ctx context.Context, shortCode string) error
+	AddURL(ctx context.Context, url *domain.URL, ttl time.Duration) error
+	SetNotFound(ctx context.Context, shortCode string, ttl time.Duration) error
+	SetURLs(ctx context.Context, urls []*domain.URL, ttl func(url *domain.URL) time.Duration) error
 }
 
+// negativeCacheTTL is how long a short code that doesn't exist is cached as
+// not found. Creating the link replaces the entry straight away.
+const negativeCacheTTL = time.Minute
+
+// earlyRefreshBeta scales how long before its Redis entry expires a hot link
+// may be refreshed. Above 1 refreshes earlier, below 1 later.
+const earlyRefreshBeta = 1.0
+
 type AnalyticsRepository interface {
-	RecordClick(ctx context.Context, click *domain.ClickRequest) error
-	GetAnalytics(ctx context.Context, urlID int64, days int) (*domain.URLAnalytics, error)
-	GetClickHistory(ctx context.Context, urlID int64, page, pageSize int) (*domain.ClickHistory, error)
+	RecordClick(ctx context.Context, click *domain.ClickRequest) (*domain.ClickEvent, error)
+	GetAnalytics(ctx context.Context, urlID int64, q *domain.AnalyticsQuery) (*domain.URLAnalytics, error)
+	CountClicks(ctx context.Context, urlID int64, from, to time.Time, includeBots bool) (int64, error)
+	GetClickHistory(ctx context.Context, filter *domain.ClickFilter, page, pageSize int) (*domain.ClickHistory, error)
+	GetClickHistoryAfter(ctx context.Context, filter *domain.ClickFilter, pageSize int) (*domain.ClickHistory, error)
+	ExportClicks(ctx context.Context, filter *domain.ClickFilter, fn func(*domain.URLClick) error) error
+	GetOverview(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet) (*domain.AnalyticsOverview, error)
+	CountOverviewClicks(ctx context.Context, timeRange *domain.TimeRange, includeBots bool, links *domain.LinkSet) (int64, error)
+	GetTopLinks(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet, limit int) ([]domain.LinkStats, error)
+}
+
+type VisitorCounter interface {
+	Track(ctx context.Context, urlID int64, visitor string, at time.Time) error
+	CountTotal(ctx context.Context, urlID int64) (int64, error)
+	CountRange(ctx context.Context, urlID int64, from, to time.Time) (int64, error)
+	CountByDay(ctx context.Context, urlID int64, days []string) (map[string]int64, error)
+}
+
+type ClickStream interface {
+	Publish(ctx context.Context, urlID int64, event *domain.ClickEvent) error
+	Subscribe(ctx context.Context, urlID int64) (<-chan *domain.ClickEvent, error)
+}
+
+type PreviewQueue interface {
+	Enqueue(ctx context.Context, url *domain.URL)
+}
+
+// LinkEvents is notified of link activity, for delivery to webhooks.
+type LinkEvents interface {
+	LinkCreated(ctx context.Context, url *domain.URL)
+	LinkClicked(ctx context.Context, urlID int64, shortCode string, totalClicks int64)
 }
 
 type ShortenerService struct {
 	urlRepo       URLRepository
 	cacheRepo     CacheRepository
 	analyticsRepo AnalyticsRepository
+	visitors      VisitorCounter
+	clicks        ClickStream
+	previews      PreviewQueue
+	auditor       Auditor
+	events        LinkEvents
+	local         LocalCache
+	codes         ShortCodeIndex
+	fills         CacheFillQueue
+
+	// loads coalesces concurrent database lookups of the same short code
+	loads singleflight.Group
+	// refreshing holds the short codes with an early refresh queued
+	refreshing sync.Map
+	// loadTime is a moving average of database lookup time, in nanoseconds
+	loadTime atomic.Int64
+
+	l1Hits         atomic.Int64
+	l2Hits         atomic.Int64
+	negativeHits   atomic.Int64
+	filtered       atomic.Int64
+	misses         atomic.Int64
+	earlyRefreshes atomic.Int64
 }
 
-func NewShortenerService(urlRepo URLRepository, cacheRepo CacheRepository, analyticsRepo AnalyticsRepository) *ShortenerService {
+// ShortenerOptions are the service's optional collaborators. Any of them may
+// be left nil.
+type ShortenerOptions struct {
+	// Previews fetches a preview of each new link; without it links get none.
+	Previews PreviewQueue
+	// Auditor records changes to links; without it they aren't audited.
+	Auditor Auditor
+	// Events delivers link activity to webhooks; without it none are sent.
+	Events LinkEvents
+	// Local caches links in process; without it every lookup goes to Redis.
+	Local LocalCache
+	// Codes rules out unknown short codes before Redis; without it only the
+	// negative cache catches them.
+	Codes ShortCodeIndex
+	// Fills caches loaded links in the background; without it a lookup
+	// writes the cache before returning.
+	Fills CacheFillQueue
+}
+
+func NewShortenerService(urlRepo URLRepository, cacheRepo CacheRepository, analyticsRepo AnalyticsRepository, visitors VisitorCounter, clicks ClickStream, opts ShortenerOptions) *ShortenerService {
 	return &ShortenerService{
 		urlRepo:       urlRepo,
 		cacheRepo:     cacheRepo,
 		analyticsRepo: analyticsRepo,
+		visitors:      visitors,
+		clicks:        clicks,
+		previews:      opts.Previews,
+		auditor:       opts.Auditor,
+		events:        opts.Events,
+		local:         opts.Local,
+		codes:         opts.Codes,
+		fills:         opts.Fills,
 	}
 }
 
@@ -60,6 +180,16 @@ func (s *ShortenerService) ShortenURL(ctx context.Context, req *domain.CreatedUR
 			OriginalURL: req.OriginalURL,
 			ShortCode:   shortCode,
 			IsActive:    true,
+			Title:       strings.TrimSpace(req.Title),
+			Notes:       strings.TrimSpace(req.Notes),
+			Folder:      strings.TrimSpace(req.Folder),
+			Owner:       req.Owner,
+			Tags:        domain.NormalizeTags(req.Tags),
+			SocialCard: domain.SocialCard{
+				Title:       strings.TrimSpace(req.OGTitle),
+				Description: strings.TrimSpace(req.OGDescription),
+				Image:       strings.TrimSpace(req.OGImage),
+			},
 		}
 
 		if req.ExpiryHours > 0 {
@@ -69,6 +199,14 @@ func (s *ShortenerService) ShortenURL(ctx context.Context, req *domain.CreatedUR
 
 		err = s.urlRepo.Create(ctx, url)
 		if err == nil {
+			s.addToCache(ctx, url)
+			if s.previews != nil {
+				s.previews.Enqueue(ctx, url)
+			}
+			s.audit(ctx, domain.AuditURLCreate, url.ShortCode, nil, url)
+			if s.events != nil {
+				s.events.LinkCreated(ctx, url)
+			}
 			return url, nil
 		}
 
@@ -86,36 +224,456 @@ func (s *ShortenerService) ShortenURL(ctx context.Context, req *domain.CreatedUR
 	return nil, fmt.Errorf("failed to generate short code after %d retries: %w", maxRetries, err)
 }
 
-func (s *ShortenerService) GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, bool, error) {
-	url, err := s.cacheRepo.GetURL(ctx, shortCode)
+// GetOriginalURL looks a link up in the in-process cache, then Redis, then the
+// database, and reports which tier it came from. Codes the short code index
+// rules out, and codes recently found not to exist, never reach the database.
+func (s *ShortenerService) GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, string, error) {
+	if s.local != nil {
+		if url, ok := s.local.Get(shortCode); ok {
+			s.l1Hits.Add(1)
+			return url, domain.CacheTierL1, nil
+		}
+	}
+
+	if s.codes != nil && !s.codes.MightExist(shortCode) {
+		s.filtered.Add(1)
+		return nil, "", domain.ErrURLNotFound
+	}
+
+	url, ttl, err := s.cacheRepo.GetURL(ctx, shortCode)
 	if err == nil && url != nil {
-		return url, true, nil
+		s.l2Hits.Add(1)
+		if s.refreshDue(ttl) {
+			s.refresh(ctx, shortCode)
+		}
+		if s.local != nil {
+			s.local.Set(url)
+		}
+		return url, domain.CacheTierL2, nil
+	}
+	if errors.Is(err, domain.ErrURLNotFound) {
+		s.negativeHits.Add(1)
+		return nil, "", domain.ErrURLNotFound
 	}
 
-	url, err = s.urlRepo.GetByShortCode(ctx, shortCode)
+	s.misses.Add(1)
+	url, err = s.load(ctx, shortCode)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, false, fmt.Errorf("URL not found")
+			return nil, "", domain.ErrURLNotFound
 		}
-		return nil, false, fmt.Errorf("failed to get original url: %w", err)
+		return nil, "", fmt.Errorf("failed to get original url: %w", err)
 	}
 
-	go func() {
-		ttl := 24 * time.Hour
-		if url.ExpiresAt != nil {
-			ttl = time.Until(*url.ExpiresAt)
+	if s.local != nil {
+		s.local.Set(url)
+	}
+
+	return url, domain.CacheTierMiss, nil
+}
+
+// load reads a link from the database and caches the result. Concurrent loads
+// of the same code share one query and one cache write, so a popular link
+// expiring from Redis doesn't send every request to the database.
+func (s *ShortenerService) load(ctx context.Context, shortCode string) (*domain.URL, error) {
+	v, err, _ := s.loads.Do(shortCode, func() (any, error) {
+		// other callers may be waiting on this query, so it must outlive ctx
+		start := time.Now()
+		url, err := s.urlRepo.GetByShortCode(context.WithoutCancel(ctx), shortCode)
+		s.observeLoadTime(time.Since(start))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.fill(ctx, shortCode, func(ctx context.Context) error {
+				return s.cacheRepo.SetNotFound(ctx, shortCode, negativeCacheTTL)
+			})
 		}
-		s.cacheRepo.SetURL(context.Background(), url, ttl)
-	}()
+		if err != nil {
+			return nil, err
+		}
+
+		s.fill(ctx, shortCode, func(ctx context.Context) error {
+			return s.cacheRepo.SetURL(ctx, url, cacheTTL(url))
+		})
+		return url, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*domain.URL), nil
+}
 
-	return url, false, nil
+// refreshDue decides whether to reload a link whose Redis entry expires in
+// ttl. The chance rises sharply as ttl nears the time a database lookup takes
+// (probabilistic early expiration), so one request refreshes a hot link just
+// before it expires instead of every request missing at once.
+func (s *ShortenerService) refreshDue(ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	gap := float64(s.loadTime.Load()) * earlyRefreshBeta * -math.Log(rand.Float64())
+	return gap >= float64(ttl)
+}
+
+// refresh reloads a cached link in the background, at most once at a time per
+// code.
+func (s *ShortenerService) refresh(ctx context.Context, shortCode string) {
+	if _, queued := s.refreshing.LoadOrStore(shortCode, struct{}{}); queued {
+		return
+	}
+	s.earlyRefreshes.Add(1)
+
+	queued := s.fill(ctx, shortCode, func(ctx context.Context) error {
+		defer s.refreshing.Delete(shortCode)
+		_, err := s.load(ctx, shortCode)
+		return err
+	})
+	if !queued {
+		s.refreshing.Delete(shortCode)
+	}
+}
+
+// fill runs a cache write on the fill queue, and reports false when the queue
+// was full and the write was skipped. A skipped write only costs a later miss.
+func (s *ShortenerService) fill(ctx context.Context, shortCode string, write func(ctx context.Context) error) bool {
+	log := logger.FromContext(ctx)
+	job := func(ctx context.Context) {
+		err := write(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, errCacheUnavailable) {
+			log.Warn("Failed to fill cache", "short_code", shortCode, "error", err)
+		}
+	}
+
+	if s.fills == nil {
+		job(context.WithoutCancel(ctx))
+		return true
+	}
+	if !s.fills.Enqueue(job) {
+		log.Warn("Cache fill queue full, skipping", "short_code", shortCode)
+		return false
+	}
+	return true
+}
+
+// observeLoadTime folds a database lookup time into the moving average. Racing
+// updates may drop a sample, which doesn't matter for an estimate.
+func (s *ShortenerService) observeLoadTime(d time.Duration) {
+	avg := s.loadTime.Load()
+	if avg == 0 {
+		s.loadTime.Store(int64(d))
+		return
+	}
+	s.loadTime.Store(avg + (int64(d)-avg)/8)
+}
+
+func cacheTTL(url *domain.URL) time.Duration {
+	if url.ExpiresAt != nil {
+		return time.Until(*url.ExpiresAt)
+	}
+	return 24 * time.Hour
+}
+
+// addToCache caches a new link, replacing any not-found cached for its code,
+// and adds it to every replica's short code index.
+func (s *ShortenerService) addToCache(ctx context.Context, url *domain.URL) {
+	if s.codes != nil {
+		s.codes.Add(url.ShortCode)
+	}
+	if err := s.cacheRepo.AddURL(ctx, url, cacheTTL(url)); err != nil {
+		logger.FromContext(ctx).Warn("Failed to cache new URL", "short_code", url.ShortCode, "error", err)
+	}
+}
+
+// CacheStats returns redirect cache hit counts and ratios per tier.
+func (s *ShortenerService) CacheStats() *domain.CacheStats {
+	stats := &domain.CacheStats{
+		L1Hits:         s.l1Hits.Load(),
+		L2Hits:         s.l2Hits.Load(),
+		NegativeHits:   s.negativeHits.Load(),
+		Filtered:       s.filtered.Load(),
+		Misses:         s.misses.Load(),
+		EarlyRefreshes: s.earlyRefreshes.Load(),
+	}
+	stats.Lookups = stats.L1Hits + stats.Filtered + stats.L2Hits + stats.NegativeHits + stats.Misses
+	stats.L1HitRatio = ratio(stats.L1Hits, stats.Lookups)
+	stats.L2HitRatio = ratio(stats.L2Hits+stats.NegativeHits, stats.Lookups-stats.L1Hits-stats.Filtered)
+	stats.HitRatio = ratio(stats.Lookups-stats.Misses, stats.Lookups)
+
+	if s.local != nil {
+		stats.L1Entries, stats.L1Bytes = s.local.Stats()
+	}
+	return stats
+}
+
+func ratio(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// ListURLs returns a page of links with their folders and tags.
+func (s *ShortenerService) ListURLs(ctx context.Context, links *domain.LinkSet, page, pageSize int) (*domain.URLList, error) {
+	list, err := s.urlRepo.List(ctx, links, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list URLs: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateURL changes a link. The cached redirect is dropped when its
+// destination, expiry or active flag changes.
+func (s *ShortenerService) UpdateURL(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error) {
+	for _, field := range []**string{&req.Title, &req.Notes, &req.Folder, &req.OGTitle, &req.OGDescription, &req.OGImage} {
+		if *field != nil {
+			trimmed := strings.TrimSpace(**field)
+			*field = &trimmed
+		}
+	}
+	if req.Tags != nil {
+		tags := domain.NormalizeTags(*req.Tags)
+		req.Tags = &tags
+	}
+
+	before := s.auditSnapshot(ctx, shortCode)
+	url, err := s.urlRepo.Update(ctx, shortCode, req)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrURLNotFound
+		}
+		return nil, fmt.Errorf("failed to update URL: %w", err)
+	}
+
+	if req.OriginalURL != nil || req.ExpiryHours != nil || req.IsActive != nil {
+		s.invalidateCache(ctx, shortCode)
+	}
+	if req.OriginalURL != nil {
+		s.refreshPreview(ctx, before, url)
+	}
+	s.audit(ctx, domain.AuditURLUpdate, shortCode, before, url)
+	return url, nil
+}
+
+// GetURLHistory returns every version of a link, newest first.
+func (s *ShortenerService) GetURLHistory(ctx context.Context, shortCode string) ([]domain.URLVersion, error) {
+	versions, err := s.urlRepo.ListVersions(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrURLNotFound
+		}
+		return nil, fmt.Errorf("failed to get URL history: %w", err)
+	}
+	return versions, nil
+}
+
+// RollbackURL points a link back at what it pointed to in version. The
+// rollback itself becomes the link's newest version.
+func (s *ShortenerService) RollbackURL(ctx context.Context, shortCode string, version int, changedBy string) (*domain.URL, error) {
+	before := s.auditSnapshot(ctx, shortCode)
+	url, err := s.urlRepo.Rollback(ctx, shortCode, version, changedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, domain.ErrURLNotFound
+		case errors.Is(err, domain.ErrVersionNotFound):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to roll back URL: %w", err)
+	}
+
+	s.invalidateCache(ctx, shortCode)
+	s.refreshPreview(ctx, before, url)
+	s.audit(ctx, domain.AuditURLRollback, shortCode, before, url)
+	return url, nil
+}
+
+// refreshPreview fetches a new preview for a link whose destination may have
+// changed. before is nil when the old destination isn't known.
+func (s *ShortenerService) refreshPreview(ctx context.Context, before, after *domain.URL) {
+	if s.previews == nil || (before != nil && before.OriginalURL == after.OriginalURL) {
+		return
+	}
+	s.previews.Enqueue(ctx, after)
+}
+
+func (s *ShortenerService) audit(ctx context.Context, action, shortCode string, before, after *domain.URL) {
+	if s.auditor != nil {
+		s.auditor.Record(ctx, action, shortCode, before, after)
+	}
+}
+
+// auditSnapshot returns a link as it is before a change, for its audit event.
+// Without an auditor there's nothing to snapshot for.
+func (s *ShortenerService) auditSnapshot(ctx context.Context, shortCode string) *domain.URL {
+	if s.auditor == nil {
+		return nil
+	}
+
+	url, err := s.urlRepo.GetDetails(ctx, shortCode)
+	if err != nil {
+		return nil
+	}
+	return url
+}
+
+// invalidateCache drops a changed link from both cache tiers. Redis tells the
+// other replicas to drop their in-process copies.
+func (s *ShortenerService) invalidateCache(ctx context.Context, shortCode string) {
+	if s.local != nil {
+		s.local.Delete(shortCode)
+	}
+	if err := s.cacheRepo.DeleteURL(ctx, shortCode); err != nil {
+		logger.FromContext(ctx).Warn("Failed to invalidate cached URL", "short_code", shortCode, "error", err)
+	}
+}
+
+// GetSocialCard returns the card served to link unfurlers in place of a
+// redirect. It reads the database directly, since only unfurler requests need
+// it and the redirect cache doesn't hold it.
+func (s *ShortenerService) GetSocialCard(ctx context.Context, shortCode string) (*domain.SocialCard, error) {
+	url, err := s.urlRepo.GetDetails(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrURLNotFound
+		}
+		return nil, fmt.Errorf("failed to get social card: %w", err)
+	}
+
+	card := url.Card()
+	return &card, nil
 }
 
 func (s *ShortenerService) RecordClick(ctx context.Context, click *domain.ClickRequest) error {
-	return s.analyticsRepo.RecordClick(ctx, click)
+	event, err := s.analyticsRepo.RecordClick(ctx, click)
+	if err != nil {
+		return err
+	}
+
+	if click.IsBot {
+		return nil
+	}
+
+	if err := s.clicks.Publish(ctx, click.URLID, event); err != nil {
+		logger.FromContext(ctx).Warn("Failed to publish click", "url_id", click.URLID, "error", err)
+	}
+	if s.events != nil {
+		s.events.LinkClicked(ctx, click.URLID, click.ShortCode, event.TotalClicks)
+	}
+
+	return s.visitors.Track(ctx, click.URLID, click.IPAddress, time.Now())
+}
+
+func (s *ShortenerService) GetAnalytics(ctx context.Context, shortCode string, q *domain.AnalyticsQuery) (*domain.URLAnalytics, error) {
+	url, err := s.urlRepo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("URL not found")
+		}
+		return nil, fmt.Errorf("failed to get URL: %w", err)
+	}
+
+	analytics, err := s.analyticsRepo.GetAnalytics(ctx, url.ID, q)
+	if err != nil {
+		return nil, err
+	}
+	analytics.From = q.Range.From
+	analytics.To = q.Range.To
+	analytics.Granularity = q.Range.Granularity
+	analytics.Timezone = q.Range.Location.String()
+
+	if err := s.compareWithPrevious(ctx, url.ID, q, analytics); err != nil {
+		return nil, err
+	}
+
+	if err := s.countUniqueVisitors(ctx, url.ID, q.Range, analytics); err != nil {
+		logger.FromContext(ctx).Warn("Failed to count unique visitors", "short_code", shortCode, "error", err)
+	}
+
+	return analytics, nil
+}
+
+func (s *ShortenerService) compareWithPrevious(ctx context.Context, urlID int64, q *domain.AnalyticsQuery, analytics *domain.URLAnalytics) error {
+	previous := q.Range.Previous()
+
+	previousClicks, err := s.analyticsRepo.CountClicks(ctx, urlID, previous.From, previous.To, q.IncludeBots)
+	if err != nil {
+		return err
+	}
+
+	var clicks int64
+	for _, bucket := range analytics.ClicksByDate {
+		clicks += bucket.Count
+	}
+
+	comparison := &domain.Comparison{
+		ClicksComparison: domain.NewClicksComparison(clicks, previousClicks, previous),
+	}
+	analytics.Comparison = comparison
+	return nil
+}
+
+// countUniqueVisitors fills in visitor counts from the daily sketches, which
+// follow UTC days. Buckets only get a count when they are made of whole UTC
+// days, and ranges are widened to the UTC days they touch.
+func (s *ShortenerService) countUniqueVisitors(ctx context.Context, urlID int64, r *domain.TimeRange, analytics *domain.URLAnalytics) error {
+	total, err := s.visitors.CountTotal(ctx, urlID)
+	if err != nil {
+		return err
+	}
+	analytics.UniqueIPs = total
+
+	last := r.To.Add(-time.Nanosecond)
+	inRange, err := s.visitors.CountRange(ctx, urlID, r.From, last)
+	if err != nil {
+		return err
+	}
+	analytics.UniqueVisitors = inRange
+
+	previous := r.Previous()
+	previousInRange, err := s.visitors.CountRange(ctx, urlID, previous.From, previous.To.Add(-time.Nanosecond))
+	if err != nil {
+		return err
+	}
+	analytics.Comparison.UniqueVisitors = inRange
+	analytics.Comparison.PreviousUniqueVisitors = previousInRange
+
+	if r.Granularity == domain.GranularityHour || r.Location != time.UTC {
+		return nil
+	}
+
+	if r.Granularity == domain.GranularityDay {
+		dates := make([]string, len(analytics.ClicksByDate))
+		for i, cbd := range analytics.ClicksByDate {
+			dates[i] = cbd.Date
+		}
+
+		byDay, err := s.visitors.CountByDay(ctx, urlID, dates)
+		if err != nil {
+			return err
+		}
+		for i := range analytics.ClicksByDate {
+			analytics.ClicksByDate[i].UniqueVisitors = byDay[analytics.ClicksByDate[i].Date]
+		}
+		return nil
+	}
+
+	buckets := r.Buckets()
+	for i, start := range buckets {
+		end := last
+		if i+1 < len(buckets) {
+			end = buckets[i+1].Add(-time.Nanosecond)
+		}
+
+		count, err := s.visitors.CountRange(ctx, urlID, start, end)
+		if err != nil {
+			return err
+		}
+		analytics.ClicksByDate[i].UniqueVisitors = count
+	}
+
+	return nil
 }
 
-func (s *ShortenerService) GetAnalytics(ctx context.Context, shortCode string, days int) (*domain.URLAnalytics, error) {
+func (s *ShortenerService) GetClickHistory(ctx context.Context, shortCode string, filter *domain.ClickFilter, page, pageSize int) (*domain.ClickHistory, error) {
 	url, err := s.urlRepo.GetByShortCode(ctx, shortCode)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -123,11 +681,12 @@ func (s *ShortenerService) GetAnalytics(ctx context.Context, shortCode string, d
 		}
 		return nil, fmt.Errorf("failed to get URL: %w", err)
 	}
+	filter.URLID = url.ID
 
-	return s.analyticsRepo.GetAnalytics(ctx, url.ID, days)
+	return s.analyticsRepo.GetClickHistory(ctx, filter, page, pageSize)
 }
 
-func (s *ShortenerService) GetClickHistory(ctx context.Context, shortCode string, page, pageSize int) (*domain.ClickHistory, error) {
+func (s *ShortenerService) GetClickHistoryAfter(ctx context.Context, shortCode string, filter *domain.ClickFilter, pageSize int) (*domain.ClickHistory, error) {
 	url, err := s.urlRepo.GetByShortCode(ctx, shortCode)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -135,6 +694,77 @@ func (s *ShortenerService) GetClickHistory(ctx context.Context, shortCode string
 		}
 		return nil, fmt.Errorf("failed to get URL: %w", err)
 	}
+	filter.URLID = url.ID
+
+	return s.analyticsRepo.GetClickHistoryAfter(ctx, filter, pageSize)
+}
+
+func (s *ShortenerService) StreamClicks(ctx context.Context, shortCode string) (<-chan *domain.ClickEvent, error) {
+	url, err := s.urlRepo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("URL not found")
+		}
+		return nil, fmt.Errorf("failed to get URL: %w", err)
+	}
+
+	return s.clicks.Subscribe(ctx, url.ID)
+}
+
+// ExportClicks streams the clicks of shortCode to fn, or the clicks of every
+// link when shortCode is empty.
+func (s *ShortenerService) ExportClicks(ctx context.Context, shortCode string, filter *domain.ClickFilter, fn func(*domain.URLClick) error) error {
+	if shortCode != "" {
+		url, err := s.urlRepo.GetByShortCode(ctx, shortCode)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrURLNotFound
+			}
+			return fmt.Errorf("failed to get URL: %w", err)
+		}
+		filter.URLID = url.ID
+	}
+
+	return s.analyticsRepo.ExportClicks(ctx, filter, fn)
+}
+
+// GetOverview aggregates the clicks of a set of links, such as every link
+// created in one batch, and compares them with the period before.
+func (s *ShortenerService) GetOverview(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet) (*domain.AnalyticsOverview, error) {
+	overview, err := s.analyticsRepo.GetOverview(ctx, q, links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview: %w", err)
+	}
+	overview.From = q.Range.From
+	overview.To = q.Range.To
+	overview.Granularity = q.Range.Granularity
+	overview.Timezone = q.Range.Location.String()
+
+	previous := q.Range.Previous()
+	previousClicks, err := s.analyticsRepo.CountOverviewClicks(ctx, previous, q.IncludeBots, links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview: %w", err)
+	}
+
+	comparison := domain.NewClicksComparison(overview.TotalClicks, previousClicks, previous)
+	overview.Comparison = &comparison
+
+	return overview, nil
+}
+
+func (s *ShortenerService) GetTopLinks(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet, limit int) (*domain.TopLinks, error) {
+	stats, err := s.analyticsRepo.GetTopLinks(ctx, q, links, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top links: %w", err)
+	}
+	if stats == nil {
+		stats = []domain.LinkStats{}
+	}
 
-	return s.analyticsRepo.GetClickHistory(ctx, url.ID, page, pageSize)
+	return &domain.TopLinks{
+		From:     q.Range.From,
+		To:       q.Range.To,
+		Timezone: q.Range.Location.String(),
+		Links:    stats,
+	}, nil
 }
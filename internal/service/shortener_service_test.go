@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/bloom"
 	"github.com/gamassss/url-shortener/tests/mocks"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -18,11 +20,11 @@ import (
 func TestShortenURL_Success_GeneratedCode(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL: "https://example.com",
+		OriginalURL: "https://example.com",
 	}
 
 	mockURLRepo.On("Create", ctx, mock.MatchedBy(func(url *domain.URL) bool {
@@ -32,6 +34,9 @@ func TestShortenURL_Success_GeneratedCode(t *testing.T) {
 			url.ExpiresAt == nil
 	})).Return(nil).Once()
 
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
 	result, err := service.ShortenURL(ctx, req)
 
 	assert.NoError(t, err)
@@ -46,11 +51,11 @@ func TestShortenURL_Success_GeneratedCode(t *testing.T) {
 func TestShortenURL_Success_CustomAlias(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL:         "https://example.com",
+		OriginalURL: "https://example.com",
 		CustomAlias: "mylink",
 	}
 
@@ -59,6 +64,9 @@ func TestShortenURL_Success_CustomAlias(t *testing.T) {
 			url.OriginalURL == "https://example.com"
 	})).Return(nil).Once()
 
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
 	result, err := service.ShortenURL(ctx, req)
 
 	assert.NoError(t, err)
@@ -70,11 +78,11 @@ func TestShortenURL_Success_CustomAlias(t *testing.T) {
 func TestShortenURL_Success_WithExpiry(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL:         "https://example.com",
+		OriginalURL: "https://example.com",
 		ExpiryHours: 24,
 	}
 
@@ -88,6 +96,9 @@ func TestShortenURL_Success_WithExpiry(t *testing.T) {
 		return diff < time.Minute && diff > -time.Minute
 	})).Return(nil).Once()
 
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
 	result, err := service.ShortenURL(ctx, req)
 
 	assert.NoError(t, err)
@@ -99,11 +110,11 @@ func TestShortenURL_Success_WithExpiry(t *testing.T) {
 func TestShortenURL_Retry_SuccessAfterCollision(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL: "https://example.com",
+		OriginalURL: "https://example.com",
 	}
 
 	pgErr := &pgconn.PgError{
@@ -117,6 +128,9 @@ func TestShortenURL_Retry_SuccessAfterCollision(t *testing.T) {
 	mockURLRepo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).
 		Return(nil).Once()
 
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
 	result, err := service.ShortenURL(ctx, req)
 
 	assert.NoError(t, err)
@@ -129,11 +143,11 @@ func TestShortenURL_Retry_SuccessAfterCollision(t *testing.T) {
 func TestShortenURL_Retry_FailAfterMaxRetries(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL: "https://example.com",
+		OriginalURL: "https://example.com",
 	}
 
 	pgErr := &pgconn.PgError{
@@ -156,11 +170,11 @@ func TestShortenURL_Retry_FailAfterMaxRetries(t *testing.T) {
 func TestShortenURL_CustomAlias_DuplicateError(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL:         "https://example.com",
+		OriginalURL: "https://example.com",
 		CustomAlias: "existing",
 	}
 
@@ -186,7 +200,7 @@ func TestShortenURL_CustomAlias_DuplicateError(t *testing.T) {
 func TestGetOriginalURL_Success_FromCache(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	cachedURL := &domain.URL{
@@ -200,7 +214,7 @@ func TestGetOriginalURL_Success_FromCache(t *testing.T) {
 	}
 
 	mockCacheRepo.On("GetURL", ctx, "abc123").
-		Return(cachedURL, nil).Once()
+		Return(cachedURL, 24*time.Hour, nil).Once()
 
 	result, _, err := service.GetOriginalURL(ctx, "abc123")
 
@@ -216,7 +230,7 @@ func TestGetOriginalURL_Success_FromCache(t *testing.T) {
 func TestGetOriginalURL_Success_FromDB_CacheMiss(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	expectedURL := &domain.URL{
@@ -230,9 +244,9 @@ func TestGetOriginalURL_Success_FromDB_CacheMiss(t *testing.T) {
 	}
 
 	mockCacheRepo.On("GetURL", ctx, "abc123").
-		Return(nil, errors.New("cache miss")).Once()
+		Return(nil, 0, errors.New("cache miss")).Once()
 
-	mockURLRepo.On("GetByShortCode", ctx, "abc123").
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").
 		Return(expectedURL, nil).Once()
 
 	mockCacheRepo.On("SetURL", mock.Anything, expectedURL, mock.AnythingOfType("time.Duration")).
@@ -252,15 +266,18 @@ func TestGetOriginalURL_Success_FromDB_CacheMiss(t *testing.T) {
 func TestGetOriginalURL_NotFound(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	mockCacheRepo.On("GetURL", ctx, "notfound").
-		Return(nil, errors.New("cache miss")).Once()
+		Return(nil, 0, errors.New("cache miss")).Once()
 
-	mockURLRepo.On("GetByShortCode", ctx, "notfound").
+	mockURLRepo.On("GetByShortCode", mock.Anything, "notfound").
 		Return(nil, pgx.ErrNoRows).Once()
 
+	mockCacheRepo.On("SetNotFound", mock.Anything, "notfound", negativeCacheTTL).
+		Return(nil).Maybe()
+
 	result, _, err := service.GetOriginalURL(ctx, "notfound")
 
 	assert.Error(t, err)
@@ -274,15 +291,15 @@ func TestGetOriginalURL_NotFound(t *testing.T) {
 func TestGetOriginalURL_DatabaseError(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	dbErr := errors.New("connection timeout")
 
 	mockCacheRepo.On("GetURL", ctx, "abc123").
-		Return(nil, errors.New("cache miss")).Once()
+		Return(nil, 0, errors.New("cache miss")).Once()
 
-	mockURLRepo.On("GetByShortCode", ctx, "abc123").
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").
 		Return(nil, dbErr).Once()
 
 	result, _, err := service.GetOriginalURL(ctx, "abc123")
@@ -298,11 +315,11 @@ func TestGetOriginalURL_DatabaseError(t *testing.T) {
 func TestShortenURL_DatabaseError(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	req := &domain.CreatedURLRequest{
-		URL: "https://example.com",
+		OriginalURL: "https://example.com",
 	}
 
 	dbErr := fmt.Errorf("database connection failed")
@@ -322,7 +339,7 @@ func TestShortenURL_DatabaseError(t *testing.T) {
 func TestGetOriginalURL_CacheError_FallbackToDB(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	expectedURL := &domain.URL{
@@ -333,9 +350,9 @@ func TestGetOriginalURL_CacheError_FallbackToDB(t *testing.T) {
 	}
 
 	mockCacheRepo.On("GetURL", ctx, "abc123").
-		Return(nil, errors.New("redis connection error")).Once()
+		Return(nil, 0, errors.New("redis connection error")).Once()
 
-	mockURLRepo.On("GetByShortCode", ctx, "abc123").
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").
 		Return(expectedURL, nil).Once()
 
 	mockCacheRepo.On("SetURL", mock.Anything, expectedURL, mock.AnythingOfType("time.Duration")).
@@ -354,7 +371,7 @@ func TestGetOriginalURL_CacheError_FallbackToDB(t *testing.T) {
 func TestGetOriginalURL_WithExpiry_CorrectTTL(t *testing.T) {
 	mockURLRepo := new(mocks.MockURLRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	service := NewShortenerService(mockURLRepo, mockCacheRepo)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
 	ctx := context.Background()
 
 	expiresAt := time.Now().Add(2 * time.Hour)
@@ -367,9 +384,9 @@ func TestGetOriginalURL_WithExpiry_CorrectTTL(t *testing.T) {
 	}
 
 	mockCacheRepo.On("GetURL", ctx, "abc123").
-		Return(nil, errors.New("cache miss")).Once()
+		Return(nil, 0, errors.New("cache miss")).Once()
 
-	mockURLRepo.On("GetByShortCode", ctx, "abc123").
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").
 		Return(expectedURL, nil).Once()
 
 	mockCacheRepo.On("SetURL", mock.Anything, expectedURL, mock.MatchedBy(func(ttl time.Duration) bool {
@@ -388,3 +405,684 @@ func TestGetOriginalURL_WithExpiry_CorrectTTL(t *testing.T) {
 	mockCacheRepo.AssertExpectations(t)
 	mockURLRepo.AssertExpectations(t)
 }
+
+func TestGetAnalytics_ComparesWithPreviousPeriod(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockAnalyticsRepo := new(mocks.MockAnalyticsRepository)
+	mockVisitors := new(mocks.MockVisitorCounter)
+	service := NewShortenerService(mockURLRepo, nil, mockAnalyticsRepo, mockVisitors, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)
+	timeRange, err := domain.NewTimeRange(from, to, domain.GranularityWeek, "UTC")
+	assert.NoError(t, err)
+	q := &domain.AnalyticsQuery{Range: timeRange}
+
+	mockURLRepo.On("GetByShortCode", ctx, "abc123").
+		Return(&domain.URL{ID: 1, ShortCode: "abc123"}, nil).Once()
+	mockAnalyticsRepo.On("GetAnalytics", ctx, int64(1), q).
+		Return(&domain.URLAnalytics{
+			ShortCode: "abc123",
+			ClicksByDate: []domain.ClicksByDate{
+				{Date: "2025-12-01", Count: 30},
+				{Date: "2025-12-08", Count: 20},
+			},
+		}, nil).Once()
+	mockAnalyticsRepo.On("CountClicks", ctx, int64(1), from.AddDate(0, 0, -14), from, false).
+		Return(int64(40), nil).Once()
+
+	last := to.Add(-time.Nanosecond)
+	mockVisitors.On("CountTotal", ctx, int64(1)).Return(int64(500), nil).Once()
+	mockVisitors.On("CountRange", ctx, int64(1), from, last).Return(int64(35), nil).Once()
+	mockVisitors.On("CountRange", ctx, int64(1), from.AddDate(0, 0, -14), from.Add(-time.Nanosecond)).Return(int64(28), nil).Once()
+	mockVisitors.On("CountRange", ctx, int64(1), from, from.AddDate(0, 0, 7).Add(-time.Nanosecond)).Return(int64(22), nil).Once()
+	mockVisitors.On("CountRange", ctx, int64(1), from.AddDate(0, 0, 7), last).Return(int64(15), nil).Once()
+
+	analytics, err := service.GetAnalytics(ctx, "abc123", q)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.GranularityWeek, analytics.Granularity)
+	assert.Equal(t, "UTC", analytics.Timezone)
+	assert.Equal(t, int64(50), analytics.Comparison.Clicks)
+	assert.Equal(t, int64(40), analytics.Comparison.PreviousClicks)
+	assert.InDelta(t, 25.0, *analytics.Comparison.ClicksChange, 0.001)
+	assert.Equal(t, int64(35), analytics.Comparison.UniqueVisitors)
+	assert.Equal(t, int64(28), analytics.Comparison.PreviousUniqueVisitors)
+	assert.Equal(t, int64(22), analytics.ClicksByDate[0].UniqueVisitors)
+	assert.Equal(t, int64(15), analytics.ClicksByDate[1].UniqueVisitors)
+	mockAnalyticsRepo.AssertExpectations(t)
+	mockVisitors.AssertExpectations(t)
+}
+
+func TestGetAnalytics_NoChangeWithoutPreviousClicks(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockAnalyticsRepo := new(mocks.MockAnalyticsRepository)
+	mockVisitors := new(mocks.MockVisitorCounter)
+	service := NewShortenerService(mockURLRepo, nil, mockAnalyticsRepo, mockVisitors, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	timeRange, err := domain.NewTimeRange(from, from.Add(2*time.Hour), domain.GranularityHour, "Asia/Jakarta")
+	assert.NoError(t, err)
+	q := &domain.AnalyticsQuery{Range: timeRange}
+
+	mockURLRepo.On("GetByShortCode", ctx, "abc123").
+		Return(&domain.URL{ID: 1, ShortCode: "abc123"}, nil).Once()
+	mockAnalyticsRepo.On("GetAnalytics", ctx, int64(1), q).
+		Return(&domain.URLAnalytics{ClicksByDate: []domain.ClicksByDate{{Count: 3}, {Count: 0}}}, nil).Once()
+	mockAnalyticsRepo.On("CountClicks", ctx, int64(1), mock.Anything, mock.Anything, false).
+		Return(int64(0), nil).Once()
+	mockVisitors.On("CountTotal", ctx, int64(1)).Return(int64(3), nil).Once()
+	mockVisitors.On("CountRange", ctx, int64(1), mock.Anything, mock.Anything).Return(int64(2), nil).Twice()
+
+	analytics, err := service.GetAnalytics(ctx, "abc123", q)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), analytics.Comparison.Clicks)
+	assert.Nil(t, analytics.Comparison.ClicksChange)
+	assert.Equal(t, int64(0), analytics.ClicksByDate[0].UniqueVisitors, "hourly buckets have no visitor counts")
+	mockVisitors.AssertExpectations(t)
+}
+
+func TestGetOverview_ComparesWithPreviousPeriod(t *testing.T) {
+	mockAnalyticsRepo := new(mocks.MockAnalyticsRepository)
+	service := NewShortenerService(nil, nil, mockAnalyticsRepo, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	timeRange, err := domain.NewTimeRange(from, from.AddDate(0, 0, 7), domain.GranularityDay, "UTC")
+	assert.NoError(t, err)
+	q := &domain.AnalyticsQuery{Range: timeRange}
+	links := &domain.LinkSet{ShortCodes: []string{"abc123", "xyz789"}}
+
+	mockAnalyticsRepo.On("GetOverview", ctx, q, links).
+		Return(&domain.AnalyticsOverview{TotalClicks: 30, ActiveLinks: 2}, nil).Once()
+	mockAnalyticsRepo.On("CountOverviewClicks", ctx, timeRange.Previous(), false, links).
+		Return(int64(40), nil).Once()
+
+	overview, err := service.GetOverview(ctx, q, links)
+
+	assert.NoError(t, err)
+	assert.Equal(t, from, overview.From)
+	assert.Equal(t, int64(30), overview.Comparison.Clicks)
+	assert.Equal(t, int64(40), overview.Comparison.PreviousClicks)
+	assert.InDelta(t, -25.0, *overview.Comparison.ClicksChange, 0.001)
+	mockAnalyticsRepo.AssertExpectations(t)
+}
+
+func TestShortenURL_Success_WithFolderAndTags(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	req := &domain.CreatedURLRequest{
+		OriginalURL: "https://example.com",
+		Folder:      " Q4 Campaigns ",
+		Tags:        []string{"Promo", "promo", "Email"},
+		Owner:       "alice",
+	}
+
+	mockURLRepo.On("Create", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.Folder == "Q4 Campaigns" && url.Owner == "alice" &&
+			assert.ObjectsAreEqual([]string{"promo", "email"}, url.Tags)
+	})).Return(nil).Once()
+
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
+	result, err := service.ShortenURL(ctx, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"promo", "email"}, result.Tags)
+	mockURLRepo.AssertExpectations(t)
+}
+
+func TestUpdateURL_NotFound(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	tags := []string{"Promo"}
+	req := &domain.UpdateURLRequest{Tags: &tags}
+
+	mockURLRepo.On("Update", ctx, "missing", req).Return(nil, pgx.ErrNoRows).Once()
+
+	url, err := service.UpdateURL(ctx, "missing", req)
+
+	assert.Nil(t, url)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Equal(t, []string{"promo"}, *req.Tags)
+}
+
+func TestShortenURL_EnqueuesPreview(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockPreviews := new(mocks.MockPreviewQueue)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Previews: mockPreviews})
+	ctx := context.Background()
+
+	req := &domain.CreatedURLRequest{
+		OriginalURL: "https://example.com",
+		Title:       " Spring sale ",
+	}
+
+	mockURLRepo.On("Create", ctx, mock.Anything).Return(nil).Once()
+	mockPreviews.On("Enqueue", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.OriginalURL == "https://example.com" && url.Title == "Spring sale"
+	})).Once()
+
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
+	_, err := service.ShortenURL(ctx, req)
+
+	assert.NoError(t, err)
+	mockPreviews.AssertExpectations(t)
+}
+
+func TestGetSocialCard_UsesPreviewFallbacks(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	mockURLRepo.On("GetDetails", ctx, "abc1234").Return(&domain.URL{
+		ShortCode:  "abc1234",
+		SocialCard: domain.SocialCard{Title: "Spring sale"},
+		Preview:    &domain.LinkPreview{OGTitle: "Shop", OGImage: "https://example.com/og.png"},
+	}, nil).Once()
+
+	card, err := service.GetSocialCard(ctx, "abc1234")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.SocialCard{Title: "Spring sale", Image: "https://example.com/og.png"}, card)
+}
+
+func TestGetSocialCard_NotFound(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	mockURLRepo.On("GetDetails", ctx, "missing").Return(nil, pgx.ErrNoRows).Once()
+
+	card, err := service.GetSocialCard(ctx, "missing")
+
+	assert.Nil(t, card)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestGetSocialCard_DatabaseError(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	dbErr := errors.New("connection refused")
+	mockURLRepo.On("GetDetails", ctx, "abc1234").Return(nil, dbErr).Once()
+
+	_, err := service.GetSocialCard(ctx, "abc1234")
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestExportClicks_NotFound(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	mockURLRepo.On("GetByShortCode", ctx, "missing").Return(nil, pgx.ErrNoRows).Once()
+
+	err := service.ExportClicks(ctx, "missing", &domain.ClickFilter{}, func(*domain.URLClick) error { return nil })
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestExportClicks_DatabaseError(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	dbErr := errors.New("connection refused")
+	mockURLRepo.On("GetByShortCode", ctx, "abc1234").Return(nil, dbErr).Once()
+
+	err := service.ExportClicks(ctx, "abc1234", &domain.ClickFilter{}, func(*domain.URLClick) error { return nil })
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestUpdateURL_DestinationChangeInvalidatesCache(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	destination := "https://example.com/new"
+	req := &domain.UpdateURLRequest{OriginalURL: &destination, ChangedBy: "alice"}
+	updated := &domain.URL{ShortCode: "abc1234", OriginalURL: destination, Version: 2}
+
+	mockURLRepo.On("Update", ctx, "abc1234", req).Return(updated, nil).Once()
+	mockCacheRepo.On("DeleteURL", ctx, "abc1234").Return(nil).Once()
+
+	url, err := service.UpdateURL(ctx, "abc1234", req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, url)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestUpdateURL_MetadataChangeKeepsCache(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	title := "Spring sale"
+	req := &domain.UpdateURLRequest{Title: &title}
+
+	mockURLRepo.On("Update", ctx, "abc1234", req).Return(&domain.URL{ShortCode: "abc1234"}, nil).Once()
+
+	_, err := service.UpdateURL(ctx, "abc1234", req)
+
+	assert.NoError(t, err)
+	mockCacheRepo.AssertNotCalled(t, "DeleteURL", mock.Anything, mock.Anything)
+}
+
+func TestRollbackURL_Success(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	rolledBack := &domain.URL{ShortCode: "abc1234", OriginalURL: "https://example.com/v1", Version: 3}
+
+	mockURLRepo.On("Rollback", ctx, "abc1234", 1, "bob").Return(rolledBack, nil).Once()
+	mockCacheRepo.On("DeleteURL", ctx, "abc1234").Return(errors.New("redis down")).Once()
+
+	url, err := service.RollbackURL(ctx, "abc1234", 1, "bob")
+
+	assert.NoError(t, err, "cache errors should not fail the rollback")
+	assert.Equal(t, rolledBack, url)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestUpdateURL_DestinationChangeRefreshesPreview(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockPreviews := new(mocks.MockPreviewQueue)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Previews: mockPreviews})
+	ctx := context.Background()
+
+	destination := "https://example.com/new"
+	req := &domain.UpdateURLRequest{OriginalURL: &destination}
+	updated := &domain.URL{ID: 1, ShortCode: "abc1234", OriginalURL: destination, Version: 2}
+
+	mockURLRepo.On("Update", ctx, "abc1234", req).Return(updated, nil).Once()
+	mockCacheRepo.On("DeleteURL", ctx, "abc1234").Return(nil).Once()
+	mockPreviews.On("Enqueue", ctx, updated).Once()
+
+	_, err := service.UpdateURL(ctx, "abc1234", req)
+
+	assert.NoError(t, err)
+	mockPreviews.AssertExpectations(t)
+}
+
+func TestUpdateURL_MetadataChangeKeepsPreview(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockPreviews := new(mocks.MockPreviewQueue)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{Previews: mockPreviews})
+	ctx := context.Background()
+
+	title := "Spring sale"
+	req := &domain.UpdateURLRequest{Title: &title}
+	mockURLRepo.On("Update", ctx, "abc1234", req).Return(&domain.URL{ShortCode: "abc1234"}, nil).Once()
+
+	_, err := service.UpdateURL(ctx, "abc1234", req)
+
+	assert.NoError(t, err)
+	mockPreviews.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+}
+
+func TestRollbackURL_RefreshesPreview(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockPreviews := new(mocks.MockPreviewQueue)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Previews: mockPreviews})
+	ctx := context.Background()
+
+	rolledBack := &domain.URL{ID: 1, ShortCode: "abc1234", OriginalURL: "https://example.com/v1", Version: 3}
+	mockURLRepo.On("Rollback", ctx, "abc1234", 1, "bob").Return(rolledBack, nil).Once()
+	mockCacheRepo.On("DeleteURL", ctx, "abc1234").Return(nil).Once()
+	mockPreviews.On("Enqueue", ctx, rolledBack).Once()
+
+	_, err := service.RollbackURL(ctx, "abc1234", 1, "bob")
+
+	assert.NoError(t, err)
+	mockPreviews.AssertExpectations(t)
+}
+
+func TestRollbackURL_VersionNotFound(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	mockURLRepo.On("Rollback", ctx, "abc1234", 9, "").Return(nil, domain.ErrVersionNotFound).Once()
+
+	url, err := service.RollbackURL(ctx, "abc1234", 9, "")
+
+	assert.Nil(t, url)
+	assert.ErrorIs(t, err, domain.ErrVersionNotFound)
+}
+
+func TestGetURLHistory_NotFound(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	mockURLRepo.On("ListVersions", ctx, "missing").Return(nil, pgx.ErrNoRows).Once()
+
+	versions, err := service.GetURLHistory(ctx, "missing")
+
+	assert.Nil(t, versions)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestGetURLHistory_DatabaseError(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	dbErr := errors.New("connection refused")
+	mockURLRepo.On("ListVersions", ctx, "abc1234").Return(nil, dbErr).Once()
+
+	_, err := service.GetURLHistory(ctx, "abc1234")
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestRollbackURL_DatabaseError(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	dbErr := errors.New("connection refused")
+	mockURLRepo.On("Rollback", ctx, "abc1234", 2, "").Return(nil, dbErr).Once()
+
+	_, err := service.RollbackURL(ctx, "abc1234", 2, "")
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestUpdateURL_AuditsBeforeAndAfter(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockAuditor := new(mocks.MockAuditor)
+	service := NewShortenerService(mockURLRepo, nil, nil, nil, nil, ShortenerOptions{Auditor: mockAuditor})
+	ctx := context.Background()
+
+	title := "Spring sale"
+	req := &domain.UpdateURLRequest{Title: &title}
+	before := &domain.URL{ShortCode: "abc1234", Title: "Sale"}
+	after := &domain.URL{ShortCode: "abc1234", Title: "Spring sale"}
+
+	mockURLRepo.On("GetDetails", ctx, "abc1234").Return(before, nil).Once()
+	mockURLRepo.On("Update", ctx, "abc1234", req).Return(after, nil).Once()
+	mockAuditor.On("Record", ctx, domain.AuditURLUpdate, "abc1234", before, after).Once()
+
+	_, err := service.UpdateURL(ctx, "abc1234", req)
+
+	assert.NoError(t, err)
+	mockAuditor.AssertExpectations(t)
+}
+
+func TestShortenURL_EmitsLinkCreated(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockEvents := new(mocks.MockLinkEvents)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Events: mockEvents})
+	ctx := context.Background()
+
+	mockURLRepo.On("Create", ctx, mock.Anything).Return(nil).Once()
+	mockEvents.On("LinkCreated", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "mylink"
+	})).Once()
+
+	mockCacheRepo.On("AddURL", ctx, mock.AnythingOfType("*domain.URL"), mock.AnythingOfType("time.Duration")).
+		Return(nil).Once()
+
+	_, err := service.ShortenURL(ctx, &domain.CreatedURLRequest{OriginalURL: "https://example.com", CustomAlias: "mylink"})
+
+	assert.NoError(t, err)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestGetOriginalURL_FromLocalCache(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockLocal := new(mocks.MockLocalCache)
+	service := NewShortenerService(nil, mockCacheRepo, nil, nil, nil, ShortenerOptions{Local: mockLocal})
+	ctx := context.Background()
+
+	cachedURL := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com"}
+	mockLocal.On("Get", "abc123").Return(cachedURL, true).Once()
+
+	result, tier, err := service.GetOriginalURL(ctx, "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, cachedURL, result)
+	assert.Equal(t, domain.CacheTierL1, tier)
+	mockCacheRepo.AssertNotCalled(t, "GetURL")
+}
+
+func TestGetOriginalURL_RedisHitFillsLocalCache(t *testing.T) {
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockLocal := new(mocks.MockLocalCache)
+	service := NewShortenerService(nil, mockCacheRepo, nil, nil, nil, ShortenerOptions{Local: mockLocal})
+	ctx := context.Background()
+
+	cachedURL := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com"}
+	mockLocal.On("Get", "abc123").Return(nil, false).Once()
+	mockCacheRepo.On("GetURL", ctx, "abc123").Return(cachedURL, 24*time.Hour, nil).Once()
+	mockLocal.On("Set", cachedURL).Once()
+
+	_, tier, err := service.GetOriginalURL(ctx, "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.CacheTierL2, tier)
+	mockLocal.AssertExpectations(t)
+}
+
+func TestCacheStats_RatiosPerTier(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockLocal := new(mocks.MockLocalCache)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Local: mockLocal})
+	ctx := context.Background()
+
+	url := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com"}
+	mockLocal.On("Get", "hot").Return(url, true).Times(2)
+	mockLocal.On("Get", "warm").Return(nil, false).Once()
+	mockLocal.On("Get", "cold").Return(nil, false).Once()
+	mockLocal.On("Set", url).Twice()
+	mockLocal.On("Stats").Return(1, int64(300)).Once()
+	mockCacheRepo.On("GetURL", ctx, "warm").Return(url, 24*time.Hour, nil).Once()
+	mockCacheRepo.On("GetURL", ctx, "cold").Return(nil, 0, errors.New("cache miss")).Once()
+	mockCacheRepo.On("SetURL", mock.Anything, url, mock.Anything).Return(nil).Maybe()
+	mockURLRepo.On("GetByShortCode", mock.Anything, "cold").Return(url, nil).Once()
+
+	for _, shortCode := range []string{"hot", "hot", "warm", "cold"} {
+		_, _, err := service.GetOriginalURL(ctx, shortCode)
+		assert.NoError(t, err)
+	}
+
+	stats := service.CacheStats()
+	assert.Equal(t, int64(4), stats.Lookups)
+	assert.Equal(t, 0.5, stats.L1HitRatio)
+	assert.Equal(t, 0.5, stats.L2HitRatio)
+	assert.Equal(t, 0.75, stats.HitRatio)
+	assert.Equal(t, 1, stats.L1Entries)
+}
+
+func TestUpdateURL_InvalidatesLocalCache(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockLocal := new(mocks.MockLocalCache)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Local: mockLocal})
+	ctx := context.Background()
+
+	active := false
+	req := &domain.UpdateURLRequest{IsActive: &active}
+	mockURLRepo.On("Update", ctx, "abc123", req).Return(&domain.URL{ShortCode: "abc123"}, nil).Once()
+	mockLocal.On("Delete", "abc123").Once()
+	mockCacheRepo.On("DeleteURL", ctx, "abc123").Return(nil).Once()
+
+	_, err := service.UpdateURL(ctx, "abc123", req)
+
+	assert.NoError(t, err)
+	mockLocal.AssertExpectations(t)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestGetOriginalURL_CachedNotFound(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	mockCacheRepo.On("GetURL", ctx, "cold_123").Return(nil, 0, domain.ErrURLNotFound).Once()
+
+	_, _, err := service.GetOriginalURL(ctx, "cold_123")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockURLRepo.AssertNotCalled(t, "GetByShortCode")
+	assert.Equal(t, int64(1), service.CacheStats().NegativeHits)
+}
+
+func TestGetOriginalURL_FilteredByShortCodeIndex(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	codes := NewShortCodeFilter(nil, 100, 0.01, nil)
+	codes.current = bloom.New(100, 0.01)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Codes: codes})
+	ctx := context.Background()
+
+	_, _, err := service.GetOriginalURL(ctx, "cold_123")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockCacheRepo.AssertNotCalled(t, "GetURL")
+	mockURLRepo.AssertNotCalled(t, "GetByShortCode")
+	assert.Equal(t, int64(1), service.CacheStats().Filtered)
+}
+
+func TestShortenURL_AddsToShortCodeIndex(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	codes := NewShortCodeFilter(nil, 100, 0.01, nil)
+	codes.current = bloom.New(100, 0.01)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Codes: codes})
+	ctx := context.Background()
+
+	mockURLRepo.On("Create", ctx, mock.Anything).Return(nil).Once()
+	mockCacheRepo.On("AddURL", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "mylink"
+	}), 24*time.Hour).Return(nil).Once()
+
+	_, err := service.ShortenURL(ctx, &domain.CreatedURLRequest{OriginalURL: "https://example.com", CustomAlias: "mylink"})
+
+	assert.NoError(t, err)
+	assert.True(t, codes.MightExist("mylink"))
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestGetOriginalURL_ConcurrentMissesShareOneQuery(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	url := &domain.URL{ID: 1, ShortCode: "hot1234", OriginalURL: "https://example.com", IsActive: true}
+	mockCacheRepo.On("GetURL", ctx, "hot1234").Return(nil, 0, errors.New("cache miss"))
+	mockURLRepo.On("GetByShortCode", mock.Anything, "hot1234").
+		After(50*time.Millisecond).Return(url, nil).Once()
+	mockCacheRepo.On("SetURL", mock.Anything, url, 24*time.Hour).Return(nil).Once()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _, err := service.GetOriginalURL(ctx, "hot1234")
+			assert.NoError(t, err)
+			assert.Equal(t, url.OriginalURL, result.OriginalURL)
+		}()
+	}
+	wg.Wait()
+
+	mockURLRepo.AssertNumberOfCalls(t, "GetByShortCode", 1)
+	mockCacheRepo.AssertNumberOfCalls(t, "SetURL", 1)
+}
+
+func TestGetOriginalURL_RefreshesBeforeExpiry(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	// a database lookup far slower than the time left makes a refresh certain
+	service.loadTime.Store(int64(time.Hour))
+
+	cachedURL := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	freshURL := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com/new", IsActive: true}
+	mockCacheRepo.On("GetURL", ctx, "abc123").Return(cachedURL, time.Millisecond, nil).Once()
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").Return(freshURL, nil).Once()
+	mockCacheRepo.On("SetURL", mock.Anything, freshURL, 24*time.Hour).Return(nil).Once()
+
+	result, tier, err := service.GetOriginalURL(ctx, "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, cachedURL.OriginalURL, result.OriginalURL, "the cached link is served while it is refreshed")
+	assert.Equal(t, domain.CacheTierL2, tier)
+	mockURLRepo.AssertExpectations(t)
+	mockCacheRepo.AssertExpectations(t)
+	assert.Equal(t, int64(1), service.CacheStats().EarlyRefreshes)
+}
+
+func TestGetOriginalURL_NoRefreshFarFromExpiry(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{})
+	ctx := context.Background()
+
+	service.loadTime.Store(int64(5 * time.Millisecond))
+
+	cachedURL := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	mockCacheRepo.On("GetURL", ctx, "abc123").Return(cachedURL, 24*time.Hour, nil).Times(100)
+
+	for i := 0; i < 100; i++ {
+		_, _, err := service.GetOriginalURL(ctx, "abc123")
+		assert.NoError(t, err)
+	}
+
+	mockURLRepo.AssertNotCalled(t, "GetByShortCode")
+	assert.Equal(t, int64(0), service.CacheStats().EarlyRefreshes)
+}
+
+func TestGetOriginalURL_FillQueueFull(t *testing.T) {
+	mockURLRepo := new(mocks.MockURLRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	fills := NewCacheFiller(0)
+	service := NewShortenerService(mockURLRepo, mockCacheRepo, nil, nil, nil, ShortenerOptions{Fills: fills})
+	ctx := context.Background()
+
+	url := &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	mockCacheRepo.On("GetURL", ctx, "abc123").Return(nil, 0, errors.New("cache miss")).Once()
+	mockURLRepo.On("GetByShortCode", mock.Anything, "abc123").Return(url, nil).Once()
+
+	result, tier, err := service.GetOriginalURL(ctx, "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+	assert.Equal(t, domain.CacheTierMiss, tier)
+	mockCacheRepo.AssertNotCalled(t, "SetURL")
+}
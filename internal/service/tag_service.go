@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+)
+
+type TagRepository interface {
+	List(ctx context.Context) ([]domain.Tag, error)
+	Rename(ctx context.Context, name, newName string) error
+	Merge(ctx context.Context, source, target string) error
+	Delete(ctx context.Context, name string) error
+}
+
+type TagService struct {
+	repo    TagRepository
+	auditor Auditor
+}
+
+// NewTagService creates the service. auditor may be nil, in which case
+// changes to tags aren't audited.
+func NewTagService(repo TagRepository, auditor Auditor) *TagService {
+	return &TagService{repo: repo, auditor: auditor}
+}
+
+func (s *TagService) ListTags(ctx context.Context) ([]domain.Tag, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *TagService) RenameTag(ctx context.Context, name, newName string) error {
+	name, newName = domain.NormalizeTag(name), domain.NormalizeTag(newName)
+	if newName == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrInvalidTag)
+	}
+	if name == newName {
+		return nil
+	}
+	if err := s.repo.Rename(ctx, name, newName); err != nil {
+		return err
+	}
+
+	s.audit(ctx, domain.AuditTagRename, name, tagPayload(name), tagPayload(newName))
+	return nil
+}
+
+// MergeTags retags every link tagged source with target, then deletes source.
+func (s *TagService) MergeTags(ctx context.Context, source, target string) error {
+	source, target = domain.NormalizeTag(source), domain.NormalizeTag(target)
+	if source == target {
+		return fmt.Errorf("%w: cannot merge a tag into itself", domain.ErrInvalidTag)
+	}
+	if err := s.repo.Merge(ctx, source, target); err != nil {
+		return err
+	}
+
+	s.audit(ctx, domain.AuditTagMerge, source, tagPayload(source), tagPayload(target))
+	return nil
+}
+
+func (s *TagService) DeleteTag(ctx context.Context, name string) error {
+	name = domain.NormalizeTag(name)
+	if err := s.repo.Delete(ctx, name); err != nil {
+		return err
+	}
+
+	s.audit(ctx, domain.AuditTagDelete, name, tagPayload(name), nil)
+	return nil
+}
+
+func (s *TagService) audit(ctx context.Context, action, name string, before, after any) {
+	if s.auditor != nil {
+		s.auditor.Record(ctx, action, name, before, after)
+	}
+}
+
+func tagPayload(name string) map[string]string {
+	return map[string]string{"name": name}
+}
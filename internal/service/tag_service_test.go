@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRenameTag_NormalizesNames(t *testing.T) {
+	mockTagRepo := new(mocks.MockTagRepository)
+	service := NewTagService(mockTagRepo, nil)
+	ctx := context.Background()
+
+	mockTagRepo.On("Rename", ctx, "promo", "black friday").Return(nil).Once()
+
+	err := service.RenameTag(ctx, "Promo", " Black Friday ")
+
+	assert.NoError(t, err)
+	mockTagRepo.AssertExpectations(t)
+}
+
+func TestRenameTag_AlreadyExists(t *testing.T) {
+	mockTagRepo := new(mocks.MockTagRepository)
+	service := NewTagService(mockTagRepo, nil)
+	ctx := context.Background()
+
+	mockTagRepo.On("Rename", ctx, "promo", "sale").Return(domain.ErrTagExists).Once()
+
+	err := service.RenameTag(ctx, "promo", "sale")
+
+	assert.ErrorIs(t, err, domain.ErrTagExists)
+}
+
+func TestMergeTags_IntoItself(t *testing.T) {
+	mockTagRepo := new(mocks.MockTagRepository)
+	service := NewTagService(mockTagRepo, nil)
+
+	err := service.MergeTags(context.Background(), "Promo", "promo")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTag)
+	mockTagRepo.AssertNotCalled(t, "Merge")
+}
+
+func TestDeleteTag_Audited(t *testing.T) {
+	mockTagRepo := new(mocks.MockTagRepository)
+	mockAuditor := new(mocks.MockAuditor)
+	service := NewTagService(mockTagRepo, mockAuditor)
+	ctx := context.Background()
+
+	mockTagRepo.On("Delete", ctx, "promo").Return(nil).Once()
+	mockAuditor.On("Record", ctx, domain.AuditTagDelete, "promo", map[string]string{"name": "promo"}, nil).Once()
+
+	err := service.DeleteTag(ctx, " Promo ")
+
+	assert.NoError(t, err)
+	mockAuditor.AssertExpectations(t)
+}
+
+func TestRenameTag_FailureNotAudited(t *testing.T) {
+	mockTagRepo := new(mocks.MockTagRepository)
+	mockAuditor := new(mocks.MockAuditor)
+	service := NewTagService(mockTagRepo, mockAuditor)
+	ctx := context.Background()
+
+	mockTagRepo.On("Rename", ctx, "promo", "sale").Return(domain.ErrTagNotFound).Once()
+
+	err := service.RenameTag(ctx, "promo", "sale")
+
+	assert.ErrorIs(t, err, domain.ErrTagNotFound)
+	mockAuditor.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
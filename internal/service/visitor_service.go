@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+)
+
+const (
+	dayLayout        = "2006-01-02"
+	sketchFlushBatch = 500
+)
+
+type SketchCache interface {
+	Add(ctx context.Context, urlID int64, day, visitor string) error
+	Count(ctx context.Context, urlID int64, days []string) (int64, error)
+	CountEach(ctx context.Context, urlID int64, days []string) ([]int64, error)
+	Missing(ctx context.Context, urlID int64, days []string) ([]string, error)
+	Get(ctx context.Context, urlID int64, day string) ([]byte, error)
+	Merge(ctx context.Context, sketch *domain.VisitorSketch) error
+	MarkDirty(ctx context.Context, urlID int64, day string) error
+	PopDirty(ctx context.Context, count int64) ([]domain.VisitorSketch, error)
+}
+
+type SketchRepository interface {
+	SaveSketch(ctx context.Context, sketch *domain.VisitorSketch) error
+	GetSketches(ctx context.Context, urlID int64, days []string) ([]domain.VisitorSketch, error)
+}
+
+// VisitorService counts unique visitors with HyperLogLog sketches kept in
+// Redis and periodically persisted to Postgres, so ranges older than the
+// cache TTL can still be answered by merging the stored sketches.
+type VisitorService struct {
+	cache SketchCache
+	repo  SketchRepository
+}
+
+func NewVisitorService(cache SketchCache, repo SketchRepository) *VisitorService {
+	return &VisitorService{
+		cache: cache,
+		repo:  repo,
+	}
+}
+
+func (s *VisitorService) Track(ctx context.Context, urlID int64, visitor string, at time.Time) error {
+	return s.cache.Add(ctx, urlID, at.UTC().Format(dayLayout), visitor)
+}
+
+func (s *VisitorService) CountTotal(ctx context.Context, urlID int64) (int64, error) {
+	days := []string{domain.AllTimeSketch}
+	if err := s.restore(ctx, urlID, days); err != nil {
+		return 0, err
+	}
+
+	return s.cache.Count(ctx, urlID, days)
+}
+
+func (s *VisitorService) CountRange(ctx context.Context, urlID int64, from, to time.Time) (int64, error) {
+	days := daysBetween(from, to)
+	if err := s.restore(ctx, urlID, days); err != nil {
+		return 0, err
+	}
+
+	return s.cache.Count(ctx, urlID, days)
+}
+
+func (s *VisitorService) CountByDay(ctx context.Context, urlID int64, days []string) (map[string]int64, error) {
+	if err := s.restore(ctx, urlID, days); err != nil {
+		return nil, err
+	}
+
+	counts, err := s.cache.CountEach(ctx, urlID, days)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(days))
+	for i, day := range days {
+		result[day] = counts[i]
+	}
+
+	return result, nil
+}
+
+// restore loads the persisted sketches of days that are no longer cached.
+func (s *VisitorService) restore(ctx context.Context, urlID int64, days []string) error {
+	if len(days) == 0 {
+		return nil
+	}
+
+	missing, err := s.cache.Missing(ctx, urlID, days)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	sketches, err := s.repo.GetSketches(ctx, urlID, missing)
+	if err != nil {
+		return err
+	}
+
+	for i := range sketches {
+		if err := s.cache.Merge(ctx, &sketches[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush persists every sketch that changed since the last flush and returns
+// how many were written.
+func (s *VisitorService) Flush(ctx context.Context) (int, error) {
+	flushed := 0
+	for {
+		dirty, err := s.cache.PopDirty(ctx, sketchFlushBatch)
+		if err != nil {
+			return flushed, err
+		}
+		if len(dirty) == 0 {
+			return flushed, nil
+		}
+
+		for i := range dirty {
+			if err := s.flushSketch(ctx, &dirty[i]); err != nil {
+				_ = s.cache.MarkDirty(ctx, dirty[i].URLID, dirty[i].Day)
+				return flushed, err
+			}
+			flushed++
+		}
+	}
+}
+
+// flushSketch merges the stored sketch into the cached one before saving it,
+// so a key that expired from Redis and started over never overwrites the
+// history kept in Postgres.
+func (s *VisitorService) flushSketch(ctx context.Context, sketch *domain.VisitorSketch) error {
+	stored, err := s.repo.GetSketches(ctx, sketch.URLID, []string{sketch.Day})
+	if err != nil {
+		return err
+	}
+
+	for i := range stored {
+		if err := s.cache.Merge(ctx, &stored[i]); err != nil {
+			return err
+		}
+	}
+
+	data, err := s.cache.Get(ctx, sketch.URLID, sketch.Day)
+	if err != nil || data == nil {
+		return err
+	}
+	sketch.Data = data
+
+	return s.repo.SaveSketch(ctx, sketch)
+}
+
+// Run flushes sketches every interval until ctx is cancelled.
+func (s *VisitorService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log := logger.Get()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Flush(ctx); err != nil {
+				log.Error("Failed to flush visitor sketches", "error", err)
+			}
+		}
+	}
+}
+
+func daysBetween(from, to time.Time) []string {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC()
+
+	var days []string
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(dayLayout))
+	}
+
+	return days
+}
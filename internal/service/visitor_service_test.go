@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVisitorService_Track_UsesUTCDay(t *testing.T) {
+	mockCache := new(mocks.MockSketchCache)
+	mockRepo := new(mocks.MockSketchRepository)
+	service := NewVisitorService(mockCache, mockRepo)
+	ctx := context.Background()
+
+	at := time.Date(2025, 12, 26, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+
+	mockCache.On("Add", ctx, int64(1), "2025-12-27", "10.0.0.1").Return(nil).Once()
+
+	err := service.Track(ctx, 1, "10.0.0.1", at)
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+}
+
+func TestVisitorService_CountRange_RestoresMissingDays(t *testing.T) {
+	mockCache := new(mocks.MockSketchCache)
+	mockRepo := new(mocks.MockSketchRepository)
+	service := NewVisitorService(mockCache, mockRepo)
+	ctx := context.Background()
+
+	from := time.Date(2025, 12, 24, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 26, 10, 0, 0, 0, time.UTC)
+	days := []string{"2025-12-24", "2025-12-25", "2025-12-26"}
+	stored := []domain.VisitorSketch{{URLID: 1, Day: "2025-12-24", Data: []byte("HYLL")}}
+
+	mockCache.On("Missing", ctx, int64(1), days).Return([]string{"2025-12-24"}, nil).Once()
+	mockRepo.On("GetSketches", ctx, int64(1), []string{"2025-12-24"}).Return(stored, nil).Once()
+	mockCache.On("Merge", ctx, &stored[0]).Return(nil).Once()
+	mockCache.On("Count", ctx, int64(1), days).Return(int64(42), nil).Once()
+
+	count, err := service.CountRange(ctx, 1, from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), count)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVisitorService_CountByDay(t *testing.T) {
+	mockCache := new(mocks.MockSketchCache)
+	mockRepo := new(mocks.MockSketchRepository)
+	service := NewVisitorService(mockCache, mockRepo)
+	ctx := context.Background()
+
+	days := []string{"2025-12-26", "2025-12-25"}
+
+	mockCache.On("Missing", ctx, int64(1), days).Return(nil, nil).Once()
+	mockCache.On("CountEach", ctx, int64(1), days).Return([]int64{7, 3}, nil).Once()
+
+	counts, err := service.CountByDay(ctx, 1, days)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"2025-12-26": 7, "2025-12-25": 3}, counts)
+	mockRepo.AssertNotCalled(t, "GetSketches")
+}
+
+func TestVisitorService_Flush_MergesStoredSketchBeforeSaving(t *testing.T) {
+	mockCache := new(mocks.MockSketchCache)
+	mockRepo := new(mocks.MockSketchRepository)
+	service := NewVisitorService(mockCache, mockRepo)
+	ctx := context.Background()
+
+	stored := []domain.VisitorSketch{{URLID: 1, Day: domain.AllTimeSketch, Data: []byte("old")}}
+
+	mockCache.On("PopDirty", ctx, int64(sketchFlushBatch)).
+		Return([]domain.VisitorSketch{{URLID: 1, Day: domain.AllTimeSketch}}, nil).Once()
+	mockCache.On("PopDirty", ctx, int64(sketchFlushBatch)).
+		Return([]domain.VisitorSketch{}, nil).Once()
+	mockRepo.On("GetSketches", ctx, int64(1), []string{domain.AllTimeSketch}).Return(stored, nil).Once()
+	mockCache.On("Merge", ctx, &stored[0]).Return(nil).Once()
+	mockCache.On("Get", ctx, int64(1), domain.AllTimeSketch).Return([]byte("merged"), nil).Once()
+	mockRepo.On("SaveSketch", ctx, mock.MatchedBy(func(sketch *domain.VisitorSketch) bool {
+		return sketch.URLID == 1 && string(sketch.Data) == "merged"
+	})).Return(nil).Once()
+
+	flushed, err := service.Flush(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, flushed)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVisitorService_Flush_RequeuesOnFailure(t *testing.T) {
+	mockCache := new(mocks.MockSketchCache)
+	mockRepo := new(mocks.MockSketchRepository)
+	service := NewVisitorService(mockCache, mockRepo)
+	ctx := context.Background()
+
+	mockCache.On("PopDirty", ctx, int64(sketchFlushBatch)).
+		Return([]domain.VisitorSketch{{URLID: 1, Day: "2025-12-26"}}, nil).Once()
+	mockRepo.On("GetSketches", ctx, int64(1), []string{"2025-12-26"}).
+		Return(nil, errors.New("connection refused")).Once()
+	mockCache.On("MarkDirty", ctx, int64(1), "2025-12-26").Return(nil).Once()
+
+	flushed, err := service.Flush(ctx)
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, flushed)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SaveSketch")
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/logger"
+	"github.com/gamassss/url-shortener/pkg/webhook"
+)
+
+// maxDeliveryError is how much of a failed delivery's error is kept.
+const maxDeliveryError = 1000
+
+type WebhookRepository interface {
+	Create(ctx context.Context, hook *domain.Webhook) error
+	List(ctx context.Context) ([]domain.Webhook, error)
+	Get(ctx context.Context, id int64) (*domain.Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	Enqueue(ctx context.Context, event *domain.WebhookEvent, webhookIDs []int64) error
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.ClaimedDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, responseStatus int) error
+	MarkFailed(ctx context.Context, id int64, responseStatus int, lastError string, retryAt *time.Time) error
+	Retry(ctx context.Context, webhookID, deliveryID int64) error
+	ListDeliveries(ctx context.Context, webhookID int64, status string, page, pageSize int) (*domain.DeliveryLog, error)
+	ClaimExpiredLinks(ctx context.Context, limit int) ([]domain.URL, error)
+}
+
+type WebhookSender interface {
+	Send(ctx context.Context, req *webhook.Request) (int, error)
+}
+
+// RetryPolicy spaces out attempts at a failing delivery: the delay doubles
+// after every attempt from BaseDelay up to MaxDelay, and the delivery is
+// given up on after MaxAttempts.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Next returns how long to wait after a delivery's attempts-th failed attempt,
+// and false once it has run out of attempts.
+func (p RetryPolicy) Next(attempts int) (time.Duration, bool) {
+	if attempts >= p.MaxAttempts {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay), true
+}
+
+// WebhookService notifies webhook subscribers of link events. Events are
+// queued in the database and delivered by Run, so the requests they come from
+// never wait on a subscriber.
+type WebhookService struct {
+	repo      WebhookRepository
+	sender    WebhookSender
+	auditor   Auditor
+	retry     RetryPolicy
+	batchSize int
+	lease     time.Duration
+
+	// hooks is the subscription list, kept in memory so clicks can be
+	// checked against it without a query.
+	hooks atomic.Pointer[[]domain.Webhook]
+}
+
+// NewWebhookService creates the service. Run delivers up to batchSize events
+// at a time, and a claimed delivery isn't retried by another worker until
+// lease has passed, so lease should exceed the sender's timeout. auditor may
+// be nil, in which case changes to webhooks aren't audited.
+func NewWebhookService(repo WebhookRepository, sender WebhookSender, auditor Auditor, retry RetryPolicy, batchSize int, lease time.Duration) *WebhookService {
+	return &WebhookService{
+		repo:      repo,
+		sender:    sender,
+		auditor:   auditor,
+		retry:     retry,
+		batchSize: batchSize,
+		lease:     lease,
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *domain.CreateWebhookRequest) (*domain.Webhook, error) {
+	hook := &domain.Webhook{
+		URL:             req.URL,
+		Secret:          req.Secret,
+		Events:          uniqueStrings(req.Events),
+		ClickMilestones: req.ClickMilestones,
+	}
+	if hook.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		hook.Secret = secret
+	}
+	if len(hook.ClickMilestones) == 0 {
+		hook.ClickMilestones = []int64{1}
+	}
+
+	if err := s.repo.Create(ctx, hook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	s.refresh(ctx)
+
+	if s.auditor != nil {
+		after := *hook
+		after.Secret = ""
+		s.auditor.Record(ctx, domain.AuditWebhookCreate, strconv.FormatInt(hook.ID, 10), nil, after)
+	}
+	return hook, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	hooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id int64) error {
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+
+	if s.auditor != nil {
+		s.auditor.Record(ctx, domain.AuditWebhookDelete, strconv.FormatInt(id, 10), before, nil)
+	}
+	return nil
+}
+
+// ListDeliveries returns a page of a webhook's delivery log, optionally only
+// the deliveries with the given status.
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID int64, status string, page, pageSize int) (*domain.DeliveryLog, error) {
+	if _, err := s.repo.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, webhookID, status, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery queues a dead delivery again.
+func (s *WebhookService) RetryDelivery(ctx context.Context, webhookID, deliveryID int64) error {
+	return s.repo.Retry(ctx, webhookID, deliveryID)
+}
+
+// LinkCreated queues link.created for its subscribers.
+func (s *WebhookService) LinkCreated(ctx context.Context, url *domain.URL) {
+	s.emit(ctx, domain.EventLinkCreated, fmt.Sprintf("%s:%d", domain.EventLinkCreated, url.ID), s.subscribers(domain.EventLinkCreated, 0), domain.LinkEventData{
+		ShortCode:   url.ShortCode,
+		OriginalURL: url.OriginalURL,
+		ExpiresAt:   url.ExpiresAt,
+	})
+}
+
+// LinkClicked queues link.click_milestone for the subscribers with a
+// milestone at totalClicks. Most clicks match no milestone and cost nothing
+// beyond a look at the in-memory subscription list.
+func (s *WebhookService) LinkClicked(ctx context.Context, urlID int64, shortCode string, totalClicks int64) {
+	hooks := s.subscribers(domain.EventLinkClickMilestone, totalClicks)
+	if len(hooks) == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("%s:%d:%d", domain.EventLinkClickMilestone, urlID, totalClicks)
+	s.emit(ctx, domain.EventLinkClickMilestone, key, hooks, domain.LinkEventData{
+		ShortCode: shortCode,
+		Clicks:    totalClicks,
+	})
+}
+
+// Run delivers queued events every interval until ctx is cancelled. Each round
+// also reloads the subscription list and queues link.expired for links that
+// have expired since the last round.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log := logger.Get()
+	s.refresh(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+			if err := s.sweepExpired(ctx); err != nil {
+				log.Error("Failed to queue link expiry events", "error", err)
+			}
+			if _, err := s.Dispatch(ctx); err != nil {
+				log.Error("Failed to dispatch webhooks", "error", err)
+			}
+		}
+	}
+}
+
+// Dispatch sends one batch of due deliveries concurrently and returns how
+// many it attempted.
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.ClaimDeliveries(ctx, s.batchSize, s.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.deliver(ctx, d)
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (s *WebhookService) deliver(ctx context.Context, d domain.ClaimedDelivery) {
+	log := logger.Get()
+
+	status, err := s.sender.Send(ctx, &webhook.Request{
+		URL:        d.URL,
+		Secret:     d.Secret,
+		Event:      d.EventType,
+		DeliveryID: strconv.FormatInt(d.ID, 10),
+		Body:       d.Payload,
+	})
+	if err == nil {
+		if err := s.repo.MarkDelivered(ctx, d.ID, status); err != nil {
+			log.Error("Failed to mark webhook delivered", "delivery_id", d.ID, "error", err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if delay, ok := s.retry.Next(d.Attempts); ok {
+		at := time.Now().Add(delay)
+		retryAt = &at
+	}
+
+	log.Warn("Webhook delivery failed",
+		"delivery_id", d.ID,
+		"webhook_id", d.WebhookID,
+		"attempt", d.Attempts,
+		"dead", retryAt == nil,
+		"error", err,
+	)
+
+	if err := s.repo.MarkFailed(ctx, d.ID, status, truncateError(err), retryAt); err != nil {
+		log.Error("Failed to record webhook failure", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// sweepExpired queues link.expired for newly expired links. Links are claimed
+// even without subscribers, so a later subscriber isn't sent old expiries.
+func (s *WebhookService) sweepExpired(ctx context.Context) error {
+	for {
+		urls, err := s.repo.ClaimExpiredLinks(ctx, s.batchSize)
+		if err != nil {
+			return err
+		}
+
+		hooks := s.subscribers(domain.EventLinkExpired, 0)
+		for _, url := range urls {
+			key := fmt.Sprintf("%s:%d:%d", domain.EventLinkExpired, url.ID, url.ExpiresAt.Unix())
+			s.emit(ctx, domain.EventLinkExpired, key, hooks, domain.LinkEventData{
+				ShortCode:   url.ShortCode,
+				OriginalURL: url.OriginalURL,
+				ExpiresAt:   url.ExpiresAt,
+			})
+		}
+
+		if len(urls) < s.batchSize {
+			return nil
+		}
+	}
+}
+
+// emit queues an event for hooks. Events are side effects of changes that
+// have already happened, so failures are logged rather than returned.
+func (s *WebhookService) emit(ctx context.Context, eventType, key string, hooks []int64, data domain.LinkEventData) {
+	if len(hooks) == 0 {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	event, err := newWebhookEvent(eventType, key, data)
+	if err != nil {
+		log.Error("Failed to encode webhook event", "event", eventType, "error", err)
+		return
+	}
+
+	if err := s.repo.Enqueue(context.WithoutCancel(ctx), event, hooks); err != nil {
+		log.Error("Failed to queue webhook event", "event", eventType, "short_code", data.ShortCode, "error", err)
+	}
+}
+
+// subscribers returns the webhooks subscribed to eventType. For
+// link.click_milestone only those with a milestone at clicks are returned.
+func (s *WebhookService) subscribers(eventType string, clicks int64) []int64 {
+	hooks := s.hooks.Load()
+	if hooks == nil {
+		return nil
+	}
+
+	var ids []int64
+	for _, hook := range *hooks {
+		if !hook.Subscribes(eventType) {
+			continue
+		}
+		if eventType == domain.EventLinkClickMilestone && !hook.HasMilestone(clicks) {
+			continue
+		}
+		ids = append(ids, hook.ID)
+	}
+	return ids
+}
+
+// refresh reloads the subscription list, keeping the old one if that fails.
+func (s *WebhookService) refresh(ctx context.Context) {
+	hooks, err := s.repo.List(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to load webhooks", "error", err)
+		return
+	}
+	s.hooks.Store(&hooks)
+}
+
+func newWebhookEvent(eventType, key string, data domain.LinkEventData) (*domain.WebhookEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WebhookEvent{
+		ID:         "evt_" + hex.EncodeToString(id),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+		Key:        key,
+	}, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > maxDeliveryError {
+		msg = strings.ToValidUTF8(msg[:maxDeliveryError], "")
+	}
+	return msg
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/webhook"
+	"github.com/gamassss/url-shortener/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 30 * time.Second, MaxDelay: 90 * time.Second}
+
+func TestRetryPolicy_Next(t *testing.T) {
+	delays := []time.Duration{30 * time.Second, 60 * time.Second, 90 * time.Second}
+	for i, want := range delays {
+		delay, ok := testRetryPolicy.Next(i + 1)
+		assert.True(t, ok)
+		assert.Equal(t, want, delay, "attempt %d", i+1)
+	}
+
+	_, ok := testRetryPolicy.Next(4)
+	assert.False(t, ok)
+}
+
+func TestCreateWebhook_GeneratesSecret(t *testing.T) {
+	mockRepo := new(mocks.MockWebhookRepository)
+	service := NewWebhookService(mockRepo, nil, nil, testRetryPolicy, 10, time.Minute)
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(hook *domain.Webhook) bool {
+		return strings.HasPrefix(hook.Secret, "whsec_") &&
+			assert.ObjectsAreEqual([]string{domain.EventLinkCreated}, hook.Events) &&
+			assert.ObjectsAreEqual([]int64{1}, hook.ClickMilestones)
+	})).Return(nil).Once()
+	mockRepo.On("List", ctx).Return([]domain.Webhook{}, nil).Once()
+
+	hook, err := service.CreateWebhook(ctx, &domain.CreateWebhookRequest{
+		URL:    "https://hooks.example.com",
+		Events: []string{domain.EventLinkCreated, domain.EventLinkCreated},
+	})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, hook.Secret)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLinkClicked_OnlyAtMilestones(t *testing.T) {
+	mockRepo := new(mocks.MockWebhookRepository)
+	service := NewWebhookService(mockRepo, nil, nil, testRetryPolicy, 10, time.Minute)
+	ctx := context.Background()
+
+	mockRepo.On("List", ctx).Return([]domain.Webhook{
+		{ID: 1, Events: []string{domain.EventLinkClickMilestone}, ClickMilestones: []int64{1, 100}},
+		{ID: 2, Events: []string{domain.EventLinkClickMilestone}, ClickMilestones: []int64{100}},
+		{ID: 3, Events: []string{domain.EventLinkCreated}, ClickMilestones: []int64{1}},
+	}, nil).Once()
+	service.refresh(ctx)
+
+	mockRepo.On("Enqueue", mock.Anything, mock.MatchedBy(func(event *domain.WebhookEvent) bool {
+		return event.Type == domain.EventLinkClickMilestone &&
+			event.Key == "link.click_milestone:7:100" &&
+			strings.Contains(string(event.Data), `"clicks":100`)
+	}), []int64{1, 2}).Return(nil).Once()
+
+	service.LinkClicked(ctx, 7, "abc1234", 99)
+	service.LinkClicked(ctx, 7, "abc1234", 100)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDispatch_RetriesThenGivesUp(t *testing.T) {
+	mockRepo := new(mocks.MockWebhookRepository)
+	mockSender := new(mocks.MockWebhookSender)
+	service := NewWebhookService(mockRepo, mockSender, nil, testRetryPolicy, 10, time.Minute)
+	ctx := context.Background()
+
+	mockRepo.On("ClaimDeliveries", ctx, 10, time.Minute).Return([]domain.ClaimedDelivery{
+		{ID: 1, URL: "https://a.example.com", Secret: "s", EventType: domain.EventLinkCreated, Payload: []byte(`{}`), Attempts: 1},
+		{ID: 2, URL: "https://b.example.com", Secret: "s", EventType: domain.EventLinkCreated, Payload: []byte(`{}`), Attempts: 1},
+		{ID: 3, URL: "https://c.example.com", Secret: "s", EventType: domain.EventLinkCreated, Payload: []byte(`{}`), Attempts: 4},
+	}, nil).Once()
+
+	mockSender.On("Send", ctx, mock.MatchedBy(func(req *webhook.Request) bool { return req.DeliveryID == "1" })).
+		Return(200, nil).Once()
+	mockSender.On("Send", ctx, mock.MatchedBy(func(req *webhook.Request) bool { return req.DeliveryID == "2" })).
+		Return(503, errors.New("webhook: subscriber responded 503")).Once()
+	mockSender.On("Send", ctx, mock.MatchedBy(func(req *webhook.Request) bool { return req.DeliveryID == "3" })).
+		Return(0, errors.New("connection refused")).Once()
+
+	mockRepo.On("MarkDelivered", ctx, int64(1), 200).Return(nil).Once()
+	mockRepo.On("MarkFailed", ctx, int64(2), 503, "webhook: subscriber responded 503", mock.MatchedBy(func(at *time.Time) bool {
+		return at != nil && time.Until(*at) > 25*time.Second && time.Until(*at) <= 30*time.Second
+	})).Return(nil).Once()
+	mockRepo.On("MarkFailed", ctx, int64(3), 0, "connection refused", (*time.Time)(nil)).Return(nil).Once()
+
+	n, err := service.Dispatch(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	mockRepo.AssertExpectations(t)
+	mockSender.AssertExpectations(t)
+}
+
+func TestDeleteWebhook_NotFound(t *testing.T) {
+	mockRepo := new(mocks.MockWebhookRepository)
+	service := NewWebhookService(mockRepo, nil, nil, testRetryPolicy, 10, time.Minute)
+	ctx := context.Background()
+
+	mockRepo.On("Get", ctx, int64(9)).Return(nil, domain.ErrWebhookNotFound).Once()
+
+	err := service.DeleteWebhook(ctx, 9)
+
+	assert.ErrorIs(t, err, domain.ErrWebhookNotFound)
+	mockRepo.AssertNotCalled(t, "Delete")
+}
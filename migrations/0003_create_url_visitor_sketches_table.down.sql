@@ -0,0 +1 @@
+DROP TABLE IF EXISTS url_visitor_sketches;
\ No newline at end of file
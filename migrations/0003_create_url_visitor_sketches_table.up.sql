@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS url_visitor_sketches (
+    url_id     BIGINT    NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    day        DATE,
+    sketch     BYTEA     NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_url_visitor_sketches_url_id_day UNIQUE NULLS NOT DISTINCT (url_id, day)
+);
\ No newline at end of file
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_url_clicks_url_id_os;
+DROP INDEX IF EXISTS idx_url_clicks_url_id_browser;
+
+ALTER TABLE url_clicks
+    DROP COLUMN IF EXISTS os_version,
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS browser_version,
+    DROP COLUMN IF EXISTS browser;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE url_clicks
+    ADD COLUMN IF NOT EXISTS browser         VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS browser_version VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS os              VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS os_version      VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_browser ON url_clicks(url_id, browser);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_os ON url_clicks(url_id, os);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_clicks_url_id_country_code;
+
+ALTER TABLE url_clicks
+    DROP COLUMN IF EXISTS city,
+    DROP COLUMN IF EXISTS region;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE url_clicks
+    ADD COLUMN IF NOT EXISTS region VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS city   VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_country_code ON url_clicks(url_id, country_code);
\ No newline at end of file
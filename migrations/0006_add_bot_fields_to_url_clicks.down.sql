@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS trigger_update_click_count ON url_clicks;
+
+CREATE TRIGGER trigger_update_click_count
+    AFTER INSERT ON url_clicks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_url_click_count();
+
+DROP INDEX IF EXISTS idx_url_clicks_url_id_is_bot;
+
+ALTER TABLE url_clicks
+    DROP COLUMN IF EXISTS bot_reason,
+    DROP COLUMN IF EXISTS is_bot;
\ No newline at end of file
@@ -0,0 +1,13 @@
+ALTER TABLE url_clicks
+    ADD COLUMN IF NOT EXISTS is_bot     BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS bot_reason VARCHAR(20);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_is_bot ON url_clicks(url_id, is_bot);
+
+DROP TRIGGER IF EXISTS trigger_update_click_count ON url_clicks;
+
+CREATE TRIGGER trigger_update_click_count
+    AFTER INSERT ON url_clicks
+    FOR EACH ROW
+    WHEN (NOT NEW.is_bot)
+    EXECUTE FUNCTION update_url_click_count();
\ No newline at end of file
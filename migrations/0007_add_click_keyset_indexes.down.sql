@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_clicks_clicked_at_id;
+DROP INDEX IF EXISTS idx_url_clicks_url_id_clicked_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_clicked_at ON url_clicks(clicked_at);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_clicked_at ON url_clicks(url_id, clicked_at DESC);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_clicks_url_id_clicked_at;
+DROP INDEX IF EXISTS idx_url_clicks_clicked_at;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_clicked_at_id ON url_clicks(url_id, clicked_at, id);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_clicked_at_id ON url_clicks(clicked_at, id);
\ No newline at end of file
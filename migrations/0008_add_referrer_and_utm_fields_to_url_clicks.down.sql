@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_url_clicks_url_id_utm_campaign;
+DROP INDEX IF EXISTS idx_url_clicks_url_id_referrer_host;
+
+ALTER TABLE url_clicks
+    DROP COLUMN IF EXISTS utm_content,
+    DROP COLUMN IF EXISTS utm_term,
+    DROP COLUMN IF EXISTS utm_campaign,
+    DROP COLUMN IF EXISTS utm_medium,
+    DROP COLUMN IF EXISTS utm_source,
+    DROP COLUMN IF EXISTS referrer_source,
+    DROP COLUMN IF EXISTS referrer_host;
\ No newline at end of file
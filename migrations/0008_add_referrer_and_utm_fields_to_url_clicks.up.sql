@@ -0,0 +1,15 @@
+ALTER TABLE url_clicks
+    ADD COLUMN IF NOT EXISTS referrer_host   VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS referrer_source VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS utm_source      VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS utm_medium      VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS utm_campaign    VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS utm_term        VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS utm_content     VARCHAR(255);
+
+UPDATE url_clicks
+SET referrer_host = NULLIF(regexp_replace(lower(substring(referer from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^/?#@]*@)?([^/?#:]+)')), '^www\.', ''), '')
+WHERE referer IS NOT NULL AND referer <> '';
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_referrer_host ON url_clicks(url_id, referrer_host);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_url_id_utm_campaign ON url_clicks(url_id, utm_campaign) WHERE utm_campaign IS NOT NULL;
\ No newline at end of file
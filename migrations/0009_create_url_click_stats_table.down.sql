@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trigger_update_click_stats ON url_clicks;
+
+DROP FUNCTION IF EXISTS update_url_click_stats();
+
+DROP TABLE IF EXISTS url_click_stats;
\ No newline at end of file
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS url_click_stats (
+    url_id     BIGINT    NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    hour       TIMESTAMP NOT NULL,
+    clicks     BIGINT    NOT NULL DEFAULT 0,
+    bot_clicks BIGINT    NOT NULL DEFAULT 0,
+
+    CONSTRAINT pk_url_click_stats PRIMARY KEY (url_id, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_click_stats_hour ON url_click_stats(hour) INCLUDE (url_id, clicks, bot_clicks);
+
+INSERT INTO url_click_stats (url_id, hour, clicks, bot_clicks)
+SELECT
+    url_id,
+    date_trunc('hour', clicked_at),
+    COUNT(*) FILTER (WHERE NOT is_bot),
+    COUNT(*) FILTER (WHERE is_bot)
+FROM url_clicks
+GROUP BY 1, 2
+ON CONFLICT (url_id, hour) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION update_url_click_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO url_click_stats (url_id, hour, clicks, bot_clicks)
+    VALUES (
+        NEW.url_id,
+        date_trunc('hour', NEW.clicked_at),
+        CASE WHEN NEW.is_bot THEN 0 ELSE 1 END,
+        CASE WHEN NEW.is_bot THEN 1 ELSE 0 END
+    )
+    ON CONFLICT (url_id, hour) DO UPDATE
+    SET clicks     = url_click_stats.clicks + EXCLUDED.clicks,
+        bot_clicks = url_click_stats.bot_clicks + EXCLUDED.bot_clicks;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_update_click_stats
+    AFTER INSERT ON url_clicks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_url_click_stats();
\ No newline at end of file
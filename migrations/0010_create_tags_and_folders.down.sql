@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS url_tags;
+
+DROP TABLE IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_urls_folder;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS folder;
\ No newline at end of file
@@ -0,0 +1,18 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS folder VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_urls_folder ON urls(folder) WHERE folder IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         BIGSERIAL   PRIMARY KEY,
+    name       VARCHAR(50) UNIQUE NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS url_tags (
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+
+    CONSTRAINT pk_url_tags PRIMARY KEY (url_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag_id ON url_tags(tag_id, url_id);
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS preview_fetched_at,
+    DROP COLUMN IF EXISTS preview_favicon_url,
+    DROP COLUMN IF EXISTS preview_og_image,
+    DROP COLUMN IF EXISTS preview_og_description,
+    DROP COLUMN IF EXISTS preview_og_title,
+    DROP COLUMN IF EXISTS preview_page_title,
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS title;
\ No newline at end of file
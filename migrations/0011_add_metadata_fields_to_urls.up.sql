@@ -0,0 +1,9 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS title                  VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS notes                  TEXT,
+    ADD COLUMN IF NOT EXISTS preview_page_title     TEXT,
+    ADD COLUMN IF NOT EXISTS preview_og_title       TEXT,
+    ADD COLUMN IF NOT EXISTS preview_og_description TEXT,
+    ADD COLUMN IF NOT EXISTS preview_og_image       TEXT,
+    ADD COLUMN IF NOT EXISTS preview_favicon_url    TEXT,
+    ADD COLUMN IF NOT EXISTS preview_fetched_at     TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS og_title       VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS og_description TEXT,
+    ADD COLUMN IF NOT EXISTS og_image       TEXT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE url_clicks
+    DROP COLUMN IF EXISTS url_version;
+
+DROP TRIGGER IF EXISTS trigger_prevent_url_version_update ON url_versions;
+DROP FUNCTION IF EXISTS prevent_url_version_update();
+DROP TABLE IF EXISTS url_versions;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS version;
\ No newline at end of file
@@ -0,0 +1,37 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS url_versions (
+    id               BIGSERIAL    PRIMARY KEY,
+    url_id           BIGINT       NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    version          INT          NOT NULL,
+    original_url     TEXT         NOT NULL,
+    expires_at       TIMESTAMP,
+    is_active        BOOLEAN      NOT NULL,
+    change_type      VARCHAR(20)  NOT NULL,
+    restored_version INT,
+    changed_by       VARCHAR(100),
+    created_at       TIMESTAMP    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_url_versions_url_id_version UNIQUE (url_id, version)
+);
+
+INSERT INTO url_versions (url_id, version, original_url, expires_at, is_active, change_type, created_at)
+SELECT id, 1, original_url, expires_at, is_active, 'create', created_at
+FROM urls
+ON CONFLICT (url_id, version) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION prevent_url_version_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'url_versions rows are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_prevent_url_version_update
+    BEFORE UPDATE ON url_versions
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_url_version_update();
+
+ALTER TABLE url_clicks
+    ADD COLUMN IF NOT EXISTS url_version INT NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trigger_prevent_audit_event_change ON audit_events;
+
+DROP FUNCTION IF EXISTS prevent_audit_event_change();
+
+DROP TABLE IF EXISTS audit_events;
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          BIGSERIAL    PRIMARY KEY,
+    occurred_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    actor       VARCHAR(100),
+    action      VARCHAR(50)  NOT NULL,
+    target      VARCHAR(100) NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    client_ip   VARCHAR(45),
+    request_id  VARCHAR(64)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events(occurred_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor, occurred_at DESC) WHERE actor IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION prevent_audit_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events rows are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_prevent_audit_event_change
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_audit_event_change();
\ No newline at end of file
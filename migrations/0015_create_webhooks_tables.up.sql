@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id               BIGSERIAL     PRIMARY KEY,
+    url              VARCHAR(2048) NOT NULL,
+    secret           VARCHAR(255)  NOT NULL,
+    events           TEXT[]        NOT NULL,
+    click_milestones BIGINT[]      NOT NULL DEFAULT '{1}',
+    created_at       TIMESTAMP     NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL    PRIMARY KEY,
+    webhook_id      BIGINT       NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id        VARCHAR(64)  NOT NULL,
+    event_type      VARCHAR(50)  NOT NULL,
+    event_key       VARCHAR(255) NOT NULL,
+    payload         JSONB        NOT NULL,
+    status          VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    attempts        INT          NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    response_status INT,
+    created_at      TIMESTAMP    NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMP,
+    UNIQUE (webhook_id, event_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC, id DESC);
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP;
+
+UPDATE urls SET expiry_notified_at = expires_at WHERE expires_at <= NOW();
+
+CREATE INDEX IF NOT EXISTS idx_urls_expiry_pending ON urls(expires_at) WHERE expiry_notified_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS owner VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_urls_owner ON urls(owner) WHERE owner IS NOT NULL;
@@ -0,0 +1,26 @@
+CREATE OR REPLACE FUNCTION update_url_click_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO url_click_stats (url_id, hour, clicks, bot_clicks)
+    VALUES (
+        NEW.url_id,
+        date_trunc('hour', NEW.clicked_at),
+        CASE WHEN NEW.is_bot THEN 0 ELSE 1 END,
+        CASE WHEN NEW.is_bot THEN 1 ELSE 0 END
+    )
+    ON CONFLICT (url_id, hour) DO UPDATE
+    SET clicks     = url_click_stats.clicks + EXCLUDED.clicks,
+        bot_clicks = url_click_stats.bot_clicks + EXCLUDED.bot_clicks;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+INSERT INTO url_click_stats (url_id, hour, clicks, bot_clicks)
+SELECT url_id, hour, SUM(clicks), SUM(bot_clicks)
+FROM url_click_deltas
+GROUP BY url_id, hour
+ON CONFLICT (url_id, hour) DO UPDATE
+SET clicks     = url_click_stats.clicks + EXCLUDED.clicks,
+    bot_clicks = url_click_stats.bot_clicks + EXCLUDED.bot_clicks;
+
+DROP TABLE IF EXISTS url_click_deltas;
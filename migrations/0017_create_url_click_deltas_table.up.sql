@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS url_click_deltas (
+    id         BIGSERIAL PRIMARY KEY,
+    url_id     BIGINT    NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    hour       TIMESTAMP NOT NULL,
+    clicks     INT       NOT NULL,
+    bot_clicks INT       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_click_deltas_hour ON url_click_deltas(hour) INCLUDE (url_id, clicks, bot_clicks);
+
+CREATE OR REPLACE FUNCTION update_url_click_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO url_click_deltas (url_id, hour, clicks, bot_clicks)
+    VALUES (
+        NEW.url_id,
+        date_trunc('hour', NEW.clicked_at),
+        CASE WHEN NEW.is_bot THEN 0 ELSE 1 END,
+        CASE WHEN NEW.is_bot THEN 1 ELSE 0 END
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION update_url_click_count()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE urls
+    SET click_count = click_count + 1,
+        updated_at = NOW()
+    WHERE id = NEW.url_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_update_click_count
+    AFTER INSERT ON url_clicks
+    FOR EACH ROW
+    WHEN (NOT NEW.is_bot)
+    EXECUTE FUNCTION update_url_click_count();
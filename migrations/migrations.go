@@ -0,0 +1,8 @@
+// Package migrations embeds the database schema migrations, so the binaries
+// can apply them without the files next to them.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
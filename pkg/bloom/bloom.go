@@ -0,0 +1,66 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// Filter is a Bloom filter of strings. Test never reports a false negative
+// for an added string, and reports a false positive for others at about the
+// rate the filter was sized for. It is safe for concurrent use.
+type Filter struct {
+	bits   []atomic.Uint64
+	m      uint64
+	hashes uint64
+}
+
+// New returns a filter sized to hold capacity strings with the given false
+// positive rate. Adding more than capacity raises the rate.
+func New(capacity uint64, falsePositiveRate float64) *Filter {
+	capacity = max(capacity, 1)
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+
+	return &Filter{
+		bits:   make([]atomic.Uint64, (m+63)/64),
+		m:      m,
+		hashes: max(k, 1),
+	}
+}
+
+func (f *Filter) Add(s string) {
+	h1, h2 := hash(s)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64].Or(1 << (bit % 64))
+	}
+}
+
+// Test reports whether s may have been added. False means it definitely
+// wasn't.
+func (f *Filter) Test(s string) bool {
+	h1, h2 := hash(s)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SizeBytes returns the memory taken by the filter's bits.
+func (f *Filter) SizeBytes() int64 {
+	return int64(len(f.bits) * 8)
+}
+
+// hash derives the two hashes combined for each probe (Kirsch-Mitzenmacher)
+// from one 64-bit FNV-1a hash.
+func hash(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	sum := h.Sum64()
+	return sum & 0xffffffff, sum>>32 | 1
+}
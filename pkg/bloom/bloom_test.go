@@ -0,0 +1,43 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_NoFalseNegatives(t *testing.T) {
+	f := New(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprintf("code%d", i))
+	}
+
+	for i := 0; i < 10000; i++ {
+		assert.True(t, f.Test(fmt.Sprintf("code%d", i)))
+	}
+}
+
+func TestFilter_FalsePositiveRate(t *testing.T) {
+	f := New(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprintf("code%d", i))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 100000; i++ {
+		if f.Test(fmt.Sprintf("missing%d", i)) {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, float64(falsePositives)/100000, 0.03)
+}
+
+func TestFilter_Empty(t *testing.T) {
+	f := New(0, 0.01)
+
+	assert.False(t, f.Test("abc1234"))
+	f.Add("abc1234")
+	assert.True(t, f.Test("abc1234"))
+}
@@ -0,0 +1,121 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// Open fails calls fast until the cooldown has passed.
+	Open
+	// HalfOpen lets a single probe through to see whether the dependency has
+	// recovered.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Breaker is a circuit breaker. It opens after threshold consecutive
+// failures, and after cooldown lets one probe through; the probe succeeding
+// closes it again and failing keeps it open for another cooldown.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(from, to State)
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+}
+
+// New creates a closed breaker. onChange may be nil; otherwise it is called
+// on every state change.
+func New(threshold int, cooldown time.Duration, onChange func(from, to State)) *Breaker {
+	return &Breaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		onChange:  onChange,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a call may go ahead. Every allowed call must be
+// followed by Success or Failure.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	allowed, from, to := b.allow()
+	b.mu.Unlock()
+
+	b.changed(from, to)
+	return allowed
+}
+
+func (b *Breaker) allow() (bool, State, State) {
+	switch b.state {
+	case Closed:
+		return true, Closed, Closed
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, Open, Open
+		}
+		b.state = HalfOpen
+		return true, Open, HalfOpen
+	}
+	// a probe is already in flight
+	return false, HalfOpen, HalfOpen
+}
+
+// Success records a call that worked.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	from := b.state
+	b.state = Closed
+	b.failures = 0
+	b.mu.Unlock()
+
+	b.changed(from, Closed)
+}
+
+// Failure records a call that failed.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	from := b.state
+	b.failures++
+	if from == HalfOpen || (from == Closed && b.failures >= b.threshold) {
+		b.state = Open
+		b.openedAt = b.now()
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.changed(from, to)
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) changed(from, to State) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
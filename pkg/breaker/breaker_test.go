@@ -0,0 +1,83 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *clock, *[]State) {
+	var changes []State
+	b := New(threshold, cooldown, func(from, to State) {
+		changes = append(changes, to)
+	})
+	c := &clock{t: time.Unix(0, 0)}
+	b.now = c.now
+	return b, c, &changes
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b, _, changes := newTestBreaker(3, time.Second)
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, Closed, b.State())
+
+	assert.True(t, b.Allow())
+	b.Failure()
+
+	assert.Equal(t, Open, b.State())
+	assert.False(t, b.Allow())
+	assert.Equal(t, []State{Open}, *changes)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b, _, _ := newTestBreaker(2, time.Second)
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_ProbeAfterCooldown(t *testing.T) {
+	b, c, changes := newTestBreaker(1, time.Second)
+
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	c.t = c.t.Add(time.Second)
+	assert.True(t, b.Allow())
+	assert.Equal(t, HalfOpen, b.State())
+	assert.False(t, b.Allow(), "only one probe at a time")
+
+	b.Success()
+
+	assert.Equal(t, Closed, b.State())
+	assert.True(t, b.Allow())
+	assert.Equal(t, []State{Open, HalfOpen, Closed}, *changes)
+}
+
+func TestBreaker_FailedProbeReopens(t *testing.T) {
+	b, c, _ := newTestBreaker(1, time.Second)
+
+	b.Failure()
+	c.t = c.t.Add(time.Second)
+	assert.True(t, b.Allow())
+
+	b.Failure()
+
+	assert.Equal(t, Open, b.State())
+	assert.False(t, b.Allow())
+
+	c.t = c.t.Add(time.Second)
+	assert.True(t, b.Allow())
+}
@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+//go:embed crawler_ranges.txt
+var defaultCrawlerRanges []byte
+
+// CrawlerRanges holds the networks known to belong to crawlers and link
+// unfurlers, which often fetch links with a browser-like user agent.
+type CrawlerRanges struct {
+	prefixes []netip.Prefix
+}
+
+var defaultRanges atomic.Pointer[CrawlerRanges]
+
+func init() {
+	ranges, err := ParseCrawlerRanges(defaultCrawlerRanges)
+	if err != nil {
+		panic(fmt.Sprintf("detector: invalid embedded crawler ranges: %v", err))
+	}
+	defaultRanges.Store(ranges)
+}
+
+func ParseCrawlerRanges(data []byte) (*CrawlerRanges, error) {
+	ranges := &CrawlerRanges{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if idx := strings.IndexByte(text, '#'); idx != -1 {
+			text = text[:idx]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranges.prefixes = append(ranges.prefixes, prefix.Masked())
+	}
+
+	return ranges, scanner.Err()
+}
+
+// LoadCrawlerRanges replaces the embedded crawler ranges with the list at path.
+func LoadCrawlerRanges(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	ranges, err := ParseCrawlerRanges(data)
+	if err != nil {
+		return err
+	}
+
+	defaultRanges.Store(ranges)
+	return nil
+}
+
+func IsCrawlerIP(ip string) bool {
+	return defaultRanges.Load().Contains(ip)
+}
+
+func (r *CrawlerRanges) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range r.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
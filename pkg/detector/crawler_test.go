@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCrawlerIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "66.249.66.1", want: true},
+		{ip: "157.55.39.12", want: true},
+		{ip: "69.63.184.14", want: true},
+		{ip: "2a03:2880:f12f:83:face:b00c:0:25de", want: true},
+		{ip: "::ffff:199.16.157.180", want: true},
+		{ip: "203.0.113.10", want: false},
+		{ip: "2001:db8::1", want: false},
+		{ip: "not-an-ip", want: false},
+		{ip: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsCrawlerIP(tt.ip))
+		})
+	}
+}
+
+func TestParseCrawlerRanges(t *testing.T) {
+	ranges, err := ParseCrawlerRanges([]byte("# comment\n\n10.0.0.0/8 # internal scanner\n2001:db8::/32\n"))
+	require.NoError(t, err)
+
+	assert.True(t, ranges.Contains("10.20.30.40"))
+	assert.True(t, ranges.Contains("2001:db8::1"))
+	assert.False(t, ranges.Contains("192.168.1.1"))
+}
+
+func TestParseCrawlerRanges_InvalidLine(t *testing.T) {
+	_, err := ParseCrawlerRanges([]byte("10.0.0.0/8\n10.0.0.300/8\n"))
+
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestLoadCrawlerRanges_ReplacesDefaultRanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawler_ranges.txt")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.0/24\n"), 0644))
+
+	require.NoError(t, LoadCrawlerRanges(path))
+	t.Cleanup(func() {
+		ranges, err := ParseCrawlerRanges(defaultCrawlerRanges)
+		require.NoError(t, err)
+		defaultRanges.Store(ranges)
+	})
+
+	assert.True(t, IsCrawlerIP("192.0.2.55"))
+	assert.False(t, IsCrawlerIP("66.249.66.1"))
+}
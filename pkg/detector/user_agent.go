@@ -0,0 +1,223 @@
+package detector
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+
+	FamilyOther = "Other"
+
+	maxVersionLength = 32
+)
+
+//go:embed patterns.json
+var defaultPatterns []byte
+
+type UserAgent struct {
+	BrowserFamily  string
+	BrowserVersion string
+	OSFamily       string
+	OSVersion      string
+	DeviceType     string
+	IsBot          bool
+}
+
+type patternFile struct {
+	Bots      []patternDef `json:"bots"`
+	Browsers  []patternDef `json:"browsers"`
+	OS        []patternDef `json:"os"`
+	Devices   []patternDef `json:"devices"`
+	Unfurlers []patternDef `json:"unfurlers"`
+}
+
+type patternDef struct {
+	Regex    string            `json:"regex"`
+	Family   string            `json:"family"`
+	Type     string            `json:"type"`
+	Versions map[string]string `json:"versions"`
+}
+
+type rule struct {
+	re       *regexp.Regexp
+	family   string
+	versions map[string]string
+}
+
+// Parser classifies user agents with an ordered list of rules per category,
+// where the first matching rule wins.
+type Parser struct {
+	bots      []rule
+	browsers  []rule
+	os        []rule
+	devices   []rule
+	unfurlers []rule
+}
+
+var defaultParser atomic.Pointer[Parser]
+
+func init() {
+	parser, err := NewParser(defaultPatterns)
+	if err != nil {
+		panic(fmt.Sprintf("detector: invalid embedded patterns: %v", err))
+	}
+	defaultParser.Store(parser)
+}
+
+func NewParser(data []byte) (*Parser, error) {
+	var file patternFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	bots, err := compileRules(file.Bots, false)
+	if err != nil {
+		return nil, err
+	}
+	browsers, err := compileRules(file.Browsers, false)
+	if err != nil {
+		return nil, err
+	}
+	oses, err := compileRules(file.OS, false)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := compileRules(file.Devices, true)
+	if err != nil {
+		return nil, err
+	}
+	unfurlers, err := compileRules(file.Unfurlers, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		bots:      bots,
+		browsers:  browsers,
+		os:        oses,
+		devices:   devices,
+		unfurlers: unfurlers,
+	}, nil
+}
+
+func compileRules(defs []patternDef, useType bool) ([]rule, error) {
+	rules := make([]rule, 0, len(defs))
+	for _, def := range defs {
+		re, err := regexp.Compile(def.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", def.Regex, err)
+		}
+
+		family := def.Family
+		if useType {
+			family = def.Type
+		}
+
+		rules = append(rules, rule{re: re, family: family, versions: def.Versions})
+	}
+
+	return rules, nil
+}
+
+// LoadPatterns replaces the embedded pattern database with the one at path,
+// so new crawlers can be recognized without a rebuild.
+func LoadPatterns(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	parser, err := NewParser(data)
+	if err != nil {
+		return err
+	}
+
+	defaultParser.Store(parser)
+	return nil
+}
+
+func Parse(userAgent string) UserAgent {
+	return defaultParser.Load().Parse(userAgent)
+}
+
+func (p *Parser) Parse(userAgent string) UserAgent {
+	ua := UserAgent{
+		BrowserFamily: FamilyOther,
+		OSFamily:      FamilyOther,
+		DeviceType:    DeviceUnknown,
+	}
+
+	if strings.TrimSpace(userAgent) == "" {
+		return ua
+	}
+
+	if family, version, ok := match(p.os, userAgent); ok {
+		ua.OSFamily = family
+		ua.OSVersion = version
+	}
+
+	if family, version, ok := match(p.bots, userAgent); ok {
+		ua.BrowserFamily = family
+		ua.BrowserVersion = version
+		ua.DeviceType = DeviceBot
+		ua.IsBot = true
+		return ua
+	}
+
+	if family, version, ok := match(p.browsers, userAgent); ok {
+		ua.BrowserFamily = family
+		ua.BrowserVersion = version
+	}
+
+	if deviceType, _, ok := match(p.devices, userAgent); ok {
+		ua.DeviceType = deviceType
+	}
+
+	return ua
+}
+
+// IsUnfurler reports whether userAgent belongs to a service that fetches links
+// to render a preview card, such as Slack or Twitter, rather than to follow
+// them.
+func IsUnfurler(userAgent string) bool {
+	return defaultParser.Load().IsUnfurler(userAgent)
+}
+
+func (p *Parser) IsUnfurler(userAgent string) bool {
+	_, _, ok := match(p.unfurlers, userAgent)
+	return ok
+}
+
+func match(rules []rule, userAgent string) (family, version string, ok bool) {
+	for _, r := range rules {
+		groups := r.re.FindStringSubmatch(userAgent)
+		if groups == nil {
+			continue
+		}
+
+		if len(groups) > 1 {
+			version = strings.ReplaceAll(groups[1], "_", ".")
+			if len(version) > maxVersionLength {
+				version = version[:maxVersionLength]
+			}
+			if mapped, found := r.versions[version]; found {
+				version = mapped
+			}
+		}
+
+		return r.family, version, true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,555 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_RealUserAgents(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      UserAgent
+	}{
+		// desktop browsers
+		{
+			name:      "Chrome on Windows 10",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.0.0", OSFamily: "Windows", OSVersion: "10", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Chrome on Windows 7",
+			userAgent: "Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "109.0.0.0", OSFamily: "Windows", OSVersion: "7", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Chrome on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "121.0.0.0", OSFamily: "macOS", OSVersion: "10.15.7", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Chrome on Linux",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.0.0", OSFamily: "Linux", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Chrome on Chrome OS",
+			userAgent: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.0.0", OSFamily: "Chrome OS", OSVersion: "14541.0.0", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Firefox on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "121.0", OSFamily: "Windows", OSVersion: "10", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Firefox on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.2; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "121.0", OSFamily: "macOS", OSVersion: "14.2", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Firefox on Ubuntu",
+			userAgent: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "120.0", OSFamily: "Ubuntu", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Firefox on Fedora",
+			userAgent: "Mozilla/5.0 (X11; Fedora; Linux x86_64; rv:119.0) Gecko/20100101 Firefox/119.0",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "119.0", OSFamily: "Fedora", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Safari on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2.1 Safari/605.1.15",
+			want:      UserAgent{BrowserFamily: "Safari", BrowserVersion: "17.2.1", OSFamily: "macOS", OSVersion: "10.15.7", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Edge on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want:      UserAgent{BrowserFamily: "Edge", BrowserVersion: "120.0.2210.91", OSFamily: "Windows", OSVersion: "10", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Legacy Edge on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.19582",
+			want:      UserAgent{BrowserFamily: "Edge", BrowserVersion: "18.19582", OSFamily: "Windows", OSVersion: "10", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Opera on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 OPR/105.0.0.0",
+			want:      UserAgent{BrowserFamily: "Opera", BrowserVersion: "105.0.0.0", OSFamily: "Windows", OSVersion: "10", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Presto Opera on Windows XP",
+			userAgent: "Opera/9.80 (Windows NT 5.1; U; en) Presto/2.10.289 Version/12.02",
+			want:      UserAgent{BrowserFamily: "Opera", BrowserVersion: "12.02", OSFamily: "Windows", OSVersion: "XP", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Vivaldi on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Vivaldi/6.5.3206.48",
+			want:      UserAgent{BrowserFamily: "Vivaldi", BrowserVersion: "6.5.3206.48", OSFamily: "Windows", OSVersion: "10", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Yandex Browser on Windows 8.1",
+			userAgent: "Mozilla/5.0 (Windows NT 6.3; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 YaBrowser/23.11.0.0 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Yandex Browser", BrowserVersion: "23.11.0.0", OSFamily: "Windows", OSVersion: "8.1", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Internet Explorer 11",
+			userAgent: "Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			want:      UserAgent{BrowserFamily: "Internet Explorer", BrowserVersion: "11.0", OSFamily: "Windows", OSVersion: "7", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Internet Explorer 9",
+			userAgent: "Mozilla/5.0 (compatible; MSIE 9.0; Windows NT 6.0; Trident/5.0)",
+			want:      UserAgent{BrowserFamily: "Internet Explorer", BrowserVersion: "9.0", OSFamily: "Windows", OSVersion: "Vista", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Chromium on Linux",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chromium/118.0.5993.88 Chrome/118.0.5993.88 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chromium", BrowserVersion: "118.0.5993.88", OSFamily: "Linux", DeviceType: DeviceDesktop},
+		},
+
+		// mobile browsers
+		{
+			name:      "Safari on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want:      UserAgent{BrowserFamily: "Safari", BrowserVersion: "17.2", OSFamily: "iOS", OSVersion: "17.2.1", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Chrome on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.6099.119", OSFamily: "iOS", OSVersion: "17.1", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Firefox on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/120.0 Mobile/15E148 Safari/605.1.15",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "120.0", OSFamily: "iOS", OSVersion: "16.6", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Edge on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 EdgiOS/120.0.2210.126 Mobile/15E148 Safari/605.1.15",
+			want:      UserAgent{BrowserFamily: "Edge", BrowserVersion: "120.0.2210.126", OSFamily: "iOS", OSVersion: "17.2", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "WKWebView on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148",
+			want:      UserAgent{BrowserFamily: "Mobile Safari UI/WKWebView", OSFamily: "iOS", OSVersion: "16.3", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Safari on iPod touch",
+			userAgent: "Mozilla/5.0 (iPod touch; CPU iPhone OS 12_5_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.2 Mobile/15E148 Safari/604.1",
+			want:      UserAgent{BrowserFamily: "Safari", BrowserVersion: "12.1.2", OSFamily: "iOS", OSVersion: "12.5.7", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Chrome on Android phone",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.6099.144", OSFamily: "Android", OSVersion: "14", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Chrome on Android with reduced UA",
+			userAgent: "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.0.0", OSFamily: "Android", OSVersion: "10", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Samsung Internet on Galaxy",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Samsung Internet", BrowserVersion: "23.0", OSFamily: "Android", OSVersion: "13", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Firefox on Android",
+			userAgent: "Mozilla/5.0 (Android 14; Mobile; rv:121.0) Gecko/121.0 Firefox/121.0",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "121.0", OSFamily: "Android", OSVersion: "14", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Opera on Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 12; SM-A525F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.6045.163 Mobile Safari/537.36 OPR/79.2.4195.76432",
+			want:      UserAgent{BrowserFamily: "Opera", BrowserVersion: "79.2.4195.76432", OSFamily: "Android", OSVersion: "12", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Edge on Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 10; HD1913) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.116 Mobile Safari/537.36 EdgA/120.0.2210.115",
+			want:      UserAgent{BrowserFamily: "Edge", BrowserVersion: "120.0.2210.115", OSFamily: "Android", OSVersion: "10", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "UC Browser on Android",
+			userAgent: "Mozilla/5.0 (Linux; U; Android 9; en-US; Redmi Note 7 Build/PKQ1.180904.001) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/78.0.3904.108 UCBrowser/13.4.0.1306 Mobile Safari/537.36",
+			want:      UserAgent{BrowserFamily: "UC Browser", BrowserVersion: "13.4.0.1306", OSFamily: "Android", OSVersion: "9", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "MIUI Browser on Xiaomi",
+			userAgent: "Mozilla/5.0 (Linux; U; Android 12; en-us; 2201117TG Build/SKQ1.211006.001) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/100.0.4896.127 Mobile Safari/537.36 XiaoMi/MiuiBrowser/13.28.0-gn",
+			want:      UserAgent{BrowserFamily: "MIUI Browser", BrowserVersion: "13.28.0", OSFamily: "Android", OSVersion: "12", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Android WebView",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-G991B Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.6099.144 Mobile Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Android WebView", BrowserVersion: "120.0.6099.144", OSFamily: "Android", OSVersion: "13", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Facebook in-app browser on Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-A536B Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.6099.144 Mobile Safari/537.36 [FB_IAB/FB4A;FBAV/444.0.0.34.118;]",
+			want:      UserAgent{BrowserFamily: "Facebook", BrowserVersion: "444.0.0.34.118", OSFamily: "Android", OSVersion: "13", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Instagram in-app browser on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 311.0.2.32.112 (iPhone15,2; iOS 17_1_2; en_US; en; scale=3.00; 1179x2556; 545286221)",
+			want:      UserAgent{BrowserFamily: "Instagram", BrowserVersion: "311.0.2.32.112", OSFamily: "iOS", OSVersion: "17.1.2", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "LINE in-app browser on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Safari Line/13.10.0",
+			want:      UserAgent{BrowserFamily: "LINE", BrowserVersion: "13.10.0", OSFamily: "iOS", OSVersion: "16.5", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Opera Mini",
+			userAgent: "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54",
+			want:      UserAgent{BrowserFamily: "Opera Mini", BrowserVersion: "9.80", OSFamily: FamilyOther, DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Windows Phone 8",
+			userAgent: "Mozilla/5.0 (compatible; MSIE 10.0; Windows Phone 8.0; Trident/6.0; IEMobile/10.0; ARM; Touch; NOKIA; Lumia 920)",
+			want:      UserAgent{BrowserFamily: "Internet Explorer", BrowserVersion: "10.0", OSFamily: "Windows Phone", OSVersion: "8.0", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Windows Phone 10",
+			userAgent: "Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; Lumia 950) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Mobile Safari/537.36 Edge/15.15063",
+			want:      UserAgent{BrowserFamily: "Edge", BrowserVersion: "15.15063", OSFamily: "Windows Phone", OSVersion: "10.0", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "BlackBerry 10",
+			userAgent: "Mozilla/5.0 (BB10; Touch) AppleWebKit/537.35+ (KHTML, like Gecko) Version/10.3.3.2205 Mobile Safari/537.35+",
+			want:      UserAgent{BrowserFamily: "Safari", BrowserVersion: "10.3.3.2205", OSFamily: "BlackBerry OS", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "KaiOS feature phone",
+			userAgent: "Mozilla/5.0 (Mobile; LYF/F300B/LYF-F300B-001-01-15-130718-i;Android; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "48.0", OSFamily: "KaiOS", OSVersion: "2.5", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Huawei HarmonyOS",
+			userAgent: "Mozilla/5.0 (Linux; Android 10; HarmonyOS; NOH-AN00; HMSCore 6.12.0.302) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4844.88 HuaweiBrowser/14.0.2.311 Mobile Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "99.0.4844.88", OSFamily: "HarmonyOS", DeviceType: DeviceMobile},
+		},
+
+		// tablets
+		{
+			name:      "Safari on iPad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want:      UserAgent{BrowserFamily: "Safari", BrowserVersion: "17.2", OSFamily: "iOS", OSVersion: "17.2", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Chrome on iPad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.169 Mobile/15E148 Safari/604.1",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "119.0.6045.169", OSFamily: "iOS", OSVersion: "16.6", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Chrome on Samsung Galaxy Tab",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "120.0.6099.144", OSFamily: "Android", OSVersion: "13", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Samsung Internet on Galaxy Tab",
+			userAgent: "Mozilla/5.0 (Linux; Android 12; SM-T870) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/22.0 Chrome/111.0.5563.116 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Samsung Internet", BrowserVersion: "22.0", OSFamily: "Android", OSVersion: "12", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Silk on Kindle Fire",
+			userAgent: "Mozilla/5.0 (Linux; Android 9; KFTRWI) AppleWebKit/537.36 (KHTML, like Gecko) Silk/119.4.1 like Chrome/119.0.6045.193 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Chrome", BrowserVersion: "119.0.6045.193", OSFamily: "Android", OSVersion: "9", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Firefox on Android tablet",
+			userAgent: "Mozilla/5.0 (Android 13; Tablet; rv:121.0) Gecko/121.0 Firefox/121.0",
+			want:      UserAgent{BrowserFamily: "Firefox", BrowserVersion: "121.0", OSFamily: "Android", OSVersion: "13", DeviceType: DeviceTablet},
+		},
+
+		// bots and link unfurlers
+		{
+			name:      "Googlebot desktop",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      UserAgent{BrowserFamily: "Googlebot", BrowserVersion: "2.1", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Googlebot smartphone",
+			userAgent: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.129 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      UserAgent{BrowserFamily: "Googlebot", BrowserVersion: "2.1", OSFamily: "Android", OSVersion: "6.0.1", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Googlebot image",
+			userAgent: "Googlebot-Image/1.0",
+			want:      UserAgent{BrowserFamily: "Googlebot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Google AdsBot",
+			userAgent: "AdsBot-Google (+http://www.google.com/adsbot.html)",
+			want:      UserAgent{BrowserFamily: "AdsBot-Google", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Bingbot",
+			userAgent: "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+			want:      UserAgent{BrowserFamily: "Bingbot", BrowserVersion: "2.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Yahoo Slurp",
+			userAgent: "Mozilla/5.0 (compatible; Yahoo! Slurp; http://help.yahoo.com/help/us/ysearch/slurp)",
+			want:      UserAgent{BrowserFamily: "Yahoo! Slurp", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "DuckDuckBot",
+			userAgent: "DuckDuckBot/1.1; (+http://duckduckgo.com/duckduckbot.html)",
+			want:      UserAgent{BrowserFamily: "DuckDuckBot", BrowserVersion: "1.1", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Baiduspider",
+			userAgent: "Mozilla/5.0 (compatible; Baiduspider/2.0; +http://www.baidu.com/search/spider.html)",
+			want:      UserAgent{BrowserFamily: "Baiduspider", BrowserVersion: "2.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "YandexBot",
+			userAgent: "Mozilla/5.0 (compatible; YandexBot/3.0; +http://yandex.com/bots)",
+			want:      UserAgent{BrowserFamily: "YandexBot", BrowserVersion: "3.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Applebot",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.1.1 Safari/605.1.15 (Applebot/0.1; +http://www.apple.com/go/applebot)",
+			want:      UserAgent{BrowserFamily: "Applebot", BrowserVersion: "0.1", OSFamily: "macOS", OSVersion: "10.15.5", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Facebook link preview",
+			userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			want:      UserAgent{BrowserFamily: "Facebook External Hit", BrowserVersion: "1.1", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Meta external agent",
+			userAgent: "meta-externalagent/1.1 (+https://developers.facebook.com/docs/sharing/webmasters/crawler)",
+			want:      UserAgent{BrowserFamily: "Meta External Agent", BrowserVersion: "1.1", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Twitterbot",
+			userAgent: "Twitterbot/1.0",
+			want:      UserAgent{BrowserFamily: "Twitterbot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "LinkedInBot",
+			userAgent: "LinkedInBot/1.0 (compatible; Mozilla/5.0; Apache-HttpClient +http://www.linkedin.com)",
+			want:      UserAgent{BrowserFamily: "LinkedInBot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Slack link expander",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want:      UserAgent{BrowserFamily: "Slackbot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Slack image proxy",
+			userAgent: "Slack-ImgProxy (+https://api.slack.com/robots)",
+			want:      UserAgent{BrowserFamily: "Slackbot", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Discordbot",
+			userAgent: "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)",
+			want:      UserAgent{BrowserFamily: "Discordbot", BrowserVersion: "2.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "TelegramBot",
+			userAgent: "TelegramBot (like TwitterBot)",
+			want:      UserAgent{BrowserFamily: "TelegramBot", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "WhatsApp link preview",
+			userAgent: "WhatsApp/2.23.20.0",
+			want:      UserAgent{BrowserFamily: "WhatsApp", BrowserVersion: "2.23.20.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Skype link preview",
+			userAgent: "Mozilla/5.0 (Windows NT 6.1; WOW64) SkypeUriPreview Preview/0.5 skype-url-preview@microsoft.com",
+			want:      UserAgent{BrowserFamily: "Skype", OSFamily: "Windows", OSVersion: "7", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Pinterestbot",
+			userAgent: "Mozilla/5.0 (compatible; Pinterestbot/1.0; +http://www.pinterest.com/bot.html)",
+			want:      UserAgent{BrowserFamily: "Pinterestbot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Redditbot",
+			userAgent: "Mozilla/5.0 (compatible; redditbot/1.0; +http://www.reddit.com/feedback)",
+			want:      UserAgent{BrowserFamily: "Redditbot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "SemrushBot",
+			userAgent: "Mozilla/5.0 (compatible; SemrushBot/7~bl; +http://www.semrush.com/bot.html)",
+			want:      UserAgent{BrowserFamily: "SemrushBot", BrowserVersion: "7", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "AhrefsBot",
+			userAgent: "Mozilla/5.0 (compatible; AhrefsBot/7.0; +http://ahrefs.com/robot/)",
+			want:      UserAgent{BrowserFamily: "AhrefsBot", BrowserVersion: "7.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "MJ12bot",
+			userAgent: "Mozilla/5.0 (compatible; MJ12bot/v1.4.8; http://mj12bot.com/)",
+			want:      UserAgent{BrowserFamily: "MJ12bot", BrowserVersion: "1.4.8", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "DotBot",
+			userAgent: "Mozilla/5.0 (compatible; DotBot/1.2; +https://opensiteexplorer.org/dotbot; help@moz.com)",
+			want:      UserAgent{BrowserFamily: "DotBot", BrowserVersion: "1.2", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "PetalBot",
+			userAgent: "Mozilla/5.0 (Linux; Android 7.0;) AppleWebKit/537.36 (KHTML, like Gecko) Mobile Safari/537.36 (compatible; PetalBot;+https://webmaster.petalsearch.com/site/petalbot)",
+			want:      UserAgent{BrowserFamily: "PetalBot", OSFamily: "Android", OSVersion: "7.0", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "GPTBot",
+			userAgent: "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; GPTBot/1.0; +https://openai.com/gptbot)",
+			want:      UserAgent{BrowserFamily: "GPTBot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "ClaudeBot",
+			userAgent: "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; ClaudeBot/1.0; +claudebot@anthropic.com)",
+			want:      UserAgent{BrowserFamily: "ClaudeBot", BrowserVersion: "1.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "CCBot",
+			userAgent: "CCBot/2.0 (https://commoncrawl.org/faq/)",
+			want:      UserAgent{BrowserFamily: "CCBot", BrowserVersion: "2.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Bytespider",
+			userAgent: "Mozilla/5.0 (Linux; Android 5.0) AppleWebKit/537.36 (KHTML, like Gecko) Mobile Safari/537.36 (compatible; Bytespider; spider-feedback@bytedance.com)",
+			want:      UserAgent{BrowserFamily: "Bytespider", OSFamily: "Android", OSVersion: "5.0", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Amazonbot",
+			userAgent: "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; Amazonbot/0.1; +https://developer.amazon.com/support/amazonbot) Chrome/119.0.6045.214 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "Amazonbot", BrowserVersion: "0.1", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "UptimeRobot",
+			userAgent: "Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)",
+			want:      UserAgent{BrowserFamily: "UptimeRobot", BrowserVersion: "2.0", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Headless Chrome",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.6099.109 Safari/537.36",
+			want:      UserAgent{BrowserFamily: "HeadlessChrome", BrowserVersion: "120.0.6099.109", OSFamily: "Linux", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name:      "Unlisted crawler",
+			userAgent: "Mozilla/5.0 (compatible; ExampleCrawler/1.0; +https://example.com/crawler)",
+			want:      UserAgent{BrowserFamily: "Other Bot", OSFamily: FamilyOther, DeviceType: DeviceBot, IsBot: true},
+		},
+
+		// http libraries are not crawlers
+		{
+			name:      "curl",
+			userAgent: "curl/8.4.0",
+			want:      UserAgent{BrowserFamily: "curl", BrowserVersion: "8.4.0", OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "Wget",
+			userAgent: "Wget/1.21.4",
+			want:      UserAgent{BrowserFamily: "Wget", BrowserVersion: "1.21.4", OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "python-requests",
+			userAgent: "python-requests/2.31.0",
+			want:      UserAgent{BrowserFamily: "Python Requests", BrowserVersion: "2.31.0", OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "Go http client",
+			userAgent: "Go-http-client/1.1",
+			want:      UserAgent{BrowserFamily: "Go HTTP Client", BrowserVersion: "1.1", OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "OkHttp",
+			userAgent: "okhttp/4.12.0",
+			want:      UserAgent{BrowserFamily: "OkHttp", BrowserVersion: "4.12.0", OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "Postman",
+			userAgent: "PostmanRuntime/7.36.0",
+			want:      UserAgent{BrowserFamily: "Postman", BrowserVersion: "7.36.0", OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+
+		// garbage
+		{
+			name:      "empty",
+			userAgent: "",
+			want:      UserAgent{BrowserFamily: FamilyOther, OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "whitespace",
+			userAgent: "   ",
+			want:      UserAgent{BrowserFamily: FamilyOther, OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+		{
+			name:      "unrecognized",
+			userAgent: "Mozilla/4.0",
+			want:      UserAgent{BrowserFamily: FamilyOther, OSFamily: FamilyOther, DeviceType: DeviceUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.userAgent))
+		})
+	}
+}
+
+func TestNewParser_InvalidPattern(t *testing.T) {
+	_, err := NewParser([]byte(`{"bots": [{"regex": "(unclosed", "family": "Broken"}]}`))
+
+	assert.Error(t, err)
+}
+
+func TestLoadPatterns_ReplacesDefaultParser(t *testing.T) {
+	original := defaultParser.Load()
+	defer defaultParser.Store(original)
+
+	path := filepath.Join(t.TempDir(), "patterns.json")
+	patterns := `{"bots": [{"regex": "NewCrawler/(\\d+)", "family": "NewCrawler"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(patterns), 0644))
+
+	require.NoError(t, LoadPatterns(path))
+
+	ua := Parse("NewCrawler/3")
+	assert.True(t, ua.IsBot)
+	assert.Equal(t, "NewCrawler", ua.BrowserFamily)
+	assert.Equal(t, "3", ua.BrowserVersion)
+}
+
+func TestIsUnfurler(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      bool
+	}{
+		{name: "Slack", userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", want: true},
+		{name: "Twitter", userAgent: "Twitterbot/1.0", want: true},
+		{name: "Facebook", userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", want: true},
+		{name: "LinkedIn", userAgent: "LinkedInBot/1.0 (compatible; Mozilla/5.0; Apache-HttpClient +http://www.linkedin.com)", want: true},
+		{name: "Discord", userAgent: "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", want: true},
+		{name: "WhatsApp", userAgent: "WhatsApp/2.23.20.0", want: true},
+		{name: "Slack image proxy", userAgent: "Slack-ImgProxy (+https://api.slack.com/robots)", want: false},
+		{name: "Googlebot", userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", want: false},
+		{name: "Chrome", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", want: false},
+		{name: "empty", userAgent: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsUnfurler(tt.userAgent))
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	userAgent := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1"
+	for i := 0; i < b.N; i++ {
+		Parse(userAgent)
+	}
+}
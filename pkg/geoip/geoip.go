@@ -0,0 +1,172 @@
+package geoip
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+type Location struct {
+	CountryCode string
+	Region      string
+	City        string
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names struct {
+			EN string `maxminddb:"en"`
+		} `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names struct {
+			EN string `maxminddb:"en"`
+		} `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+type database struct {
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+
+	// locations memoizes decoded records by their offset in the data
+	// section, which many networks share, so repeat lookups skip decoding.
+	locations sync.Map
+}
+
+// Resolver looks up client IPs in a MaxMind-format database held in memory.
+// A Resolver without a database resolves every address to an empty Location.
+type Resolver struct {
+	path string
+	db   atomic.Pointer[database]
+}
+
+func Open(path string) (*Resolver, error) {
+	r := &Resolver{path: path}
+	if path == "" {
+		return r, nil
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// load reads the whole file instead of memory-mapping it, so a reader that is
+// swapped out during a reload stays valid for lookups still in flight.
+func (r *Resolver) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	reader, err := maxminddb.OpenBytes(data)
+	if err != nil {
+		return err
+	}
+
+	r.db.Store(&database{
+		reader:  reader,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	})
+
+	return nil
+}
+
+func (r *Resolver) Lookup(ip string) Location {
+	db := r.db.Load()
+	if db == nil {
+		return Location{}
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}
+	}
+
+	result := db.reader.Lookup(addr.Unmap())
+	if result.Err() != nil || !result.Found() {
+		return Location{}
+	}
+
+	if cached, ok := db.locations.Load(result.Offset()); ok {
+		return cached.(Location)
+	}
+
+	var rec record
+	if err := result.Decode(&rec); err != nil {
+		return Location{}
+	}
+
+	loc := Location{
+		CountryCode: rec.Country.ISOCode,
+		City:        rec.City.Names.EN,
+	}
+	if len(rec.Subdivisions) > 0 {
+		loc.Region = rec.Subdivisions[0].Names.EN
+	}
+	db.locations.Store(result.Offset(), loc)
+
+	return loc
+}
+
+// Reload loads the database again if the file changed since it was last read.
+func (r *Resolver) Reload() (bool, error) {
+	if r.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, err
+	}
+
+	if db := r.db.Load(); db != nil && info.ModTime().Equal(db.modTime) && info.Size() == db.size {
+		return false, nil
+	}
+
+	if err := r.load(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Watch checks the database file every interval and reloads it when it
+// changes, until ctx is cancelled. A failed reload keeps the previous database.
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	if r.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
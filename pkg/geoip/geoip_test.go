@@ -0,0 +1,153 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildDatabase encodes a minimal IPv4 MaxMind DB with a single search tree
+// node: 0.0.0.0/1 resolves to the given location, 128.0.0.0/1 is unknown.
+func buildDatabase(loc Location) []byte {
+	str := func(s string) []byte {
+		return append([]byte{0x40 | byte(len(s))}, s...)
+	}
+	uint16Field := func(v uint16) []byte {
+		return []byte{0xA0 | 2, byte(v >> 8), byte(v)}
+	}
+	names := func(en string) []byte {
+		b := append([]byte{0xE0 | 1}, str("names")...)
+		b = append(b, 0xE0|1)
+		b = append(b, str("en")...)
+		return append(b, str(en)...)
+	}
+
+	var data []byte
+	data = append(data, 0xE0|3)
+	data = append(data, str("country")...)
+	data = append(data, 0xE0|1)
+	data = append(data, str("iso_code")...)
+	data = append(data, str(loc.CountryCode)...)
+	data = append(data, str("subdivisions")...)
+	data = append(data, 1, 11-7)
+	data = append(data, names(loc.Region)...)
+	data = append(data, str("city")...)
+	data = append(data, names(loc.City)...)
+
+	const nodeCount = 1
+	tree := []byte{0, 0, nodeCount + 16, 0, 0, nodeCount}
+
+	var metadata []byte
+	metadata = append(metadata, 0xE0|4)
+	metadata = append(metadata, str("node_count")...)
+	metadata = append(metadata, 0xC0|1, nodeCount)
+	metadata = append(metadata, str("record_size")...)
+	metadata = append(metadata, uint16Field(24)...)
+	metadata = append(metadata, str("ip_version")...)
+	metadata = append(metadata, uint16Field(4)...)
+	metadata = append(metadata, str("binary_format_major_version")...)
+	metadata = append(metadata, uint16Field(2)...)
+
+	var db []byte
+	db = append(db, tree...)
+	db = append(db, make([]byte, 16)...)
+	db = append(db, data...)
+	db = append(db, "\xAB\xCD\xEFMaxMind.com"...)
+	return append(db, metadata...)
+}
+
+func writeDatabase(t *testing.T, path string, loc Location, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, buildDatabase(loc), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestResolver_Lookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	writeDatabase(t, path, Location{CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}, time.Now())
+
+	resolver, err := Open(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, Location{CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}, resolver.Lookup("10.1.2.3"))
+	assert.Equal(t, Location{CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}, resolver.Lookup("::ffff:10.1.2.3"))
+	assert.Equal(t, Location{}, resolver.Lookup("200.1.2.3"))
+	assert.Equal(t, Location{}, resolver.Lookup("not-an-ip"))
+	assert.Equal(t, Location{}, resolver.Lookup(""))
+}
+
+func TestResolver_WithoutDatabase(t *testing.T) {
+	resolver, err := Open("")
+	require.NoError(t, err)
+
+	assert.Equal(t, Location{}, resolver.Lookup("10.1.2.3"))
+
+	reloaded, err := resolver.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+
+	assert.Error(t, err)
+}
+
+func TestOpen_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.mmdb")
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0644))
+
+	_, err := Open(path)
+
+	assert.Error(t, err)
+}
+
+func TestResolver_Reload_PicksUpChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	start := time.Now().Add(-time.Hour)
+	writeDatabase(t, path, Location{CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}, start)
+
+	resolver, err := Open(path)
+	require.NoError(t, err)
+
+	reloaded, err := resolver.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged file should not be reloaded")
+
+	writeDatabase(t, path, Location{CountryCode: "SG", Region: "Central", City: "Singapore"}, start.Add(time.Minute))
+
+	reloaded, err = resolver.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "SG", resolver.Lookup("10.1.2.3").CountryCode)
+}
+
+func TestResolver_Reload_KeepsPreviousDatabaseOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	writeDatabase(t, path, Location{CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}, time.Now().Add(-time.Hour))
+
+	resolver, err := Open(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("truncated"), 0644))
+
+	_, err = resolver.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "ID", resolver.Lookup("10.1.2.3").CountryCode)
+}
+
+func BenchmarkResolver_Lookup(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "GeoLite2-City.mmdb")
+	require.NoError(b, os.WriteFile(path, buildDatabase(Location{CountryCode: "ID", Region: "Jakarta", City: "Jakarta"}), 0644))
+
+	resolver, err := Open(path)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resolver.Lookup("10.1.2.3")
+	}
+}
@@ -0,0 +1,116 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	key       string
+	value     V
+	size      int64
+	expiresAt time.Time
+}
+
+// Cache is safe for concurrent use. Entries expire at the time they were set
+// with, and the least recently used are evicted once the sizes of the entries
+// add up to more than the cache's limit.
+type Cache[V any] struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	order    *list.List
+	items    map[string]*list.Element
+	now      func() time.Time
+}
+
+func New[V any](maxBytes int64) *Cache[V] {
+	return &Cache[V]{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value for key, and false if it is missing or has expired.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := el.Value.(*entry[V])
+	if !c.now().Before(e.expiresAt) {
+		c.remove(el)
+		return zero, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores value under key until expiresAt. size is the value's cost against
+// the cache's limit; values larger than the whole cache aren't stored.
+func (c *Cache[V]) Set(key string, value V, size int64, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	if size > c.maxBytes || !c.now().Before(expiresAt) {
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[V]{key: key, value: value, size: size, expiresAt: expiresAt})
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *Cache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge removes every entry.
+func (c *Cache[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Bytes returns the total size of the entries.
+func (c *Cache[V]) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+func (c *Cache[V]) remove(el *list.Element) {
+	e := c.order.Remove(el).(*entry[V])
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
@@ -0,0 +1,75 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string](30)
+	later := time.Now().Add(time.Minute)
+
+	c.Set("a", "A", 10, later)
+	c.Set("b", "B", 10, later)
+	c.Set("c", "C", 10, later)
+	c.Get("a")
+	c.Set("d", "D", 10, later)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "b was least recently used")
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok := c.Get(key)
+		assert.True(t, ok, key)
+	}
+	assert.Equal(t, int64(30), c.Bytes())
+}
+
+func TestCache_Expiry(t *testing.T) {
+	now := time.Now()
+	c := New[string](100)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", "A", 10, now.Add(time.Second))
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, int64(0), c.Bytes())
+}
+
+func TestCache_ReplaceAndOversized(t *testing.T) {
+	c := New[string](20)
+	later := time.Now().Add(time.Minute)
+
+	c.Set("a", "A", 10, later)
+	c.Set("a", "AA", 15, later)
+	value, _ := c.Get("a")
+	assert.Equal(t, "AA", value)
+	assert.Equal(t, int64(15), c.Bytes())
+
+	c.Set("a", "huge", 21, later)
+	_, ok := c.Get("a")
+	assert.False(t, ok, "a value larger than the cache replaces nothing")
+	assert.Equal(t, int64(0), c.Bytes())
+}
+
+func TestCache_DeleteAndPurge(t *testing.T) {
+	c := New[string](100)
+	later := time.Now().Add(time.Minute)
+
+	c.Set("a", "A", 10, later)
+	c.Set("b", "B", 10, later)
+	c.Delete("a")
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, int64(10), c.Bytes())
+
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, int64(0), c.Bytes())
+}
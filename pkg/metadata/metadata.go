@@ -0,0 +1,196 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const userAgent = "Mozilla/5.0 (compatible; url-shortener-preview/1.0)"
+
+var ErrNotHTML = errors.New("metadata: response is not HTML")
+
+// HTTPClient sends preview requests. *http.Client satisfies it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Page is what an HTML page says about itself in its <head>. Links are
+// absolute.
+type Page struct {
+	Title         string
+	OGTitle       string
+	OGDescription string
+	OGImage       string
+	FaviconURL    string
+}
+
+// Fetcher downloads pages and reads their metadata. Every fetch is bounded by
+// timeout, and at most maxBytes of a page are read.
+type Fetcher struct {
+	client   HTTPClient
+	timeout  time.Duration
+	maxBytes int64
+}
+
+func NewFetcher(client HTTPClient, timeout time.Duration, maxBytes int64) *Fetcher {
+	return &Fetcher{client: client, timeout: timeout, maxBytes: maxBytes}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, pageURL string) (*Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("metadata: unexpected status %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, ErrNotHTML
+	}
+
+	// redirects change the base that relative links resolve against
+	base := req.URL
+	if resp.Request != nil {
+		base = resp.Request.URL
+	}
+
+	page, err := Parse(io.LimitReader(resp.Body, f.maxBytes), base)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Parse reads the metadata in the <head> of an HTML document, resolving links
+// against base. It stops at the end of the head or of r, whichever is first.
+// Pages without an icon link get the conventional /favicon.ico.
+func Parse(r io.Reader, base *url.URL) (*Page, error) {
+	page := &Page{}
+	var title strings.Builder
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for done := false; !done; {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			done = true
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				done = true
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = page.Title == "" && title.Len() == 0
+			case "body":
+				done = true
+			case "meta", "link":
+				if hasAttr {
+					readTag(page, string(name), attributes(z))
+				}
+			}
+		}
+	}
+
+	page.Title = strings.Join(strings.Fields(title.String()), " ")
+	page.OGImage = resolve(base, page.OGImage)
+	if page.FaviconURL == "" {
+		page.FaviconURL = "/favicon.ico"
+	}
+	page.FaviconURL = resolve(base, page.FaviconURL)
+
+	return page, nil
+}
+
+func attributes(z *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, value, more := z.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+func readTag(page *Page, name string, attrs map[string]string) {
+	if name == "link" {
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			if rel == "icon" && page.FaviconURL == "" {
+				page.FaviconURL = strings.TrimSpace(attrs["href"])
+			}
+		}
+		return
+	}
+
+	// some sites use name instead of property for Open Graph tags
+	property := attrs["property"]
+	if property == "" {
+		property = attrs["name"]
+	}
+	content := strings.TrimSpace(attrs["content"])
+
+	switch strings.ToLower(property) {
+	case "og:title":
+		setOnce(&page.OGTitle, content)
+	case "og:description":
+		setOnce(&page.OGDescription, content)
+	case "og:image", "og:image:url":
+		setOnce(&page.OGImage, content)
+	}
+}
+
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" || base == nil {
+		return ref
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+
+	resolved := base.ResolveReference(u)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
@@ -0,0 +1,149 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>
+		Spring   Sale
+	</title>
+	<meta property="og:title" content="Spring Sale — 50% off">
+	<meta property="og:description" content="Everything must go">
+	<meta property="og:image" content="/images/sale.png">
+	<link rel="shortcut icon" href="https://cdn.example.com/favicon.png">
+</head>
+<body>
+	<meta property="og:title" content="ignored">
+</body>
+</html>`
+
+func TestFetcher_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.UserAgent(), "url-shortener-preview")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(server.Client(), time.Second, 64<<10)
+	page, err := fetcher.Fetch(context.Background(), server.URL+"/sale")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Spring Sale", page.Title)
+	assert.Equal(t, "Spring Sale — 50% off", page.OGTitle)
+	assert.Equal(t, "Everything must go", page.OGDescription)
+	assert.Equal(t, server.URL+"/images/sale.png", page.OGImage)
+	assert.Equal(t, "https://cdn.example.com/favicon.png", page.FaviconURL)
+}
+
+func TestFetcher_Fetch_ResolvesAgainstRedirectTarget(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="icon" href="icon.svg"></head></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	page, err := NewFetcher(server.Client(), time.Second, 64<<10).Fetch(context.Background(), server.URL+"/old")
+
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/new/icon.svg", page.FaviconURL)
+}
+
+func TestFetcher_Fetch_DefaultFavicon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<title>Plain</title>`))
+	}))
+	defer server.Close()
+
+	page, err := NewFetcher(server.Client(), time.Second, 64<<10).Fetch(context.Background(), server.URL+"/a/b")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Plain", page.Title)
+	assert.Equal(t, server.URL+"/favicon.ico", page.FaviconURL)
+}
+
+func TestFetcher_Fetch_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := NewFetcher(server.Client(), 50*time.Millisecond, 64<<10).Fetch(context.Background(), server.URL)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestFetcher_Fetch_SizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1000) + `<meta property="og:title" content="late">`))
+	}))
+	defer server.Close()
+
+	page, err := NewFetcher(server.Client(), time.Second, 1024).Fetch(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Empty(t, page.OGTitle)
+}
+
+func TestFetcher_Fetch_NotHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.7"))
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher(server.Client(), time.Second, 64<<10).Fetch(context.Background(), server.URL)
+
+	assert.ErrorIs(t, err, ErrNotHTML)
+}
+
+func TestFetcher_Fetch_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewFetcher(server.Client(), time.Second, 64<<10).Fetch(context.Background(), server.URL)
+
+	assert.Error(t, err)
+}
+
+func TestParse_IgnoresNonHTTPImages(t *testing.T) {
+	base, err := url.Parse("https://example.com/")
+	require.NoError(t, err)
+
+	page, err := Parse(strings.NewReader(`<meta property="og:image" content="javascript:alert(1)">`), base)
+
+	require.NoError(t, err)
+	assert.Empty(t, page.OGImage)
+}
+
+func TestNewClient_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<title>internal</title>`))
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher(NewClient(time.Second), time.Second, 64<<10).Fetch(context.Background(), server.URL)
+
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
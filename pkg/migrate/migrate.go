@@ -0,0 +1,298 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDirty is returned when a migration failed partway through outside a
+// transaction, such as one applied by an older tool. The schema has to be
+// fixed by hand and the version then set with Force.
+var ErrDirty = errors.New("database is dirty")
+
+// lockKey is the advisory lock every runner takes, so migrations started by
+// several replicas at once run one after another.
+const lockKey int64 = 0x75726c5f6d6967
+
+// schema_migrations holds a single row with the version of the last migration
+// applied, the same table golang-migrate keeps, so databases it migrated carry
+// on from where it left off. The table is empty before the first migration.
+const createTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT  NOT NULL PRIMARY KEY,
+		dirty   BOOLEAN NOT NULL
+	)
+`
+
+// Migration is one schema change, read from a NNNN_name.up.sql file and the
+// NNNN_name.down.sql that undoes it.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is the version of the last migration applied, 0 for none, and the
+// migrations after it.
+type Status struct {
+	Version int64
+	Dirty   bool
+	Pending []Migration
+}
+
+// Migrator applies migrations to a database. Each migration runs in a
+// transaction with its version update, so a failed one leaves the schema as
+// it was.
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// New reads the migrations in the root of fsys.
+func New(db *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every migration after the current version and returns them.
+// A database already migrated past the newest migration is left as it is.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		current, err := clean(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= current {
+				continue
+			}
+			if err := apply(ctx, conn, migration, migration.Up, migration.Version); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down undoes the last n migrations applied and returns them, newest first.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var undone []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		current, err := clean(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for ; n > 0 && current > 0; n-- {
+			i := m.index(current)
+			if i < 0 {
+				return fmt.Errorf("version %d has no migration to undo", current)
+			}
+			migration := m.migrations[i]
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down migration", migration.Version, migration.Name)
+			}
+
+			previous := int64(0)
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := apply(ctx, conn, migration, migration.Down, previous); err != nil {
+				return err
+			}
+			undone = append(undone, migration)
+			current = previous
+		}
+		return nil
+	})
+	return undone, err
+}
+
+// Status returns the database's version and the migrations not yet applied.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version > status.Version {
+				status.Pending = append(status.Pending, migration)
+			}
+		}
+		return nil
+	})
+	return &status, err
+}
+
+// Force sets the database's version without running any migration, and
+// clears the dirty flag. Version 0 marks no migration as applied.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("version %d has no migration", version)
+	}
+
+	return m.locked(ctx, func(conn *pgx.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return setVersion(ctx, tx, version)
+		})
+	})
+}
+
+func (m *Migrator) index(version int64) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// locked runs fn on a connection holding the migration lock, waiting for any
+// other runner to finish first.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+	defer func() {
+		// closing the connection releases the lock if unlocking fails
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createTable); err != nil {
+		return err
+	}
+	return fn(conn.Conn())
+}
+
+func version(ctx context.Context, conn *pgx.Conn) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// clean returns the database's version, or ErrDirty.
+func clean(ctx context.Context, conn *pgx.Conn) (int64, error) {
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+	return current, nil
+}
+
+func apply(ctx context.Context, conn *pgx.Conn, migration Migration, sql string, to int64) error {
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+		return setVersion(ctx, tx, to)
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func setVersion(ctx context.Context, tx pgx.Tx, version int64) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", version)
+	return err
+}
+
+// load reads NNNN_name.up.sql and NNNN_name.down.sql files, ordered by
+// version. Every migration needs an up file; the down file is optional.
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, name := range names {
+		base, direction := strings.TrimSuffix(name, ".sql"), ""
+		switch path.Ext(base) {
+		case ".up", ".down":
+			base, direction = strings.TrimSuffix(base, path.Ext(base)), path.Ext(base)
+		default:
+			return nil, fmt.Errorf("%s: not an .up.sql or .down.sql file", name)
+		}
+
+		prefix, title, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: version must be a positive number", name)
+		}
+
+		sql, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: title}
+			byVersion[version] = migration
+		}
+		if migration.Name != title {
+			return nil, fmt.Errorf("%s: version %d is also %s", name, version, migration.Name)
+		}
+
+		if direction == ".up" {
+			migration.Up = string(sql)
+		} else {
+			migration.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func file(sql string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(sql)}
+}
+
+func TestLoad_OrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_create_tags.up.sql":   file("CREATE TABLE tags ();"),
+		"0010_create_tags.down.sql": file("DROP TABLE tags;"),
+		"0002_add_clicks.up.sql":    file("CREATE TABLE clicks ();"),
+		"0001_create_urls.up.sql":   file("CREATE TABLE urls ();"),
+		"0001_create_urls.down.sql": file("DROP TABLE urls;"),
+		"README.md":                 file("not a migration"),
+	}
+
+	migrations, err := load(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+
+	assert.Equal(t, Migration{Version: 1, Name: "create_urls", Up: "CREATE TABLE urls ();", Down: "DROP TABLE urls;"}, migrations[0])
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Empty(t, migrations[1].Down)
+	assert.Equal(t, "create_tags", migrations[2].Name)
+}
+
+func TestLoad_RejectsInvalidFiles(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no direction": {"0001_create_urls.sql": file("SELECT 1;")},
+		"bad version":  {"first_create_urls.up.sql": file("SELECT 1;")},
+		"zero version": {"0000_create_urls.up.sql": file("SELECT 1;")},
+		"down only":    {"0001_create_urls.down.sql": file("SELECT 1;")},
+		"same version": {
+			"0001_create_urls.up.sql":   file("SELECT 1;"),
+			"0001_create_clicks.up.sql": file("SELECT 1;"),
+		},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := load(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package referrer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const SourceDirect = "Direct"
+
+//go:embed sources.json
+var defaultSources []byte
+
+type Referrer struct {
+	Host   string
+	Source string
+}
+
+type sourceFile struct {
+	Sources []struct {
+		Source string   `json:"source"`
+		Hosts  []string `json:"hosts"`
+	} `json:"sources"`
+}
+
+type rule struct {
+	source string
+	host   string
+	// anyTLD matches host followed by any top-level domain, such as
+	// google.com and google.co.id for "google.*".
+	anyTLD bool
+}
+
+// Normalizer maps referrer hosts to a named source. Rules are checked in
+// order and the first match wins, so specific hosts go before broad ones.
+type Normalizer struct {
+	rules []rule
+}
+
+var defaultNormalizer *Normalizer
+
+func init() {
+	normalizer, err := NewNormalizer(defaultSources)
+	if err != nil {
+		panic(fmt.Sprintf("referrer: invalid embedded sources: %v", err))
+	}
+	defaultNormalizer = normalizer
+}
+
+func NewNormalizer(data []byte) (*Normalizer, error) {
+	var file sourceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	n := &Normalizer{}
+	for _, s := range file.Sources {
+		for _, host := range s.Hosts {
+			host = strings.ToLower(host)
+			if name, ok := strings.CutSuffix(host, ".*"); ok {
+				n.rules = append(n.rules, rule{source: s.Source, host: name, anyTLD: true})
+				continue
+			}
+			n.rules = append(n.rules, rule{source: s.Source, host: host})
+		}
+	}
+
+	return n, nil
+}
+
+func Parse(referer string) Referrer {
+	return defaultNormalizer.Parse(referer)
+}
+
+// Parse extracts the host of referer, lowercased and without "www.", and the
+// source it belongs to. Hosts without a known source are their own source.
+func (n *Normalizer) Parse(referer string) Referrer {
+	host := Host(referer)
+	if host == "" {
+		return Referrer{Source: SourceDirect}
+	}
+
+	return Referrer{Host: host, Source: n.Source(host)}
+}
+
+// Source returns the source a host, as returned by Host, belongs to. Clicks
+// without a host are direct.
+func Source(host string) string {
+	return defaultNormalizer.Source(host)
+}
+
+func (n *Normalizer) Source(host string) string {
+	if host == "" {
+		return SourceDirect
+	}
+
+	for _, r := range n.rules {
+		if r.anyTLD {
+			if matchAnyTLD(host, r.host) {
+				return r.source
+			}
+			continue
+		}
+		if host == r.host || strings.HasSuffix(host, "."+r.host) {
+			return r.source
+		}
+	}
+
+	return host
+}
+
+func matchAnyTLD(host, name string) bool {
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if label != name {
+			continue
+		}
+
+		switch rest := labels[i+1:]; len(rest) {
+		case 1:
+			return true
+		case 2:
+			// second-level suffixes like co.id and com.au
+			return len(rest[0]) <= 3
+		}
+	}
+
+	return false
+}
+
+// Host returns the lowercased host of referer without a leading "www.", or
+// an empty string when referer is not an absolute URL.
+func Host(referer string) string {
+	referer = strings.TrimSpace(referer)
+	if referer == "" {
+		return ""
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	return strings.TrimPrefix(host, "www.")
+}
@@ -0,0 +1,59 @@
+package referrer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		referer string
+		want    Referrer
+	}{
+		{referer: "", want: Referrer{Source: SourceDirect}},
+		{referer: "not a url", want: Referrer{Source: SourceDirect}},
+		{referer: "https://t.co/abc", want: Referrer{Host: "t.co", Source: "Twitter"}},
+		{referer: "https://t.co/xyz", want: Referrer{Host: "t.co", Source: "Twitter"}},
+		{referer: "https://x.com/someone/status/1", want: Referrer{Host: "x.com", Source: "Twitter"}},
+		{referer: "https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com", want: Referrer{Host: "l.facebook.com", Source: "Facebook"}},
+		{referer: "https://lm.facebook.com/", want: Referrer{Host: "lm.facebook.com", Source: "Facebook"}},
+		{referer: "https://WWW.Reddit.com/r/golang/", want: Referrer{Host: "reddit.com", Source: "Reddit"}},
+		{referer: "https://out.reddit.com/t3_abc", want: Referrer{Host: "out.reddit.com", Source: "Reddit"}},
+		{referer: "https://www.google.com/", want: Referrer{Host: "google.com", Source: "Google"}},
+		{referer: "https://www.google.co.id/", want: Referrer{Host: "google.co.id", Source: "Google"}},
+		{referer: "https://mail.google.com/mail/u/0/", want: Referrer{Host: "mail.google.com", Source: "Gmail"}},
+		{referer: "https://google.evil.com/", want: Referrer{Host: "google.evil.com", Source: "google.evil.com"}},
+		{referer: "https://notfacebook.com/", want: Referrer{Host: "notfacebook.com", Source: "notfacebook.com"}},
+		{referer: "https://news.ycombinator.com/item?id=1", want: Referrer{Host: "news.ycombinator.com", Source: "Hacker News"}},
+		{referer: "https://blog.example.com:8443/post", want: Referrer{Host: "blog.example.com", Source: "blog.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.referer, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.referer))
+		})
+	}
+}
+
+func TestSource(t *testing.T) {
+	assert.Equal(t, SourceDirect, Source(""))
+	assert.Equal(t, "Twitter", Source("t.co"))
+	assert.Equal(t, "Google", Source("google.co.id"))
+	assert.Equal(t, "example.com", Source("example.com"))
+}
+
+func TestNewNormalizer_CustomSources(t *testing.T) {
+	normalizer, err := NewNormalizer([]byte(`{"sources": [{"source": "Newsletter", "hosts": ["mailer.example.com"]}]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Newsletter", normalizer.Parse("https://mailer.example.com/c/123").Source)
+	assert.Equal(t, "t.co", normalizer.Parse("https://t.co/abc").Source)
+}
+
+func TestNewNormalizer_InvalidJSON(t *testing.T) {
+	_, err := NewNormalizer([]byte(`{`))
+
+	assert.Error(t, err)
+}
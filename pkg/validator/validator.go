@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -19,6 +20,7 @@ func init() {
 	validate = validator.New()
 
 	validate.RegisterValidation("alias", validateAlias)
+	validate.RegisterValidation("web_url", validateWebURL)
 }
 
 func Validate(data interface{}) []response.ValidationError {
@@ -44,6 +46,21 @@ func validateAlias(fl validator.FieldLevel) bool {
 	return matched
 }
 
+// validateWebURL accepts absolute http and https URLs. An empty value passes so
+// optional fields can be cleared.
+func validateWebURL(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if value == "" {
+		return true
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 func IsReservedKeyword(alias string) bool {
 	return reservedKeywords[strings.ToLower(alias)]
 }
@@ -56,6 +73,8 @@ func getErrorMessage(err validator.FieldError) string {
 		return fmt.Sprintf("%s is required", field)
 	case "url":
 		return fmt.Sprintf("%s must be a valid URL", field)
+	case "web_url":
+		return fmt.Sprintf("%s must be an http or https URL", field)
 	case "min":
 		return fmt.Sprintf("%s must be at least %s characters", field, err.Param())
 	case "max":
@@ -66,6 +85,8 @@ func getErrorMessage(err validator.FieldError) string {
 		return fmt.Sprintf("%s must be greater than or equal to %s", field, err.Param())
 	case "lte":
 		return fmt.Sprintf("%s must be less than or equal to %s", field, err.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, err.Param())
 	default:
 		return fmt.Sprintf("%s is invalid", field)
 	}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"link.created"}`)
+	signature := Sign("secret", 1700000000, body)
+
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.True(t, Verify("secret", signature, 1700000000, body))
+	assert.False(t, Verify("other", signature, 1700000000, body))
+	assert.False(t, Verify("secret", signature, 1700000001, body))
+	assert.False(t, Verify("secret", signature, 1700000000, []byte(`{}`)))
+}
+
+func TestSender_Send(t *testing.T) {
+	body := []byte(`{"type":"link.created"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+
+		assert.Equal(t, body, received)
+		assert.Equal(t, "link.created", r.Header.Get(HeaderEvent))
+		assert.Equal(t, "42", r.Header.Get(HeaderDelivery))
+		assert.True(t, Verify("secret", r.Header.Get(HeaderSignature), timestamp, received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(&http.Client{Timeout: time.Second}).Send(context.Background(), &Request{
+		URL:        server.URL,
+		Secret:     "secret",
+		Event:      "link.created",
+		DeliveryID: "42",
+		Body:       body,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestSender_Send_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(&http.Client{Timeout: time.Second}).Send(context.Background(), &Request{URL: server.URL, Body: []byte(`{}`)})
+
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "try later", statusErr.Body)
+}
+
+func TestSender_Send_DoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(&http.Client{Timeout: time.Second}).Send(context.Background(), &Request{URL: server.URL, Body: []byte(`{}`)})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+}
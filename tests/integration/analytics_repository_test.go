@@ -0,0 +1,130 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/internal/repository/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyticsRepository_SetReferrerSources(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	url := &domain.URL{ShortCode: "src1234", OriginalURL: "https://example.com", IsActive: true}
+	require.NoError(t, postgres.NewURLRepository(db, nil).Create(ctx, url))
+
+	// clicks from before sources were recorded, and one from after
+	_, err := db.Exec(ctx, `
+		INSERT INTO url_clicks (url_id, referrer_host, referrer_source)
+		VALUES ($1, 't.co', NULL), ($1, NULL, NULL), ($1, 'x.com', 'Twitter')
+	`, url.ID)
+	require.NoError(t, err)
+
+	repo := postgres.NewAnalyticsRepository(db, nil)
+	clicks, err := repo.ListUnsourcedClicks(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, clicks, 2)
+	assert.Equal(t, "t.co", clicks[0].ReferrerHost)
+	assert.Equal(t, "", clicks[1].ReferrerHost)
+
+	clicks[0].ReferrerSource, clicks[1].ReferrerSource = "Twitter", "Direct"
+	require.NoError(t, repo.SetReferrerSources(ctx, clicks))
+
+	remaining, err := repo.ListUnsourcedClicks(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	var twitter int
+	require.NoError(t, db.QueryRow(ctx, "SELECT COUNT(*) FROM url_clicks WHERE referrer_source = 'Twitter'").Scan(&twitter))
+	assert.Equal(t, 2, twitter)
+}
+
+func TestAnalyticsRepository_FoldClickDeltas(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	url := &domain.URL{ShortCode: "fold123", OriginalURL: "https://example.com", IsActive: true}
+	require.NoError(t, postgres.NewURLRepository(db, nil).Create(ctx, url))
+
+	_, err := db.Exec(ctx, `
+		INSERT INTO url_clicks (url_id, clicked_at, is_bot)
+		SELECT $1, date_trunc('hour', NOW()) - INTERVAL '2 hours', n % 3 = 0 FROM generate_series(1, 6) n
+	`, url.ID)
+	require.NoError(t, err)
+
+	repo := postgres.NewAnalyticsRepository(db, nil)
+	timeRange, err := domain.NewTimeRange(time.Now().Add(-24*time.Hour), time.Now(), domain.GranularityHour, "UTC")
+	require.NoError(t, err)
+
+	// unfolded clicks are counted too
+	total, err := repo.CountOverviewClicks(ctx, timeRange, false, &domain.LinkSet{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+
+	folded, err := repo.FoldClickDeltas(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), folded)
+	folded, err = repo.FoldClickDeltas(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), folded)
+
+	var clicks, botClicks int64
+	require.NoError(t, db.QueryRow(ctx, "SELECT clicks, bot_clicks FROM url_click_stats WHERE url_id = $1", url.ID).Scan(&clicks, &botClicks))
+	assert.Equal(t, int64(4), clicks)
+	assert.Equal(t, int64(2), botClicks)
+
+	total, err = repo.CountOverviewClicks(ctx, timeRange, true, &domain.LinkSet{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), total)
+}
+
+func TestAnalyticsRepository_RecordClick_ConcurrentTotals(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	url := &domain.URL{ShortCode: "busy123", OriginalURL: "https://example.com", IsActive: true}
+	require.NoError(t, postgres.NewURLRepository(db, nil).Create(ctx, url))
+	repo := postgres.NewAnalyticsRepository(db, nil)
+
+	const clicks = 50
+	totals := make([]int64, clicks)
+	var wg sync.WaitGroup
+	for i := range clicks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			event, err := repo.RecordClick(ctx, &domain.ClickRequest{URLID: url.ID, DeviceType: "desktop"})
+			if assert.NoError(t, err) {
+				totals[i] = event.TotalClicks
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every total is seen by exactly one click, so each milestone fires once
+	slices.Sort(totals)
+	for i, total := range totals {
+		assert.Equal(t, int64(i+1), total)
+	}
+
+	event, err := repo.RecordClick(ctx, &domain.ClickRequest{URLID: url.ID, IsBot: true, BotReason: domain.BotReasonUserAgent})
+	require.NoError(t, err)
+	assert.Equal(t, int64(clicks), event.TotalClicks, "bot clicks are not counted")
+
+	var clickCount int64
+	require.NoError(t, db.QueryRow(ctx, `SELECT click_count FROM urls WHERE id = $1`, url.ID).Scan(&clickCount))
+	assert.Equal(t, int64(clicks), clickCount)
+}
@@ -52,13 +52,15 @@ func TestCacheRepository_SetAndGetURL(t *testing.T) {
 	err := repo.SetURL(ctx, url, 10*time.Minute)
 	require.NoError(t, err)
 
-	result, err := repo.GetURL(ctx, "test123")
+	result, _, err := repo.GetURL(ctx, "test123")
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, url.ShortCode, result.ShortCode)
 	assert.Equal(t, url.OriginalURL, result.OriginalURL)
-	assert.Equal(t, url.ClickCount, result.ClickCount)
+	assert.Equal(t, url.ID, result.ID)
 	assert.Equal(t, url.IsActive, result.IsActive)
+	assert.True(t, url.CreatedAt.Truncate(time.Microsecond).Equal(result.CreatedAt))
+	assert.Zero(t, result.ClickCount, "Only redirect fields are cached")
 }
 
 func TestCacheRepository_GetURL_NotFound(t *testing.T) {
@@ -68,12 +70,177 @@ func TestCacheRepository_GetURL_NotFound(t *testing.T) {
 	repo := redisrepo.NewURLCache(redisClient)
 	ctx := context.Background()
 
-	result, err := repo.GetURL(ctx, "notfound")
+	result, _, err := repo.GetURL(ctx, "notfound")
 
 	assert.NoError(t, err)
 	assert.Nil(t, result, "Should return nil for non-existent key")
 }
 
+func TestCacheRepository_DeleteURL(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	repo := redisrepo.NewURLCache(redisClient)
+	ctx := context.Background()
+
+	url := &domain.URL{ID: 1, ShortCode: "del1234", OriginalURL: "https://example.com", IsActive: true}
+	require.NoError(t, repo.SetURL(ctx, url, time.Hour))
+
+	require.NoError(t, repo.DeleteURL(ctx, "del1234"))
+
+	result, _, err := repo.GetURL(ctx, "del1234")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestCacheRepository_WatchInvalidations(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	repo := redisrepo.NewURLCache(redisClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resets := make(chan struct{}, 1)
+	invalidated := make(chan string, 1)
+	go repo.WatchInvalidations(ctx, func(shortCode string) {
+		invalidated <- shortCode
+	}, func() {
+		resets <- struct{}{}
+	})
+
+	select {
+	case <-resets:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription was not established")
+	}
+
+	require.NoError(t, repo.DeleteURL(ctx, "inv1234"))
+
+	select {
+	case shortCode := <-invalidated:
+		assert.Equal(t, "inv1234", shortCode)
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalidation was not received")
+	}
+}
+
+func TestCacheRepository_SetNotFound(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	repo := redisrepo.NewURLCache(redisClient)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SetNotFound(ctx, "neg1234", time.Minute))
+
+	result, _, err := repo.GetURL(ctx, "neg1234")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Nil(t, result)
+
+	url := &domain.URL{ShortCode: "neg1234", OriginalURL: "https://example.com", IsActive: true}
+	require.NoError(t, repo.AddURL(ctx, url, time.Hour))
+
+	result, _, err = repo.GetURL(ctx, "neg1234")
+	require.NoError(t, err)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+
+	// a late negative entry must not hide the link
+	require.NoError(t, repo.SetNotFound(ctx, "neg1234", time.Minute))
+
+	result, _, err = repo.GetURL(ctx, "neg1234")
+	require.NoError(t, err)
+	assert.Equal(t, url.OriginalURL, result.OriginalURL)
+}
+
+func TestCacheRepository_WatchCreated(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	repo := redisrepo.NewURLCache(redisClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resets := make(chan struct{}, 1)
+	created := make(chan string, 1)
+	go repo.WatchCreated(ctx, func(shortCode string) {
+		created <- shortCode
+	}, func() {
+		resets <- struct{}{}
+	})
+
+	select {
+	case <-resets:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription was not established")
+	}
+
+	url := &domain.URL{ShortCode: "new1234", OriginalURL: "https://example.com", IsActive: true}
+	require.NoError(t, repo.AddURL(ctx, url, time.Hour))
+
+	select {
+	case shortCode := <-created:
+		assert.Equal(t, "new1234", shortCode)
+	case <-time.After(2 * time.Second):
+		t.Fatal("created code was not received")
+	}
+}
+
+func TestCacheRepository_WatchCreated_ResetsWhenSubscriptionDrops(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	repo := redisrepo.NewURLCache(redisClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resets := make(chan struct{}, 3)
+	go repo.WatchCreated(ctx, func(shortCode string) {}, func() {
+		resets <- struct{}{}
+	})
+
+	waitForReset := func(msg string) {
+		select {
+		case <-resets:
+		case <-time.After(5 * time.Second):
+			t.Fatal(msg)
+		}
+	}
+
+	waitForReset("subscription was not established")
+	mr.Close()
+	waitForReset("dropped subscription did not reset")
+	require.NoError(t, mr.Restart())
+	waitForReset("subscription was not established again")
+}
+
+func TestCacheRepository_SetURLs(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	repo := redisrepo.NewURLCache(redisClient)
+	ctx := context.Background()
+
+	urls := []*domain.URL{
+		{ID: 1, ShortCode: "warm001", OriginalURL: "https://example.com/1", IsActive: true},
+		{ID: 2, ShortCode: "warm002", OriginalURL: "https://example.com/2", IsActive: true},
+	}
+	require.NoError(t, repo.SetURLs(ctx, urls, func(url *domain.URL) time.Duration {
+		return time.Duration(url.ID) * time.Hour
+	}))
+
+	for _, url := range urls {
+		result, ttl, err := repo.GetURL(ctx, url.ShortCode)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, url.OriginalURL, result.OriginalURL)
+		assert.InDelta(t, time.Duration(url.ID)*time.Hour, ttl, float64(time.Second))
+	}
+}
+
 func TestCacheRepository_SetURL_WithExpiry(t *testing.T) {
 	redisClient, cleanup := setupTestRedis(t)
 	defer cleanup()
@@ -93,10 +260,11 @@ func TestCacheRepository_SetURL_WithExpiry(t *testing.T) {
 	err := repo.SetURL(ctx, url, ttl)
 	require.NoError(t, err)
 
-	result, err := repo.GetURL(ctx, "expiry123")
+	result, remaining, err := repo.GetURL(ctx, "expiry123")
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, url.ExpiresAt.Unix(), result.ExpiresAt.Unix())
+	assert.InDelta(t, ttl, remaining, float64(time.Second))
 }
 
 func TestCacheRepository_UpdateURL(t *testing.T) {
@@ -109,21 +277,23 @@ func TestCacheRepository_UpdateURL(t *testing.T) {
 	url := &domain.URL{
 		ShortCode:   "update123",
 		OriginalURL: "https://example.com",
-		ClickCount:  5,
+		Version:     1,
 		IsActive:    true,
 	}
 
 	err := repo.SetURL(ctx, url, 10*time.Minute)
 	require.NoError(t, err)
 
-	url.ClickCount = 10
+	url.OriginalURL = "https://example.com/updated"
+	url.Version = 2
 	err = repo.SetURL(ctx, url, 10*time.Minute)
 	require.NoError(t, err)
 
-	result, err := repo.GetURL(ctx, "update123")
+	result, _, err := repo.GetURL(ctx, "update123")
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
-	assert.Equal(t, int64(10), result.ClickCount)
+	assert.Equal(t, "https://example.com/updated", result.OriginalURL)
+	assert.Equal(t, 2, result.Version)
 }
 
 func TestCacheRepository_MultipleURLs(t *testing.T) {
@@ -145,7 +315,7 @@ func TestCacheRepository_MultipleURLs(t *testing.T) {
 	}
 
 	for _, url := range urls {
-		result, err := repo.GetURL(ctx, url.ShortCode)
+		result, _, err := repo.GetURL(ctx, url.ShortCode)
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, url.OriginalURL, result.OriginalURL)
@@ -171,7 +341,7 @@ func TestCacheRepository_ConcurrentAccess(t *testing.T) {
 	done := make(chan bool, 10)
 	for i := 0; i < 10; i++ {
 		go func() {
-			result, err := repo.GetURL(ctx, "concurrent")
+			result, _, err := repo.GetURL(ctx, "concurrent")
 			assert.NoError(t, err)
 			assert.NotNil(t, result)
 			done <- true
@@ -194,11 +364,31 @@ func TestCacheRepository_InvalidJSON(t *testing.T) {
 
 	repo := redisrepo.NewURLCache(redisClient)
 
-	result, err := repo.GetURL(ctx, "invalid")
+	result, _, err := repo.GetURL(ctx, "invalid")
 	assert.Error(t, err, "Should return error for invalid JSON")
+	assert.ErrorIs(t, err, domain.ErrInvalidCacheEntry)
 	assert.Nil(t, result)
 }
 
+func TestCacheRepository_LegacyJSON(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// written by a replica from before the binary encoding
+	err := redisClient.Set(ctx, "url:legacy", `{"id":7,"short_code":"legacy","original_url":"https://example.com","is_active":true,"version":3}`, 10*time.Minute).Err()
+	require.NoError(t, err)
+
+	repo := redisrepo.NewURLCache(redisClient)
+
+	result, _, err := repo.GetURL(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.ID)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
+	assert.Equal(t, 3, result.Version)
+}
+
 func TestCacheRepository_LargePayload(t *testing.T) {
 	redisClient, cleanup := setupTestRedis(t)
 	defer cleanup()
@@ -216,7 +406,7 @@ func TestCacheRepository_LargePayload(t *testing.T) {
 	err := repo.SetURL(ctx, url, 10*time.Minute)
 	require.NoError(t, err)
 
-	result, err := repo.GetURL(ctx, "large")
+	result, _, err := repo.GetURL(ctx, "large")
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, longURL, result.OriginalURL)
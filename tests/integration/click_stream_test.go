@@ -0,0 +1,87 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	redisrepo "github.com/gamassss/url-shortener/internal/repository/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStream_PublishAndSubscribe(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	stream := redisrepo.NewClickStream(redisClient)
+	defer stream.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := stream.Subscribe(ctx, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Publish(ctx, 2, &domain.ClickEvent{TotalClicks: 99}))
+	require.NoError(t, stream.Publish(ctx, 1, &domain.ClickEvent{
+		DeviceType:  "mobile",
+		Referer:     "https://twitter.com",
+		CountryCode: "ID",
+		TotalClicks: 42,
+	}))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "mobile", event.DeviceType)
+		assert.Equal(t, "https://twitter.com", event.Referer)
+		assert.Equal(t, "ID", event.CountryCode)
+		assert.Equal(t, int64(42), event.TotalClicks)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for click event")
+	}
+}
+
+func TestClickStream_ClosesSubscriptions(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	stream := redisrepo.NewClickStream(redisClient)
+	ctx := context.Background()
+
+	events, err := stream.Subscribe(ctx, 1)
+	require.NoError(t, err)
+
+	stream.Close()
+
+	_, open := <-events
+	assert.False(t, open)
+
+	_, err = stream.Subscribe(ctx, 1)
+	assert.ErrorIs(t, err, redisrepo.ErrClickStreamClosed)
+}
+
+func TestClickStream_EndsOnContextCancel(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	stream := redisrepo.NewClickStream(redisClient)
+	defer stream.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := stream.Subscribe(ctx, 1)
+	require.NoError(t, err)
+
+	cancel()
+
+	select {
+	case _, open := <-events:
+		assert.False(t, open)
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription was not closed after cancel")
+	}
+}
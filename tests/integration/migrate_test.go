@@ -0,0 +1,134 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gamassss/url-shortener/migrations"
+	"github.com/gamassss/url-shortener/pkg/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_DownAndUp(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.Pending)
+	latest := status.Version
+
+	undone, err := migrator.Down(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, undone, 2)
+	assert.Equal(t, latest, undone[0].Version)
+
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Len(t, status.Pending, 2)
+	assert.Less(t, status.Version, undone[1].Version)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, latest, status.Version)
+}
+
+func TestMigrator_DownToEmpty(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = migrator.Down(ctx, 1000)
+	require.NoError(t, err)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, status.Version)
+
+	var exists bool
+	require.NoError(t, db.QueryRow(ctx, "SELECT to_regclass('urls') IS NOT NULL").Scan(&exists))
+	assert.False(t, exists)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, status.Pending, applied)
+}
+
+func TestMigrator_DirtyNeedsForce(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// as golang-migrate leaves a migration that failed partway through
+	_, err = db.Exec(ctx, "UPDATE schema_migrations SET dirty = true")
+	require.NoError(t, err)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Dirty)
+
+	_, err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, migrate.ErrDirty)
+
+	require.NoError(t, migrator.Force(ctx, status.Version))
+	assert.Error(t, migrator.Force(ctx, 999999), "Unknown versions should be rejected")
+
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+}
+
+func TestMigrator_ConcurrentRunners(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.Exec(ctx, "DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	require.NoError(t, err)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	pending := len(status.Pending)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := migrator.Up(ctx)
+			assert.NoError(t, err)
+			mu.Lock()
+			total += len(applied)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.Pending)
+	assert.Equal(t, pending, total, "Each migration should run exactly once")
+}
@@ -0,0 +1,154 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gamassss/url-shortener/internal/domain"
+	redisrepo "github.com/gamassss/url-shortener/internal/repository/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The sentinel and cluster tests need the servers started by
+// scripts/redis-topologies.sh and are skipped without them.
+
+func TestTopology_Standalone(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	defer client.Close()
+
+	testTopology(t, client, false)
+}
+
+func TestTopology_Sentinel(t *testing.T) {
+	addrs := os.Getenv("REDIS_SENTINEL_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_SENTINEL_ADDRS not set")
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:      strings.Split(addrs, ","),
+		MasterName: os.Getenv("REDIS_SENTINEL_MASTER"),
+	})
+	defer client.Close()
+
+	_, isFailover := client.(*redis.Client)
+	require.True(t, isFailover, "sentinel options should build a failover client")
+	testTopology(t, client, true)
+}
+
+func TestTopology_Cluster(t *testing.T) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS not set")
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:         strings.Split(addrs, ","),
+		IsClusterMode: true,
+	})
+	defer client.Close()
+
+	_, isCluster := client.(*redis.ClusterClient)
+	require.True(t, isCluster)
+	testTopology(t, client, true)
+}
+
+// testTopology runs the cache operations against client. miniredis only
+// imitates HyperLogLogs, so the visitor sketches need realRedis.
+func testTopology(t *testing.T, client redis.UniversalClient, realRedis bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, client.Ping(ctx).Err())
+
+	// unique per run, since real servers keep keys between runs
+	suffix := time.Now().UnixNano()
+	shortCode := fmt.Sprintf("topo%d", suffix)
+
+	t.Run("url cache", func(t *testing.T) {
+		repo := redisrepo.NewURLCache(client)
+		url := &domain.URL{ID: 1, ShortCode: shortCode, OriginalURL: "https://example.com", IsActive: true}
+
+		require.NoError(t, repo.SetNotFound(ctx, shortCode, time.Minute))
+		require.NoError(t, repo.AddURL(ctx, url, time.Hour))
+
+		result, ttl, err := repo.GetURL(ctx, shortCode)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, url.OriginalURL, result.OriginalURL)
+		assert.Greater(t, ttl, 59*time.Minute)
+
+		require.NoError(t, repo.DeleteURL(ctx, shortCode))
+
+		result, _, err = repo.GetURL(ctx, shortCode)
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("invalidations", func(t *testing.T) {
+		repo := redisrepo.NewURLCache(client)
+		watchCtx, stop := context.WithCancel(ctx)
+		defer stop()
+
+		resets := make(chan struct{}, 1)
+		invalidated := make(chan string, 1)
+		go repo.WatchInvalidations(watchCtx, func(code string) {
+			invalidated <- code
+		}, func() {
+			resets <- struct{}{}
+		})
+
+		select {
+		case <-resets:
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscription was not established")
+		}
+
+		require.NoError(t, repo.DeleteURL(ctx, shortCode))
+
+		select {
+		case code := <-invalidated:
+			assert.Equal(t, shortCode, code)
+		case <-time.After(5 * time.Second):
+			t.Fatal("invalidation was not received")
+		}
+	})
+
+	t.Run("visitor sketches", func(t *testing.T) {
+		if !realRedis {
+			t.Skip("needs a real Redis server")
+		}
+		cache := redisrepo.NewVisitorSketchCache(client)
+		urlID := suffix
+		days := []string{"2026-01-01", "2026-01-02"}
+
+		require.NoError(t, cache.Add(ctx, urlID, days[0], "visitor-a"))
+		require.NoError(t, cache.Add(ctx, urlID, days[0], "visitor-b"))
+		require.NoError(t, cache.Add(ctx, urlID, days[1], "visitor-a"))
+
+		count, err := cache.Count(ctx, urlID, days)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		data, err := cache.Get(ctx, urlID, days[0])
+		require.NoError(t, err)
+		require.NoError(t, cache.Merge(ctx, &domain.VisitorSketch{URLID: urlID, Day: days[1], Data: data}))
+
+		count, err = cache.Count(ctx, urlID, days[1:])
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+}
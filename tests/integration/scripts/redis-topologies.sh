@@ -0,0 +1,69 @@
+#!/bin/bash
+# Starts a local three-node Redis Cluster and a master, replica and sentinel,
+# for the topology integration tests. Needs redis-server and redis-cli.
+#
+#   tests/integration/scripts/redis-topologies.sh up
+#   REDIS_CLUSTER_ADDRS=127.0.0.1:7000,127.0.0.1:7001,127.0.0.1:7002 \
+#   REDIS_SENTINEL_ADDRS=127.0.0.1:26379 REDIS_SENTINEL_MASTER=mymaster \
+#     go test -tags=integration -run Topology ./tests/integration/...
+#   tests/integration/scripts/redis-topologies.sh down
+
+set -e
+
+DIR="${REDIS_TOPOLOGY_DIR:-/tmp/url-shortener-redis}"
+CLUSTER_PORTS="7000 7001 7002"
+MASTER_PORT=6380
+REPLICA_PORT=6381
+SENTINEL_PORT=26379
+
+up() {
+    mkdir -p "$DIR"
+
+    for port in $CLUSTER_PORTS; do
+        mkdir -p "$DIR/$port"
+        redis-server --port "$port" --dir "$DIR/$port" --daemonize yes \
+            --cluster-enabled yes --cluster-config-file nodes.conf \
+            --appendonly no --save "" --pidfile "$DIR/$port.pid"
+    done
+
+    mkdir -p "$DIR/$MASTER_PORT" "$DIR/$REPLICA_PORT" "$DIR/sentinel"
+    redis-server --port "$MASTER_PORT" --dir "$DIR/$MASTER_PORT" --daemonize yes \
+        --save "" --pidfile "$DIR/$MASTER_PORT.pid"
+    redis-server --port "$REPLICA_PORT" --dir "$DIR/$REPLICA_PORT" --daemonize yes \
+        --save "" --pidfile "$DIR/$REPLICA_PORT.pid" --replicaof 127.0.0.1 "$MASTER_PORT"
+
+    cat > "$DIR/sentinel/sentinel.conf" <<CONF
+port $SENTINEL_PORT
+sentinel monitor mymaster 127.0.0.1 $MASTER_PORT 1
+sentinel down-after-milliseconds mymaster 1000
+sentinel failover-timeout mymaster 5000
+CONF
+    redis-server "$DIR/sentinel/sentinel.conf" --sentinel --daemonize yes \
+        --pidfile "$DIR/sentinel.pid"
+
+    sleep 1
+    addrs=""
+    for port in $CLUSTER_PORTS; do
+        addrs="$addrs 127.0.0.1:$port"
+    done
+    # shellcheck disable=SC2086
+    redis-cli --cluster create $addrs --cluster-replicas 0 --cluster-yes > /dev/null
+
+    until [ "$(redis-cli -p 7000 cluster info | grep -c 'cluster_state:ok')" = 1 ]; do
+        sleep 0.5
+    done
+    echo "Redis Cluster on ports $CLUSTER_PORTS, sentinel on port $SENTINEL_PORT"
+}
+
+down() {
+    for pidfile in "$DIR"/*.pid; do
+        [ -f "$pidfile" ] && kill "$(cat "$pidfile")" 2> /dev/null || true
+    done
+    rm -rf "$DIR"
+}
+
+case "$1" in
+    up) up ;;
+    down) down ;;
+    *) echo "usage: $0 up|down" >&2; exit 1 ;;
+esac
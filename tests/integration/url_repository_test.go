@@ -6,13 +6,13 @@ package integration
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gamassss/url-shortener/internal/domain"
 	"github.com/gamassss/url-shortener/internal/repository/postgres"
+	"github.com/gamassss/url-shortener/migrations"
+	"github.com/gamassss/url-shortener/pkg/migrate"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -54,13 +54,11 @@ func setupTestDatabase(t *testing.T) (*pgxpool.Pool, func()) {
 }
 
 func applyMigration(ctx context.Context, db *pgxpool.Pool) error {
-	migrationPath := filepath.Join("..", "..", "migrations", "0001_create_urls_table.up.sql")
-	migrationSQL, err := os.ReadFile(migrationPath)
+	migrator, err := migrate.New(db, migrations.FS)
 	if err != nil {
 		return err
 	}
-
-	_, err = db.Exec(ctx, string(migrationSQL))
+	_, err = migrator.Up(ctx)
 	return err
 }
 
@@ -68,7 +66,7 @@ func TestURLRepository_Create_Success(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	url := &domain.URL{
@@ -89,7 +87,7 @@ func TestURLRepository_Create_WithExpiry(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	expiresAt := time.Now().Add(24 * time.Hour)
@@ -110,7 +108,7 @@ func TestURLRepository_Create_DuplicateShortCode(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	url1 := &domain.URL{
@@ -136,7 +134,7 @@ func TestURLRepository_GetByShortCode_Success(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	url := &domain.URL{
@@ -161,7 +159,7 @@ func TestURLRepository_GetByShortCode_NotFound(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	result, err := repo.GetByShortCode(ctx, "notfound")
@@ -174,7 +172,7 @@ func TestURLRepository_ConcurrentCreation(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	urls := []string{"url1", "url2", "url3", "url4", "url5"}
@@ -207,7 +205,7 @@ func TestURLRepository_ExpiredURL(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	expiresAt := time.Now().Add(-24 * time.Hour)
@@ -239,7 +237,7 @@ func TestURLRepository_BulkOperations(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	count := 100
@@ -275,7 +273,7 @@ func TestURLRepository_MultipleReads(t *testing.T) {
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo := postgres.NewURLRepository(db)
+	repo := postgres.NewURLRepository(db, nil)
 	ctx := context.Background()
 
 	url := &domain.URL{
@@ -293,3 +291,159 @@ func TestURLRepository_MultipleReads(t *testing.T) {
 		assert.Equal(t, "https://example.com", result.OriginalURL)
 	}
 }
+
+func TestURLRepository_VersionHistoryAndRollback(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo := postgres.NewURLRepository(db, nil)
+	ctx := context.Background()
+
+	url := &domain.URL{
+		ShortCode:   "ver1234",
+		OriginalURL: "https://example.com/v1",
+		IsActive:    true,
+	}
+	require.NoError(t, repo.Create(ctx, url))
+	assert.Equal(t, 1, url.Version)
+
+	destination := "https://example.com/v2"
+	updated, err := repo.Update(ctx, "ver1234", &domain.UpdateURLRequest{OriginalURL: &destination, ChangedBy: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	title := "Renamed"
+	updated, err = repo.Update(ctx, "ver1234", &domain.UpdateURLRequest{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version, "title changes should not create a version")
+
+	rolledBack, err := repo.Rollback(ctx, "ver1234", 1, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 3, rolledBack.Version)
+	assert.Equal(t, "https://example.com/v1", rolledBack.OriginalURL)
+
+	versions, err := repo.ListVersions(ctx, "ver1234")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, domain.ChangeRollback, versions[0].ChangeType)
+	assert.Equal(t, 1, *versions[0].RestoredVersion)
+	assert.Equal(t, "bob", versions[0].ChangedBy)
+	assert.Equal(t, "https://example.com/v2", versions[1].OriginalURL)
+	assert.Equal(t, "alice", versions[1].ChangedBy)
+
+	_, err = repo.Rollback(ctx, "ver1234", 9, "")
+	assert.ErrorIs(t, err, domain.ErrVersionNotFound)
+}
+
+func TestURLRepository_ListHot(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo := postgres.NewURLRepository(db, nil)
+	ctx := context.Background()
+
+	clicks := map[string]struct {
+		recent, old int
+	}{
+		"hot0001": {recent: 5},
+		"hot0002": {recent: 2},
+		"cold001": {old: 10},
+		"gone001": {recent: 20},
+	}
+	for code, n := range clicks {
+		url := &domain.URL{ShortCode: code, OriginalURL: "https://example.com/" + code, IsActive: true}
+		require.NoError(t, repo.Create(ctx, url))
+
+		_, err := db.Exec(ctx, `
+			INSERT INTO url_clicks (url_id, clicked_at)
+			SELECT $1, NOW() - INTERVAL '1 minute' FROM generate_series(1, $2)
+			UNION ALL
+			SELECT $1, NOW() - INTERVAL '3 days' FROM generate_series(1, $3)
+		`, url.ID, n.recent, n.old)
+		require.NoError(t, err)
+	}
+	_, err := db.Exec(ctx, `UPDATE urls SET is_active = false WHERE short_code = 'gone001'`)
+	require.NoError(t, err)
+
+	urls, err := repo.ListHot(ctx, time.Now().Add(-24*time.Hour), 10)
+	require.NoError(t, err)
+
+	codes := make([]string, len(urls))
+	for i, url := range urls {
+		codes[i] = url.ShortCode
+	}
+	assert.Equal(t, []string{"hot0001", "hot0002"}, codes)
+
+	urls, err = repo.ListHot(ctx, time.Now().Add(-24*time.Hour), 1)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "hot0001", urls[0].ShortCode)
+}
+
+// setupTestReplica creates a second database next to db's to stand in for a
+// read replica that hasn't received the primary's writes.
+func setupTestReplica(t *testing.T, db *pgxpool.Pool) *pgxpool.Pool {
+	ctx := context.Background()
+
+	_, err := db.Exec(ctx, "CREATE DATABASE replicadb")
+	require.NoError(t, err)
+
+	config := db.Config().Copy()
+	config.ConnConfig.Database = "replicadb"
+	replica, err := pgxpool.NewWithConfig(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(replica.Close)
+
+	require.NoError(t, applyMigration(ctx, replica))
+	return replica
+}
+
+func TestURLRepository_ReadsFromReplica(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	replica := setupTestReplica(t, db)
+	replicas := postgres.NewReplicaSet(db, []*pgxpool.Pool{replica}, 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replicas.Run(ctx, time.Hour)
+	require.Eventually(t, func() bool { return replicas.Healthy() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	repo := postgres.NewURLRepository(db, replicas)
+
+	// only on the replica, so listing must have read it there
+	_, err := replica.Exec(ctx, "INSERT INTO urls (short_code, original_url) VALUES ('replica1', 'https://example.com/replica')")
+	require.NoError(t, err)
+
+	list, err := repo.List(ctx, &domain.LinkSet{}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list.URLs, 1)
+	assert.Equal(t, "replica1", list.URLs[0].ShortCode)
+
+	// just created, so the replica doesn't have it yet
+	url := &domain.URL{ShortCode: "fresh01", OriginalURL: "https://example.com/fresh"}
+	require.NoError(t, repo.Create(ctx, url))
+
+	found, err := repo.GetByShortCode(ctx, "fresh01")
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, found.ID)
+}
+
+func TestURLRepository_ListByOwner(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo := postgres.NewURLRepository(db, nil)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "alice01", OriginalURL: "https://example.com/a", Owner: "alice"}))
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "bob0001", OriginalURL: "https://example.com/b", Owner: "bob"}))
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "nobody1", OriginalURL: "https://example.com/c"}))
+
+	list, err := repo.List(ctx, &domain.LinkSet{Owner: "alice"}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list.URLs, 1)
+	assert.Equal(t, "alice01", list.URLs[0].ShortCode)
+	assert.Equal(t, "alice", list.URLs[0].Owner)
+}
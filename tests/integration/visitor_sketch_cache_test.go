@@ -0,0 +1,52 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	redisrepo "github.com/gamassss/url-shortener/internal/repository/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitorSketchCache_AllTimeSketchNeverExpires(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := redisrepo.NewVisitorSketchCache(redisClient)
+	ctx := context.Background()
+
+	// as left by a version that expired the all-time sketch too
+	require.NoError(t, redisClient.PFAdd(ctx, "hll:{url:1}", "visitor-a").Err())
+	require.NoError(t, redisClient.Expire(ctx, "hll:{url:1}", time.Hour).Err())
+
+	require.NoError(t, cache.Add(ctx, 1, "2026-01-15", "visitor-b"))
+
+	ttl, err := redisClient.TTL(ctx, "hll:{url:1}").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl, "All-time sketch should have no expiry")
+
+	ttl, err = redisClient.TTL(ctx, "hll:{url:1}:2026-01-15").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 7*24*time.Hour)
+}
+
+func TestVisitorSketchCache_ExpiredDayIsMissing(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := redisrepo.NewVisitorSketchCache(redisClient)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Add(ctx, 1, "2026-01-15", "visitor-a"))
+	require.NoError(t, redisClient.Del(ctx, "hll:{url:1}:2026-01-15").Err())
+
+	missing, err := cache.Missing(ctx, 1, []string{domain.AllTimeSketch, "2026-01-15"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-01-15"}, missing)
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/gamassss/url-shortener/internal/config"
+	"github.com/gamassss/url-shortener/migrations"
+	"github.com/gamassss/url-shortener/pkg/migrate"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -46,8 +48,8 @@ func main() {
 
 	gen := &DataGenerator{pool: pool}
 
-	if err := gen.createTable(ctx); err != nil {
-		log.Fatalf("Failed to create table: %v\n", err)
+	if err := gen.migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v\n", err)
 	}
 
 	if err := gen.clearData(ctx); err != nil {
@@ -66,8 +68,8 @@ func main() {
 		log.Fatalf("Failed to insert cold URLs: %v\n", err)
 	}
 
-	if err := gen.createIndexes(ctx); err != nil {
-		log.Fatalf("Failed to create indexes: %v\n", err)
+	if err := gen.analyze(ctx); err != nil {
+		log.Fatalf("Failed to analyze table: %v\n", err)
 	}
 
 	if err := gen.verifyData(ctx); err != nil {
@@ -75,20 +77,18 @@ func main() {
 	}
 }
 
-func (g *DataGenerator) createTable(ctx context.Context) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS urls (
-		id BIGSERIAL PRIMARY KEY,
-		short_code VARCHAR(20) UNIQUE NOT NULL,
-		original_url TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT NOW()
-	)`
-	_, err := g.pool.Exec(ctx, query)
+// migrate creates the schema the API runs against, with its indexes.
+func (g *DataGenerator) migrate(ctx context.Context) error {
+	migrator, err := migrate.New(g.pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
 	return err
 }
 
 func (g *DataGenerator) clearData(ctx context.Context) error {
-	_, err := g.pool.Exec(ctx, "TRUNCATE urls RESTART IDENTITY")
+	_, err := g.pool.Exec(ctx, "TRUNCATE urls RESTART IDENTITY CASCADE")
 	return err
 }
 
@@ -236,23 +236,9 @@ func (g *DataGenerator) insertColdURLsBatch(ctx context.Context, start, end int,
 	return nil
 }
 
-func (g *DataGenerator) createIndexes(ctx context.Context) error {
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code)",
-		"CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at)",
-	}
-
-	for _, query := range indexes {
-		if _, err := g.pool.Exec(ctx, query); err != nil {
-			return err
-		}
-	}
-
-	if _, err := g.pool.Exec(ctx, "ANALYZE urls"); err != nil {
-		return err
-	}
-
-	return nil
+func (g *DataGenerator) analyze(ctx context.Context) error {
+	_, err := g.pool.Exec(ctx, "ANALYZE urls")
+	return err
 }
 
 func (g *DataGenerator) verifyData(ctx context.Context) error {
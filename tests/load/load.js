@@ -5,6 +5,8 @@ import { textSummary } from 'https://jslib.k6.io/k6-summary/0.0.1/index.js';
 
 const cacheHits = new Counter('cache_hits');
 const cacheMisses = new Counter('cache_misses');
+const l1Hits = new Counter('cache_l1_hits');
+const l2Hits = new Counter('cache_l2_hits');
 const redirectLatency = new Trend('redirect_latency');
 const dbLatency = new Trend('db_latency');
 const cacheLatency = new Trend('cache_latency');
@@ -79,8 +81,15 @@ export default function () {
             'not timeout': (r) => r.status !== 0,
         });
 
-        const cacheHit = res.headers['X-Cache-Hit'] === 'true';
-        if (cacheHit) {
+        // X-Cache-Hit is the tier that served the redirect: l1, l2 or miss
+        const cacheTier = res.headers['X-Cache-Hit'];
+        if (cacheTier === 'l1') {
+            l1Hits.add(1);
+        } else if (cacheTier === 'l2') {
+            l2Hits.add(1);
+        }
+
+        if (cacheTier === 'l1' || cacheTier === 'l2') {
             cacheHits.add(1);
             cacheLatency.add(res.timings.duration);
         } else {
@@ -111,17 +120,23 @@ export default function () {
 }
 
 export function handleSummary(data) {
-    const totalRequests = data.metrics.cache_hits.values.count +
-        data.metrics.cache_misses.values.count;
-    const cacheHitRate = totalRequests > 0
-        ? (data.metrics.cache_hits.values.count / totalRequests * 100).toFixed(2)
+    const totalRequests = (data.metrics.cache_hits?.values?.count || 0) +
+        (data.metrics.cache_misses?.values?.count || 0);
+    const rate = (metric) => totalRequests > 0
+        ? ((data.metrics[metric]?.values?.count || 0) / totalRequests * 100).toFixed(2)
         : '0.00';
+    const cacheHitRate = rate('cache_hits');
+    const l1HitRate = rate('cache_l1_hits');
+    const l2HitRate = rate('cache_l2_hits');
 
     const avgRedirectLatency = data.metrics.redirect_latency?.values?.avg?.toFixed(2) || 'N/A';
     const p95RedirectLatency = data.metrics.redirect_latency?.values?.['p(95)']?.toFixed(2) || 'N/A';
 
+    const cacheSummary = `\n Cache hit rate: ${cacheHitRate}% (L1 ${l1HitRate}%, L2 ${l2HitRate}%)\n` +
+        ` Redirect latency: avg ${avgRedirectLatency}ms, p95 ${p95RedirectLatency}ms\n`;
+
     return {
-        'stdout': textSummary(data, { indent: ' ', enableColors: true }),
+        'stdout': textSummary(data, { indent: ' ', enableColors: true }) + cacheSummary,
         'summary.json': JSON.stringify(data, null, 2),
     };
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAnalyticsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAnalyticsRepository) RecordClick(ctx context.Context, click *domain.ClickRequest) (*domain.ClickEvent, error) {
+	args := m.Called(ctx, click)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ClickEvent), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) GetAnalytics(ctx context.Context, urlID int64, q *domain.AnalyticsQuery) (*domain.URLAnalytics, error) {
+	args := m.Called(ctx, urlID, q)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLAnalytics), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) CountClicks(ctx context.Context, urlID int64, from, to time.Time, includeBots bool) (int64, error) {
+	args := m.Called(ctx, urlID, from, to, includeBots)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) GetClickHistory(ctx context.Context, filter *domain.ClickFilter, page, pageSize int) (*domain.ClickHistory, error) {
+	args := m.Called(ctx, filter, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ClickHistory), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) GetClickHistoryAfter(ctx context.Context, filter *domain.ClickFilter, pageSize int) (*domain.ClickHistory, error) {
+	args := m.Called(ctx, filter, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ClickHistory), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) ExportClicks(ctx context.Context, filter *domain.ClickFilter, fn func(*domain.URLClick) error) error {
+	args := m.Called(ctx, filter, fn)
+	return args.Error(0)
+}
+
+func (m *MockAnalyticsRepository) GetOverview(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet) (*domain.AnalyticsOverview, error) {
+	args := m.Called(ctx, q, links)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AnalyticsOverview), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) CountOverviewClicks(ctx context.Context, timeRange *domain.TimeRange, includeBots bool, links *domain.LinkSet) (int64, error) {
+	args := m.Called(ctx, timeRange, includeBots, links)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) GetTopLinks(ctx context.Context, q *domain.AnalyticsQuery, links *domain.LinkSet, limit int) ([]domain.LinkStats, error) {
+	args := m.Called(ctx, q, links, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.LinkStats), args.Error(1)
+}
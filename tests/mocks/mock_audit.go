@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditor struct {
+	mock.Mock
+}
+
+func (m *MockAuditor) Record(ctx context.Context, action, target string, before, after any) {
+	m.Called(ctx, action, target, before, after)
+}
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, filter *domain.AuditFilter, page, pageSize int) (*domain.AuditLog, error) {
+	args := m.Called(ctx, filter, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AuditLog), args.Error(1)
+}
+
+type MockAuditSink struct {
+	mock.Mock
+}
+
+func (m *MockAuditSink) Write(event *domain.AuditEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
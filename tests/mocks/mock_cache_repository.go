@@ -12,15 +12,61 @@ type MockCacheRepository struct {
 	mock.Mock
 }
 
-func (m *MockCacheRepository) GetURL(ctx context.Context, shortCode string) (*domain.URL, error) {
+func (m *MockCacheRepository) GetURL(ctx context.Context, shortCode string) (*domain.URL, time.Duration, error) {
 	args := m.Called(ctx, shortCode)
+	ttl, _ := args.Get(1).(time.Duration)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, ttl, args.Error(2)
 	}
-	return args.Get(0).(*domain.URL), args.Error(1)
+	return args.Get(0).(*domain.URL), ttl, args.Error(2)
 }
 
 func (m *MockCacheRepository) SetURL(ctx context.Context, url *domain.URL, ttl time.Duration) error {
 	args := m.Called(ctx, url, ttl)
 	return args.Error(0)
 }
+
+func (m *MockCacheRepository) DeleteURL(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockCacheRepository) AddURL(ctx context.Context, url *domain.URL, ttl time.Duration) error {
+	args := m.Called(ctx, url, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCacheRepository) SetNotFound(ctx context.Context, shortCode string, ttl time.Duration) error {
+	args := m.Called(ctx, shortCode, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCacheRepository) SetURLs(ctx context.Context, urls []*domain.URL, ttl func(url *domain.URL) time.Duration) error {
+	args := m.Called(ctx, urls)
+	return args.Error(0)
+}
+
+type MockLocalCache struct {
+	mock.Mock
+}
+
+func (m *MockLocalCache) Get(shortCode string) (*domain.URL, bool) {
+	args := m.Called(shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1)
+	}
+	return args.Get(0).(*domain.URL), args.Bool(1)
+}
+
+func (m *MockLocalCache) Set(url *domain.URL) {
+	m.Called(url)
+}
+
+func (m *MockLocalCache) Delete(shortCode string) {
+	m.Called(shortCode)
+}
+
+func (m *MockLocalCache) Stats() (int, int64) {
+	args := m.Called()
+	return args.Int(0), args.Get(1).(int64)
+}
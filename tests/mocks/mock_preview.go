@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/metadata"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPageFetcher struct {
+	mock.Mock
+}
+
+func (m *MockPageFetcher) Fetch(ctx context.Context, pageURL string) (*metadata.Page, error) {
+	args := m.Called(ctx, pageURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*metadata.Page), args.Error(1)
+}
+
+type MockPreviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockPreviewRepository) SavePreview(ctx context.Context, urlID int64, destination string, preview *domain.LinkPreview) error {
+	args := m.Called(ctx, urlID, destination, preview)
+	return args.Error(0)
+}
+
+type MockPreviewQueue struct {
+	mock.Mock
+}
+
+func (m *MockPreviewQueue) Enqueue(ctx context.Context, url *domain.URL) {
+	m.Called(ctx, url)
+}
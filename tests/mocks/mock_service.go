@@ -13,7 +13,7 @@ type MockShortenerService struct {
 
 var _ interface {
 	ShortenURL(ctx context.Context, req *domain.CreatedURLRequest) (*domain.URL, error)
-	GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, bool, error)
+	GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, string, error)
 } = (*MockShortenerService)(nil)
 
 func (m *MockShortenerService) ShortenURL(ctx context.Context, req *domain.CreatedURLRequest) (*domain.URL, error) {
@@ -24,10 +24,60 @@ func (m *MockShortenerService) ShortenURL(ctx context.Context, req *domain.Creat
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
-func (m *MockShortenerService) GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, bool, error) {
+func (m *MockShortenerService) GetOriginalURL(ctx context.Context, shortCode string) (*domain.URL, string, error) {
 	args := m.Called(ctx, shortCode)
 	if args.Get(0) == nil {
-		return nil, false, args.Error(1)
+		return nil, "", args.Error(1)
 	}
-	return args.Get(0).(*domain.URL), false, args.Error(1)
+	return args.Get(0).(*domain.URL), domain.CacheTierMiss, args.Error(1)
+}
+
+func (m *MockShortenerService) CacheStats() *domain.CacheStats {
+	args := m.Called()
+	return args.Get(0).(*domain.CacheStats)
+}
+
+func (m *MockShortenerService) RecordClick(ctx context.Context, click *domain.ClickRequest) error {
+	args := m.Called(ctx, click)
+	return args.Error(0)
+}
+
+func (m *MockShortenerService) ListURLs(ctx context.Context, links *domain.LinkSet, page, pageSize int) (*domain.URLList, error) {
+	args := m.Called(ctx, links, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLList), args.Error(1)
+}
+
+func (m *MockShortenerService) GetSocialCard(ctx context.Context, shortCode string) (*domain.SocialCard, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SocialCard), args.Error(1)
+}
+
+func (m *MockShortenerService) UpdateURL(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockShortenerService) GetURLHistory(ctx context.Context, shortCode string) ([]domain.URLVersion, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URLVersion), args.Error(1)
+}
+
+func (m *MockShortenerService) RollbackURL(ctx context.Context, shortCode string, version int, changedBy string) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode, version, changedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
 }
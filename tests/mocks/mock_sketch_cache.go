@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSketchCache struct {
+	mock.Mock
+}
+
+func (m *MockSketchCache) Add(ctx context.Context, urlID int64, day, visitor string) error {
+	args := m.Called(ctx, urlID, day, visitor)
+	return args.Error(0)
+}
+
+func (m *MockSketchCache) Count(ctx context.Context, urlID int64, days []string) (int64, error) {
+	args := m.Called(ctx, urlID, days)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSketchCache) CountEach(ctx context.Context, urlID int64, days []string) ([]int64, error) {
+	args := m.Called(ctx, urlID, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockSketchCache) Missing(ctx context.Context, urlID int64, days []string) ([]string, error) {
+	args := m.Called(ctx, urlID, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockSketchCache) Get(ctx context.Context, urlID int64, day string) ([]byte, error) {
+	args := m.Called(ctx, urlID, day)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockSketchCache) Merge(ctx context.Context, sketch *domain.VisitorSketch) error {
+	args := m.Called(ctx, sketch)
+	return args.Error(0)
+}
+
+func (m *MockSketchCache) MarkDirty(ctx context.Context, urlID int64, day string) error {
+	args := m.Called(ctx, urlID, day)
+	return args.Error(0)
+}
+
+func (m *MockSketchCache) PopDirty(ctx context.Context, count int64) ([]domain.VisitorSketch, error) {
+	args := m.Called(ctx, count)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.VisitorSketch), args.Error(1)
+}
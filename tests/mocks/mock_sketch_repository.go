@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSketchRepository struct {
+	mock.Mock
+}
+
+func (m *MockSketchRepository) SaveSketch(ctx context.Context, sketch *domain.VisitorSketch) error {
+	args := m.Called(ctx, sketch)
+	return args.Error(0)
+}
+
+func (m *MockSketchRepository) GetSketches(ctx context.Context, urlID int64, days []string) ([]domain.VisitorSketch, error) {
+	args := m.Called(ctx, urlID, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.VisitorSketch), args.Error(1)
+}
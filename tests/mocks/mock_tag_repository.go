@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagRepository struct {
+	mock.Mock
+}
+
+func (m *MockTagRepository) List(ctx context.Context) ([]domain.Tag, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) Rename(ctx context.Context, name, newName string) error {
+	args := m.Called(ctx, name, newName)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Merge(ctx context.Context, source, target string) error {
+	args := m.Called(ctx, source, target)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Delete(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
@@ -23,3 +23,43 @@ func (m *MockURLRepository) GetByShortCode(ctx context.Context, shortCode string
 	}
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
+
+func (m *MockURLRepository) GetDetails(ctx context.Context, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) List(ctx context.Context, links *domain.LinkSet, page, pageSize int) (*domain.URLList, error) {
+	args := m.Called(ctx, links, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLList), args.Error(1)
+}
+
+func (m *MockURLRepository) Update(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) Rollback(ctx context.Context, shortCode string, version int, changedBy string) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode, version, changedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) ListVersions(ctx context.Context, shortCode string) ([]domain.URLVersion, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URLVersion), args.Error(1)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockVisitorCounter struct {
+	mock.Mock
+}
+
+func (m *MockVisitorCounter) Track(ctx context.Context, urlID int64, visitor string, at time.Time) error {
+	args := m.Called(ctx, urlID, visitor, at)
+	return args.Error(0)
+}
+
+func (m *MockVisitorCounter) CountTotal(ctx context.Context, urlID int64) (int64, error) {
+	args := m.Called(ctx, urlID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockVisitorCounter) CountRange(ctx context.Context, urlID int64, from, to time.Time) (int64, error) {
+	args := m.Called(ctx, urlID, from, to)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockVisitorCounter) CountByDay(ctx context.Context, urlID int64, days []string) (map[string]int64, error) {
+	args := m.Called(ctx, urlID, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
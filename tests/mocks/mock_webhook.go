@@ -0,0 +1,105 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/gamassss/url-shortener/internal/domain"
+	"github.com/gamassss/url-shortener/pkg/webhook"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLinkEvents struct {
+	mock.Mock
+}
+
+func (m *MockLinkEvents) LinkCreated(ctx context.Context, url *domain.URL) {
+	m.Called(ctx, url)
+}
+
+func (m *MockLinkEvents) LinkClicked(ctx context.Context, urlID int64, shortCode string, totalClicks int64) {
+	m.Called(ctx, urlID, shortCode, totalClicks)
+}
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, hook *domain.Webhook) error {
+	args := m.Called(ctx, hook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) List(ctx context.Context) ([]domain.Webhook, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) Get(ctx context.Context, id int64) (*domain.Webhook, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) Enqueue(ctx context.Context, event *domain.WebhookEvent, webhookIDs []int64) error {
+	args := m.Called(ctx, event, webhookIDs)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.ClaimedDelivery, error) {
+	args := m.Called(ctx, limit, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ClaimedDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	args := m.Called(ctx, id, responseStatus)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) MarkFailed(ctx context.Context, id int64, responseStatus int, lastError string, retryAt *time.Time) error {
+	args := m.Called(ctx, id, responseStatus, lastError, retryAt)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) Retry(ctx context.Context, webhookID, deliveryID int64) error {
+	args := m.Called(ctx, webhookID, deliveryID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, status string, page, pageSize int) (*domain.DeliveryLog, error) {
+	args := m.Called(ctx, webhookID, status, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DeliveryLog), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ClaimExpiredLinks(ctx context.Context, limit int) ([]domain.URL, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+type MockWebhookSender struct {
+	mock.Mock
+}
+
+func (m *MockWebhookSender) Send(ctx context.Context, req *webhook.Request) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}